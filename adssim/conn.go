@@ -0,0 +1,136 @@
+package adssim
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+// serverConn is a client connection to the server.
+type serverConn struct {
+	server  *Server
+	netConn net.Conn
+
+	writeMu  sync.Mutex
+	invokeID uint32 // Invoke ID of the last notification sent, under writeMu
+}
+
+// run answers the requests of the connection until it is closed.
+func (c *serverConn) run() {
+	defer c.server.wg.Done()
+	defer c.server.removeConn(c)
+	defer c.netConn.Close()
+
+	for {
+		packet, err := ams.ReadPacket(c.netConn)
+		if err != nil {
+			return
+		}
+		if !packet.Header.IsRequest() {
+			continue
+		}
+
+		fault := c.server.fault(packet)
+		var data []byte
+		var code ads.Error
+		switch {
+		case fault == nil:
+			data, code = c.server.handle(c, packet)
+		case fault.Kind == FaultReset:
+			reset(c.netConn)
+			return
+		case fault.Kind == FaultError:
+			data, code = errorResponse(ads.CommandID(packet.Header.CommandID), fault.Error)
+		default:
+			data, code = c.server.handle(c, packet)
+		}
+
+		resp := &ams.Packet{
+			TCPHeader: ams.TCPHeader{Length: 32 + uint32(len(data))},
+			Header: ams.Header{
+				TargetNetID: packet.Header.SourceNetID,
+				TargetPort:  packet.Header.SourcePort,
+				SourceNetID: packet.Header.TargetNetID,
+				SourcePort:  packet.Header.TargetPort,
+				CommandID:   packet.Header.CommandID,
+				StateFlags:  ams.StateFlagsTCPResponse,
+				DataLength:  uint32(len(data)),
+				ErrorCode:   uint32(code),
+				InvokeID:    packet.Header.InvokeID,
+			},
+			Data: data,
+		}
+		if fault != nil {
+			if !c.applyFault(fault, resp) {
+				return
+			}
+			if fault.Kind == FaultDrop {
+				continue
+			}
+		}
+		if err := c.write(resp); err != nil {
+			return
+		}
+	}
+}
+
+// applyFault applies a fault to the response of a request. It returns false
+// if the connection is to be closed instead of serving further requests.
+func (c *serverConn) applyFault(fault *Fault, resp *ams.Packet) bool {
+	switch fault.Kind {
+	case FaultDelay:
+		timer := time.NewTimer(fault.Delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return true
+		case <-c.server.done:
+			return false
+		}
+
+	case FaultTruncate:
+		buf, err := resp.MarshalBinary()
+		if err == nil {
+			c.writeMu.Lock()
+			c.netConn.Write(buf[:6+16])
+			c.writeMu.Unlock()
+		}
+		return false
+
+	case FaultCorrupt:
+		resp.TCPHeader.Length = 16
+	}
+	return true
+}
+
+// write sends a packet; responses and notifications may be sent concurrently.
+func (c *serverConn) write(packet *ams.Packet) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return ams.WritePacket(c.netConn, packet)
+}
+
+// sendNotification pushes a DeviceNotification with a single sample.
+func (c *serverConn) sendNotification(n *notification, stamp uint64, data []byte) error {
+	req := ads.DeviceNotificationRequest{StampHeaders: []ads.StampHeader{{
+		Timestamp: stamp,
+		Samples:   []ads.NotificationSample{{NotificationHandle: n.handle, Data: data}},
+	}}}
+	payload, err := req.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.invokeID++
+	packet := ams.NewRequestPacket(
+		n.client.SourceNetID, n.client.SourcePort,
+		n.client.TargetNetID, n.client.TargetPort,
+		uint16(ads.CmdDeviceNotification), c.invokeID, payload,
+	)
+	return ams.WritePacket(c.netConn, packet)
+}
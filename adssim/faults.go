@@ -0,0 +1,190 @@
+package adssim
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+// FaultKind selects what a Fault does to a matching request.
+type FaultKind int
+
+const (
+	FaultDelay    FaultKind = iota + 1 // Respond after Fault.Delay
+	FaultDrop                          // Execute the request but send no response
+	FaultTruncate                      // Send part of the AMS header, then close the connection
+	FaultCorrupt                       // Respond with an AMS/TCP length shorter than the AMS header
+	FaultReset                         // Reset the connection instead of executing the request
+	FaultError                         // Respond with Fault.Error without executing the request
+)
+
+// String returns the name of the fault kind.
+func (k FaultKind) String() string {
+	switch k {
+	case FaultDelay:
+		return "Delay"
+	case FaultDrop:
+		return "Drop"
+	case FaultTruncate:
+		return "Truncate"
+	case FaultCorrupt:
+		return "Corrupt"
+	case FaultReset:
+		return "Reset"
+	case FaultError:
+		return "Error"
+	default:
+		return "Unknown"
+	}
+}
+
+// Fault is a failure injected into the request handling of a Server.
+//
+// Faults are checked in the order they were added and at most one applies to a
+// request. Faults with a Count are removed once used up, so a sequence of
+// counted faults scripts the failures of consecutive requests; a Probability
+// makes a fault random instead.
+//
+// A FaultError with an IndexGroup also fails the matching sub-commands of sum
+// requests (0xF080-0xF082), which are matched as individual Read, Write and
+// ReadWrite requests. The other sub-commands are executed.
+type Fault struct {
+	Kind  FaultKind
+	Delay time.Duration // Response delay for FaultDelay
+	Error ads.Error     // Result returned for FaultError
+
+	// Requests the fault applies to; zero values match every request. The
+	// index group is known for Read, Write, ReadWrite and AddDeviceNotification.
+	Command    ads.CommandID
+	IndexGroup uint32
+
+	Probability float64 // Chance of the fault for a matching request, 0 for always
+	Count       int     // Number of times the fault occurs, 0 for no limit
+}
+
+// matches reports whether the fault applies to a request.
+func (f *Fault) matches(command ads.CommandID, indexGroup uint32, hasIndexGroup bool) bool {
+	if f.Command != ads.CmdInvalid && f.Command != command {
+		return false
+	}
+	if f.IndexGroup != 0 && (!hasIndexGroup || f.IndexGroup != indexGroup) {
+		return false
+	}
+	return true
+}
+
+// InjectFault adds a fault behind the faults already injected.
+func (s *Server) InjectFault(fault Fault) {
+	s.mu.Lock()
+	s.faults = append(s.faults, &fault)
+	s.mu.Unlock()
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	s.faults = nil
+	s.mu.Unlock()
+}
+
+// FaultsTriggered returns how many requests a fault was applied to.
+func (s *Server) FaultsTriggered() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.faultsTriggered
+}
+
+// fault picks the fault for a request, if any.
+func (s *Server) fault(packet *ams.Packet) *Fault {
+	command := ads.CommandID(packet.Header.CommandID)
+	indexGroup, hasIndexGroup := requestIndexGroup(command, packet.Data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pickFault(command, indexGroup, hasIndexGroup, false)
+}
+
+// subCommandFault returns the error a sub-command of a sum request fails with,
+// or ads.ErrNoError. s.mu must be held.
+func (s *Server) subCommandFault(command ads.CommandID, indexGroup uint32) ads.Error {
+	if fault := s.pickFault(command, indexGroup, true, true); fault != nil {
+		return fault.Error
+	}
+	return ads.ErrNoError
+}
+
+// pickFault picks the fault for a request or, with subCommand, the FaultError
+// for a sub-command of a sum request. s.mu must be held.
+func (s *Server) pickFault(command ads.CommandID, indexGroup uint32, hasIndexGroup, subCommand bool) *Fault {
+	for i, f := range s.faults {
+		if subCommand && (f.Kind != FaultError || f.IndexGroup == 0) {
+			continue
+		}
+		if !f.matches(command, indexGroup, hasIndexGroup) {
+			continue
+		}
+		if f.Probability > 0 && s.random.Float64() >= f.Probability {
+			continue
+		}
+
+		if f.Count > 0 {
+			f.Count--
+			if f.Count == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		s.faultsTriggered++
+		fault := *f
+		return &fault
+	}
+	return nil
+}
+
+// requestIndexGroup returns the index group a request addresses.
+func requestIndexGroup(command ads.CommandID, data []byte) (uint32, bool) {
+	switch command {
+	case ads.CmdRead, ads.CmdWrite, ads.CmdReadWrite, ads.CmdAddDeviceNotification:
+		if len(data) >= 4 {
+			return binary.LittleEndian.Uint32(data[0:4]), true
+		}
+	}
+	return 0, false
+}
+
+// errorResponse returns the response of a command failing with code.
+func errorResponse(command ads.CommandID, code ads.Error) ([]byte, ads.Error) {
+	var size int
+	switch command {
+	case ads.CmdReadDeviceInfo:
+		size = 24
+	case ads.CmdRead, ads.CmdReadWrite, ads.CmdReadState, ads.CmdAddDeviceNotification:
+		size = 8
+	case ads.CmdWrite, ads.CmdWriteControl, ads.CmdDelDeviceNotification:
+		size = 4
+	default:
+		return nil, code
+	}
+	data := make([]byte, size)
+	binary.LittleEndian.PutUint32(data[0:4], uint32(code))
+	return data, ads.ErrNoError
+}
+
+// newRandom returns the random source for probabilistic faults.
+func newRandom(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// reset closes a connection with a TCP reset instead of an orderly shutdown.
+func reset(conn net.Conn) {
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	conn.Close()
+}
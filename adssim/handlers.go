@@ -0,0 +1,346 @@
+package adssim
+
+import (
+	"encoding"
+	"encoding/binary"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+// Symbol upload index groups as used by TwinCAT: 0xF00C returns the upload
+// info and 0xF00B the symbol table.
+const (
+	indexGroupSymbolUpload      uint32 = 0xF00B
+	indexGroupSymbolUploadInfo2 uint32 = 0xF00C
+)
+
+// Upload info sizes, truncated to the length a client asks for.
+const (
+	symbolUploadInfoSize   = 48
+	dataTypeUploadInfoSize = 48
+)
+
+// handle serves a request and returns the response data, or an AMS error code
+// for requests that cannot be answered.
+func (s *Server) handle(conn *serverConn, packet *ams.Packet) ([]byte, ads.Error) {
+	switch ads.CommandID(packet.Header.CommandID) {
+	case ads.CmdReadDeviceInfo:
+		return encode(&ads.ReadDeviceInfoResponse{
+			MajorVersion: s.cfg.MajorVersion,
+			MinorVersion: s.cfg.MinorVersion,
+			VersionBuild: s.cfg.VersionBuild,
+			DeviceName:   s.cfg.DeviceName,
+		})
+
+	case ads.CmdReadState:
+		adsState, deviceState := s.State()
+		return encode(&ads.ReadStateResponse{ADSState: adsState, DeviceState: deviceState})
+
+	case ads.CmdWriteControl:
+		var req ads.WriteControlRequest
+		if err := req.UnmarshalBinary(packet.Data); err != nil {
+			return nil, ads.ErrDeviceInvalidSize
+		}
+		s.SetState(req.ADSState, req.DeviceState)
+		return encode(&ads.WriteControlResponse{})
+
+	case ads.CmdRead:
+		var req ads.ReadRequest
+		if err := req.UnmarshalBinary(packet.Data); err != nil {
+			return nil, ads.ErrDeviceInvalidSize
+		}
+		s.mu.Lock()
+		data, code := s.readLocked(req.IndexGroup, req.IndexOffset, req.Length)
+		s.mu.Unlock()
+		return encode(&ads.ReadResponse{Result: uint32(code), Data: data})
+
+	case ads.CmdWrite:
+		var req ads.WriteRequest
+		if err := req.UnmarshalBinary(packet.Data); err != nil {
+			return nil, ads.ErrDeviceInvalidSize
+		}
+		s.mu.Lock()
+		code := s.writeLocked(req.IndexGroup, req.IndexOffset, req.Data)
+		s.mu.Unlock()
+		if code == ads.ErrNoError {
+			s.notifyChanges()
+		}
+		return encode(&ads.WriteResponse{Result: uint32(code)})
+
+	case ads.CmdReadWrite:
+		var req ads.ReadWriteRequest
+		if err := req.UnmarshalBinary(packet.Data); err != nil {
+			return nil, ads.ErrDeviceInvalidSize
+		}
+		s.mu.Lock()
+		data, code := s.readWriteLocked(req.IndexGroup, req.IndexOffset, req.ReadLength, req.Data)
+		s.mu.Unlock()
+		if req.IndexGroup == ads.IndexGroupSumCommandWrite || req.IndexGroup == ads.IndexGroupSumCommandReadWrite {
+			s.notifyChanges()
+		}
+		return encode(&ads.ReadWriteResponse{Result: uint32(code), Data: data})
+
+	case ads.CmdAddDeviceNotification:
+		var req ads.AddDeviceNotificationRequest
+		if err := req.UnmarshalBinary(packet.Data); err != nil {
+			return nil, ads.ErrDeviceInvalidSize
+		}
+		handle, code := s.addNotification(conn, packet.Header, req)
+		return encode(&ads.AddDeviceNotificationResponse{Result: uint32(code), NotificationHandle: handle})
+
+	case ads.CmdDelDeviceNotification:
+		var req ads.DeleteDeviceNotificationRequest
+		if err := req.UnmarshalBinary(packet.Data); err != nil {
+			return nil, ads.ErrDeviceInvalidSize
+		}
+		code := s.deleteNotification(conn, req.NotificationHandle)
+		return encode(&ads.DeleteDeviceNotificationResponse{Result: uint32(code)})
+
+	default:
+		return nil, ads.ErrDeviceServiceNotSupported
+	}
+}
+
+// encode marshals a response.
+func encode(resp encoding.BinaryMarshaler) ([]byte, ads.Error) {
+	data, err := resp.MarshalBinary()
+	if err != nil {
+		return nil, ads.ErrInternal
+	}
+	return data, ads.ErrNoError
+}
+
+// readLocked serves a read of an index group. s.mu must be held.
+func (s *Server) readLocked(indexGroup, indexOffset, length uint32) ([]byte, ads.Error) {
+	switch indexGroup {
+	case ads.IndexGroupSymbolValueByHandle:
+		target, ok := s.handles[indexOffset]
+		if !ok {
+			return nil, ads.ErrDeviceSymbolNotFound
+		}
+		if length > target.Size {
+			return nil, ads.ErrDeviceInvalidSize
+		}
+		return s.readMemory(target.IndexGroup, target.IndexOffset, length)
+
+	case ads.IndexGroupSymbolVersion:
+		return truncate([]byte{s.symbolVersion}, length), ads.ErrNoError
+
+	case indexGroupSymbolUploadInfo2:
+		table, dataTypes := s.symbolTable(), s.dataTypeTable()
+		info := make([]byte, symbolUploadInfoSize)
+		binary.LittleEndian.PutUint32(info[0:4], uint32(len(s.symbols)))
+		binary.LittleEndian.PutUint32(info[4:8], uint32(len(table)))
+		binary.LittleEndian.PutUint32(info[8:12], uint32(len(s.dataTypeOrder)))
+		binary.LittleEndian.PutUint32(info[12:16], uint32(len(dataTypes)))
+		return truncate(info, length), ads.ErrNoError
+
+	case indexGroupSymbolUpload:
+		table := s.symbolTable()
+		if length < uint32(len(table)) {
+			return nil, ads.ErrDeviceInvalidSize
+		}
+		return table, ads.ErrNoError
+
+	case ads.IndexGroupDataTypeUploadInfo:
+		dataTypes := s.dataTypeTable()
+		info := make([]byte, dataTypeUploadInfoSize)
+		binary.LittleEndian.PutUint32(info[0:4], uint32(len(s.dataTypeOrder)))
+		binary.LittleEndian.PutUint32(info[4:8], uint32(len(dataTypes)))
+		return truncate(info, length), ads.ErrNoError
+
+	case ads.IndexGroupDataTypeUpload:
+		dataTypes := s.dataTypeTable()
+		if length < uint32(len(dataTypes)) {
+			return nil, ads.ErrDeviceInvalidSize
+		}
+		return dataTypes, ads.ErrNoError
+
+	default:
+		return s.readMemory(indexGroup, indexOffset, length)
+	}
+}
+
+// writeLocked serves a write to an index group. s.mu must be held.
+func (s *Server) writeLocked(indexGroup, indexOffset uint32, data []byte) ads.Error {
+	switch indexGroup {
+	case ads.IndexGroupSymbolValueByHandle:
+		target, ok := s.handles[indexOffset]
+		if !ok {
+			return ads.ErrDeviceSymbolNotFound
+		}
+		if uint32(len(data)) > target.Size {
+			return ads.ErrDeviceInvalidSize
+		}
+		return s.writeMemory(target.IndexGroup, target.IndexOffset, data)
+
+	case ads.IndexGroupReleaseSymbolHandle:
+		if len(data) < 4 {
+			return ads.ErrDeviceInvalidSize
+		}
+		handle := binary.LittleEndian.Uint32(data[0:4])
+		if _, ok := s.handles[handle]; !ok {
+			return ads.ErrDeviceSymbolNotFound
+		}
+		delete(s.handles, handle)
+		return ads.ErrNoError
+
+	default:
+		return s.writeMemory(indexGroup, indexOffset, data)
+	}
+}
+
+// readWriteLocked serves a read/write of an index group. s.mu must be held.
+func (s *Server) readWriteLocked(indexGroup, indexOffset, readLength uint32, data []byte) ([]byte, ads.Error) {
+	var resp []byte
+	var code ads.Error
+
+	switch indexGroup {
+	case ads.IndexGroupSymbolHandleByName:
+		target, root, ok := s.resolve(cString(data))
+		if !ok {
+			return nil, ads.ErrDeviceSymbolNotFound
+		}
+		handle := s.nextHandle
+		s.nextHandle++
+		s.handles[handle] = &handleTarget{Symbol: target, root: root}
+		resp = binary.LittleEndian.AppendUint32(nil, handle)
+
+	case ads.IndexGroupSymbolValueByName:
+		target, _, ok := s.resolve(cString(data))
+		if !ok {
+			return nil, ads.ErrDeviceSymbolNotFound
+		}
+		resp, code = s.readMemory(target.IndexGroup, target.IndexOffset, target.Size)
+
+	case ads.IndexGroupSymbolInfoByNameEx:
+		target, _, ok := s.resolve(cString(data))
+		if !ok {
+			return nil, ads.ErrDeviceSymbolNotFound
+		}
+		resp = target.encode()
+
+	case ads.IndexGroupDataTypeUpload:
+		entry, ok := s.dataTypes[lowerName(cString(data))]
+		if !ok {
+			return nil, ads.ErrDeviceSymbolNotFound
+		}
+		resp = encodeDataType(entry)
+
+	case ads.IndexGroupSumCommandRead:
+		resp, code = s.sumRead(indexOffset, data)
+
+	case ads.IndexGroupSumCommandWrite:
+		resp, code = s.sumWrite(indexOffset, data)
+
+	case ads.IndexGroupSumCommandReadWrite:
+		resp, code = s.sumReadWrite(indexOffset, data)
+
+	default:
+		return nil, ads.ErrDeviceInvalidIndexGroup
+	}
+
+	if code != ads.ErrNoError {
+		return nil, code
+	}
+	if uint32(len(resp)) > readLength {
+		return nil, ads.ErrDeviceInvalidSize
+	}
+	return resp, ads.ErrNoError
+}
+
+// sumRead serves a sum read (0xF080) with count sub-commands.
+func (s *Server) sumRead(count uint32, data []byte) ([]byte, ads.Error) {
+	if count > ads.MaxSumCommands {
+		return nil, ads.ErrDeviceInvalidSize
+	}
+	req := ads.SumReadRequest{Items: make([]ads.SumReadItem, count)}
+	if err := req.UnmarshalBinary(data); err != nil {
+		return nil, ads.ErrDeviceInvalidSize
+	}
+
+	resp := ads.SumReadResponse{
+		Lengths: make([]uint32, count),
+		Results: make([]uint32, count),
+		Data:    make([][]byte, count),
+	}
+	for i, item := range req.Items {
+		var value []byte
+		code := s.subCommandFault(ads.CmdRead, item.IndexGroup)
+		if code == ads.ErrNoError {
+			value, code = s.readLocked(item.IndexGroup, item.IndexOffset, item.Length)
+		}
+		resp.Lengths[i] = item.Length
+		resp.Results[i] = uint32(code)
+		resp.Data[i] = value
+	}
+	return encode(&resp)
+}
+
+// sumWrite serves a sum write (0xF081) with count sub-commands.
+func (s *Server) sumWrite(count uint32, data []byte) ([]byte, ads.Error) {
+	if count > ads.MaxSumCommands {
+		return nil, ads.ErrDeviceInvalidSize
+	}
+	req := ads.SumWriteRequest{Items: make([]ads.SumWriteItem, count)}
+	if err := req.UnmarshalBinary(data); err != nil {
+		return nil, ads.ErrDeviceInvalidSize
+	}
+
+	resp := ads.SumWriteResponse{Results: make([]uint32, count)}
+	for i, item := range req.Items {
+		code := s.subCommandFault(ads.CmdWrite, item.IndexGroup)
+		if code == ads.ErrNoError {
+			code = s.writeLocked(item.IndexGroup, item.IndexOffset, item.Data)
+		}
+		resp.Results[i] = uint32(code)
+	}
+	return encode(&resp)
+}
+
+// sumReadWrite serves a sum read/write (0xF082) with count sub-commands.
+func (s *Server) sumReadWrite(count uint32, data []byte) ([]byte, ads.Error) {
+	if count > ads.MaxSumCommands {
+		return nil, ads.ErrDeviceInvalidSize
+	}
+	req := ads.SumReadWriteRequest{Items: make([]ads.SumReadWriteItem, count)}
+	if err := req.UnmarshalBinary(data); err != nil {
+		return nil, ads.ErrDeviceInvalidSize
+	}
+
+	resp := ads.SumReadWriteResponse{Results: make([]uint32, count), Data: make([][]byte, count)}
+	for i, item := range req.Items {
+		var value []byte
+		code := s.subCommandFault(ads.CmdReadWrite, item.IndexGroup)
+		if code == ads.ErrNoError {
+			switch item.IndexGroup {
+			case ads.IndexGroupSumCommandRead, ads.IndexGroupSumCommandWrite, ads.IndexGroupSumCommandReadWrite:
+				code = ads.ErrDeviceInvalidIndexGroup // no nested sum commands
+			default:
+				value, code = s.readWriteLocked(item.IndexGroup, item.IndexOffset, item.ReadLength, item.WriteData)
+			}
+		}
+		resp.Results[i] = uint32(code)
+		resp.Data[i] = value
+	}
+	return encode(&resp)
+}
+
+// truncate shortens data to at most length bytes.
+func truncate(data []byte, length uint32) []byte {
+	if uint32(len(data)) > length {
+		return data[:length]
+	}
+	return data
+}
+
+// cString decodes a string that may be null-terminated.
+func cString(data []byte) string {
+	for i, b := range data {
+		if b == 0 {
+			return string(data[:i])
+		}
+	}
+	return string(data)
+}
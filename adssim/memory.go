@@ -0,0 +1,67 @@
+package adssim
+
+import (
+	"bytes"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+)
+
+// Read returns memory of the simulated PLC, as seen by clients.
+func (s *Server) Read(indexGroup, indexOffset, length uint32) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, code := s.readMemory(indexGroup, indexOffset, length)
+	if code != ads.ErrNoError {
+		return nil, code
+	}
+	return data, nil
+}
+
+// Write changes memory of the simulated PLC from the PLC side. Notifications
+// are sent as for a write by a client.
+func (s *Server) Write(indexGroup, indexOffset uint32, data []byte) error {
+	s.mu.Lock()
+	code := s.writeMemory(indexGroup, indexOffset, data)
+	s.mu.Unlock()
+
+	if code != ads.ErrNoError {
+		return code
+	}
+	s.notifyChanges()
+	return nil
+}
+
+// readMemory copies memory of an area. s.mu must be held.
+func (s *Server) readMemory(indexGroup, indexOffset, length uint32) ([]byte, ads.Error) {
+	area, code := s.area(indexGroup, indexOffset, length)
+	if code != ads.ErrNoError {
+		return nil, code
+	}
+	return bytes.Clone(area), ads.ErrNoError
+}
+
+// writeMemory changes memory of an area. s.mu must be held.
+func (s *Server) writeMemory(indexGroup, indexOffset uint32, data []byte) ads.Error {
+	area, code := s.area(indexGroup, indexOffset, uint32(len(data)))
+	if code != ads.ErrNoError {
+		return code
+	}
+	copy(area, data)
+	return ads.ErrNoError
+}
+
+// area returns a range of a memory area, or the ADS error a PLC returns for it.
+func (s *Server) area(indexGroup, indexOffset, length uint32) ([]byte, ads.Error) {
+	memory, ok := s.memory[indexGroup]
+	if !ok {
+		return nil, ads.ErrDeviceInvalidIndexGroup
+	}
+	if uint64(indexOffset) > uint64(len(memory)) {
+		return nil, ads.ErrDeviceInvalidIndexOffset
+	}
+	if uint64(indexOffset)+uint64(length) > uint64(len(memory)) {
+		return nil, ads.ErrDeviceInvalidSize
+	}
+	return memory[indexOffset : indexOffset+length], ads.ErrNoError
+}
@@ -0,0 +1,151 @@
+package adssim
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+// fileTimeEpoch is the number of 100ns intervals between 1601 and 1970.
+const fileTimeEpoch = 116444736000000000
+
+// notification is a device notification added by a client.
+type notification struct {
+	handle uint32
+	conn   *serverConn
+	client ams.Header // Header of the add request, addresses the samples
+	req    ads.AddDeviceNotificationRequest
+
+	// Guarded by Server.notifyMu
+	started bool   // First sample sent
+	last    []byte // Last value sent, for on-change detection
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// stop ends the cyclic transmission of the notification.
+func (n *notification) stop() {
+	n.stopOnce.Do(func() { close(n.done) })
+}
+
+// addNotification registers a notification. The first sample is sent one PLC
+// cycle later; cyclic notifications are then sent every cycle time, the
+// on-change modes whenever the value changes.
+func (s *Server) addNotification(conn *serverConn, header ams.Header, req ads.AddDeviceNotificationRequest) (uint32, ads.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, code := s.readLocked(req.IndexGroup, req.IndexOffset, req.Length); code != ads.ErrNoError {
+		return 0, code
+	}
+
+	n := &notification{
+		handle: s.nextNotif,
+		conn:   conn,
+		client: header,
+		req:    req,
+		done:   make(chan struct{}),
+	}
+	s.nextNotif++
+	s.notifications[n.handle] = n
+
+	s.wg.Add(1)
+	go s.runNotification(n)
+	return n.handle, ads.ErrNoError
+}
+
+// deleteNotification removes a notification of the connection.
+func (s *Server) deleteNotification(conn *serverConn, handle uint32) ads.Error {
+	s.mu.Lock()
+	n, ok := s.notifications[handle]
+	if !ok || n.conn != conn {
+		s.mu.Unlock()
+		return ads.ErrDeviceNotifyHandleInvalid
+	}
+	delete(s.notifications, handle)
+	s.mu.Unlock()
+
+	n.stop()
+	return ads.ErrNoError
+}
+
+// runNotification sends the first sample and, for cyclic notifications, the
+// following samples until the notification is stopped.
+func (s *Server) runNotification(n *notification) {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(s.cfg.CycleTime)
+	select {
+	case <-timer.C:
+	case <-n.done:
+		timer.Stop()
+		return
+	}
+
+	s.notifyMu.Lock()
+	n.started = true
+	s.sample(n, true)
+	s.notifyMu.Unlock()
+
+	if n.req.TransmissionMode != ads.TransModeCyclic {
+		return
+	}
+
+	period := time.Duration(n.req.CycleTime) * time.Millisecond
+	if period <= 0 {
+		period = s.cfg.CycleTime
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.notifyMu.Lock()
+			s.sample(n, true)
+			s.notifyMu.Unlock()
+		case <-n.done:
+			return
+		}
+	}
+}
+
+// notifyChanges sends a sample for every on-change notification whose value
+// differs from the last one sent.
+func (s *Server) notifyChanges() {
+	s.notifyMu.Lock()
+	defer s.notifyMu.Unlock()
+
+	s.mu.Lock()
+	pending := make([]*notification, 0, len(s.notifications))
+	for _, n := range s.notifications {
+		if n.started && n.req.TransmissionMode != ads.TransModeCyclic {
+			pending = append(pending, n)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, n := range pending {
+		s.sample(n, false)
+	}
+}
+
+// sample reads the value of a notification and sends it if always is set or
+// the value changed. s.notifyMu must be held.
+func (s *Server) sample(n *notification, always bool) {
+	s.mu.Lock()
+	data, code := s.readLocked(n.req.IndexGroup, n.req.IndexOffset, n.req.Length)
+	s.mu.Unlock()
+
+	if code != ads.ErrNoError || (!always && bytes.Equal(data, n.last)) {
+		return
+	}
+	n.last = data
+
+	stamp := uint64(time.Now().UnixNano()/100) + fileTimeEpoch
+	n.conn.sendNotification(n, stamp, data)
+}
@@ -0,0 +1,302 @@
+// Package adssim simulates a TwinCAT PLC for tests. A Server listens on TCP,
+// speaks AMS/ADS like a PLC runtime and serves memory areas, a symbol table, a
+// data type table, symbol handles, device info and state, WriteControl, sum
+// commands and device notifications, so client code can be tested without a
+// real device.
+//
+//	server, err := adssim.StartServer("127.0.0.1:0", adssim.Config{
+//	    Symbols: []adssim.Symbol{
+//	        {Name: "MAIN.counter", Type: "DINT"},
+//	        {Name: "MAIN.speed", Type: "REAL"},
+//	    },
+//	})
+//	defer server.Close()
+//
+//	client, err := goadstc.New(
+//	    goadstc.WithTarget(server.Addr()),
+//	    goadstc.WithAMSNetID(server.NetID()),
+//	)
+//
+// Tests can change values on the PLC side with WriteSymbol, which triggers
+// on-change notifications like a write from a client, and simulate online
+// changes with AddSymbol and RemoveSymbol. InjectFault makes the server delay,
+// drop or corrupt responses, reset connections or fail requests with an ADS
+// error, to test how clients handle a misbehaving PLC or network.
+package adssim
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+const (
+	defaultDeviceName = "adssim"
+	defaultCycleTime  = 10 * time.Millisecond
+)
+
+// Config describes the simulated PLC.
+type Config struct {
+	NetID ams.NetID // NetID of the PLC (default 127.0.0.1.1.1)
+
+	// Device info returned by ReadDeviceInfo (default "adssim" 3.1.4024)
+	DeviceName   string
+	MajorVersion uint8
+	MinorVersion uint8
+	VersionBuild uint16
+
+	// Initial ADS state (default Run) and device state
+	State       ads.ADSState
+	DeviceState uint16
+
+	// Memory areas by index group with their size in bytes. Areas holding
+	// symbols grow to fit them; PLC memory (0x4020) is created for symbols
+	// without an index group.
+	Memory map[uint32]uint32
+
+	Symbols   []Symbol
+	DataTypes []symbols.DataTypeEntry
+
+	// CycleTime is the simulated PLC task cycle (default 10ms). The first
+	// sample of a notification is sent one cycle after it was added, and
+	// cyclic notifications without a cycle time use it.
+	CycleTime time.Duration
+
+	// Faults injected from the start, as by InjectFault, and the seed of
+	// probabilistic faults (0 for a random seed)
+	Faults    []Fault
+	FaultSeed int64
+}
+
+// Server is a simulated PLC listening on TCP.
+type Server struct {
+	cfg      Config
+	listener net.Listener
+
+	mu            sync.Mutex
+	memory        map[uint32][]byte
+	symbols       []*Symbol
+	symbolIndex   map[string]*Symbol // by lowercase name
+	dataTypes     map[string]symbols.DataTypeEntry
+	dataTypeOrder []string
+	symbolVersion uint8
+	state         ads.ADSState
+	deviceState   uint16
+	handles       map[uint32]*handleTarget
+	nextHandle    uint32
+	notifications map[uint32]*notification
+	nextNotif     uint32
+	conns         map[*serverConn]struct{}
+	closed        bool
+
+	faults          []*Fault
+	faultsTriggered int
+	random          *rand.Rand
+
+	done chan struct{} // Closed by Close
+
+	// notifyMu serializes change detection, so samples of one notification
+	// are sent in the order the values changed
+	notifyMu sync.Mutex
+
+	wg sync.WaitGroup
+}
+
+// StartServer listens on address (e.g. "127.0.0.1:0") and serves ADS requests
+// until Close is called.
+func StartServer(address string, cfg Config) (*Server, error) {
+	s, err := newServer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("adssim: listen %s: %w", address, err)
+	}
+	s.listener = listener
+
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// newServer builds the simulated PLC from its configuration.
+func newServer(cfg Config) (*Server, error) {
+	if cfg.NetID == (ams.NetID{}) {
+		cfg.NetID = ams.NetID{127, 0, 0, 1, 1, 1}
+	}
+	if cfg.DeviceName == "" {
+		cfg.DeviceName = defaultDeviceName
+	}
+	if len(cfg.DeviceName) > 16 {
+		return nil, fmt.Errorf("adssim: device name %q exceeds 16 bytes", cfg.DeviceName)
+	}
+	if cfg.MajorVersion == 0 && cfg.MinorVersion == 0 && cfg.VersionBuild == 0 {
+		cfg.MajorVersion, cfg.MinorVersion, cfg.VersionBuild = 3, 1, 4024
+	}
+	if cfg.State == ads.StateInvalid {
+		cfg.State = ads.StateRun
+	}
+	if cfg.CycleTime <= 0 {
+		cfg.CycleTime = defaultCycleTime
+	}
+
+	s := &Server{
+		cfg:           cfg,
+		memory:        make(map[uint32][]byte),
+		symbolIndex:   make(map[string]*Symbol),
+		dataTypes:     make(map[string]symbols.DataTypeEntry),
+		symbolVersion: 1,
+		state:         cfg.State,
+		deviceState:   cfg.DeviceState,
+		handles:       make(map[uint32]*handleTarget),
+		nextHandle:    0x1000,
+		notifications: make(map[uint32]*notification),
+		nextNotif:     1,
+		conns:         make(map[*serverConn]struct{}),
+		random:        newRandom(cfg.FaultSeed),
+		done:          make(chan struct{}),
+	}
+
+	for i := range cfg.Faults {
+		fault := cfg.Faults[i]
+		s.faults = append(s.faults, &fault)
+	}
+	for indexGroup, size := range cfg.Memory {
+		s.memory[indexGroup] = make([]byte, size)
+	}
+	for _, entry := range cfg.DataTypes {
+		if err := s.addDataType(entry); err != nil {
+			return nil, err
+		}
+	}
+	for _, symbol := range cfg.Symbols {
+		if err := s.addSymbol(symbol); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// NetID returns the AMS NetID of the simulated PLC.
+func (s *Server) NetID() ams.NetID {
+	return s.cfg.NetID
+}
+
+// Close stops the server and closes all client connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	conns := make([]*serverConn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	for _, conn := range conns {
+		conn.netConn.Close()
+	}
+	s.wg.Wait()
+	return err
+}
+
+// serve accepts connections until the listener is closed.
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		netConn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		conn := &serverConn{server: s, netConn: netConn}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			netConn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go conn.run()
+	}
+}
+
+// removeConn forgets a closed connection together with its notifications.
+func (s *Server) removeConn(conn *serverConn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	var stopped []*notification
+	for handle, n := range s.notifications {
+		if n.conn == conn {
+			delete(s.notifications, handle)
+			stopped = append(stopped, n)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, n := range stopped {
+		n.stop()
+	}
+}
+
+// State returns the current ADS state and device state.
+func (s *Server) State() (ads.ADSState, uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state, s.deviceState
+}
+
+// SetState changes the ADS state and device state, as WriteControl does.
+func (s *Server) SetState(adsState ads.ADSState, deviceState uint16) {
+	s.mu.Lock()
+	s.state = adsState
+	s.deviceState = deviceState
+	s.mu.Unlock()
+}
+
+// Connections returns the number of open client connections.
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Handles returns the number of symbol handles currently held by clients.
+func (s *Server) Handles() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.handles)
+}
+
+// Notifications returns the number of active device notifications.
+func (s *Server) Notifications() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.notifications)
+}
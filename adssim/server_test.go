@@ -0,0 +1,22 @@
+package adssim_test
+
+import (
+	"testing"
+
+	"github.com/mrpasztoradam/goadstc/adssim"
+)
+
+func TestConfigValidation(t *testing.T) {
+	tests := []adssim.Config{
+		{Symbols: []adssim.Symbol{{Name: "MAIN.x", Type: "INT"}, {Name: "main.X", Type: "INT"}}},
+		{Symbols: []adssim.Symbol{{Name: "MAIN.st", Type: "ST_Unknown"}}},
+		{Symbols: []adssim.Symbol{{Name: "MAIN.x", Type: "INT", Value: []byte{1, 2, 3}}}},
+		{DeviceName: "a device name that is too long"},
+	}
+	for i, cfg := range tests {
+		if server, err := adssim.StartServer("127.0.0.1:0", cfg); err == nil {
+			server.Close()
+			t.Errorf("config %d accepted", i)
+		}
+	}
+}
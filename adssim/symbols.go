@@ -0,0 +1,380 @@
+package adssim
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+// symbolAlignment is the alignment of symbols placed in PLC memory.
+const symbolAlignment = 8
+
+// Symbol is a variable of the simulated PLC.
+type Symbol struct {
+	Name    string
+	Type    string // PLC type name, e.g. "DINT" or "ST_Motor"
+	Comment string
+
+	// ADS data type ID and size in bytes. Both are derived from Type for
+	// elementary types (BOOL, INT, REAL, ...); struct types use
+	// symbols.DataTypeBigType and need a Size.
+	DataType symbols.DataType
+	Size     uint32
+
+	// Location of the value. Symbols without an index group are placed in
+	// PLC memory (0x4020) behind the symbols already there.
+	IndexGroup  uint32
+	IndexOffset uint32
+
+	// Initial value, zero if empty. The value of a POINTER TO or REFERENCE TO
+	// is the offset of its target in PLC memory (0x4020), 0 being NULL.
+	Value []byte
+}
+
+// elementaryTypes maps elementary PLC types to their data type ID and size.
+var elementaryTypes = map[string]struct {
+	dataType symbols.DataType
+	size     uint32
+}{
+	"BOOL":  {symbols.DataTypeBool, 1},
+	"BYTE":  {symbols.DataTypeUInt8, 1},
+	"SINT":  {symbols.DataTypeInt8, 1},
+	"USINT": {symbols.DataTypeUInt8, 1},
+	"WORD":  {symbols.DataTypeUInt16, 2},
+	"INT":   {symbols.DataTypeInt16, 2},
+	"UINT":  {symbols.DataTypeUInt16, 2},
+	"DWORD": {symbols.DataTypeUInt32, 4},
+	"DINT":  {symbols.DataTypeInt32, 4},
+	"UDINT": {symbols.DataTypeUInt32, 4},
+	"LWORD": {symbols.DataTypeUInt64, 8},
+	"LINT":  {symbols.DataTypeInt64, 8},
+	"ULINT": {symbols.DataTypeUInt64, 8},
+	"REAL":  {symbols.DataTypeReal32, 4},
+	"LREAL": {symbols.DataTypeReal64, 8},
+	"TIME":  {symbols.DataTypeUInt32, 4},
+}
+
+// addressSize is the size of a POINTER TO or REFERENCE TO, as on a 64-bit PLC.
+const addressSize = 8
+
+// lookupType returns the data type ID and size of a PLC type: an elementary
+// type, a pointer or reference, or an entry of the data type table.
+// s.mu must be held.
+func (s *Server) lookupType(typeName string) (symbols.DataType, uint32, bool) {
+	if elementary, ok := elementaryTypes[strings.ToUpper(typeName)]; ok {
+		return elementary.dataType, elementary.size, true
+	}
+	if _, ok := pointeeType(typeName); ok {
+		return symbols.DataTypeUInt64, addressSize, true
+	}
+	if entry, ok := s.dataTypes[lowerName(typeName)]; ok {
+		return entry.DataType, entry.Size, true
+	}
+	return symbols.DataTypeVoid, 0, false
+}
+
+// pointeeType returns the target type of a POINTER TO or REFERENCE TO type.
+func pointeeType(typeName string) (string, bool) {
+	if pointee, ok := cutPrefixFold(typeName, "POINTER TO "); ok {
+		return pointee, true
+	}
+	return cutPrefixFold(typeName, "REFERENCE TO ")
+}
+
+// cutPrefixFold returns s without a prefix matched case-insensitively.
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return strings.TrimSpace(s[len(prefix):]), true
+}
+
+// handleTarget is the symbol or struct field a handle refers to.
+type handleTarget struct {
+	Symbol
+	root *Symbol // Symbol of the path, the handle is released when it is removed
+}
+
+// encode returns the symbol as a symbol table entry.
+func (s *Symbol) encode() []byte {
+	return symbols.EncodeSymbolEntry(symbols.Symbol{
+		Name:        s.Name,
+		Type:        symbols.TypeInfo{Name: s.Type, BaseType: s.DataType},
+		IndexGroup:  s.IndexGroup,
+		IndexOffset: s.IndexOffset,
+		Size:        s.Size,
+		Comment:     s.Comment,
+	})
+}
+
+// AddSymbol adds a symbol, as an online change of the PLC program does: the
+// symbol version is incremented.
+func (s *Server) AddSymbol(symbol Symbol) error {
+	s.mu.Lock()
+	err := s.addSymbol(symbol)
+	if err == nil {
+		s.symbolVersion++
+	}
+	s.mu.Unlock()
+
+	if err == nil {
+		s.notifyChanges()
+	}
+	return err
+}
+
+// RemoveSymbol removes a symbol and releases the handles referring to it. The
+// symbol version is incremented. The memory of the symbol is kept.
+func (s *Server) RemoveSymbol(name string) error {
+	s.mu.Lock()
+	symbol, ok := s.symbolIndex[lowerName(name)]
+	if !ok {
+		s.mu.Unlock()
+		return ads.ErrDeviceSymbolNotFound
+	}
+
+	delete(s.symbolIndex, lowerName(name))
+	for i, sym := range s.symbols {
+		if sym == symbol {
+			s.symbols = append(s.symbols[:i], s.symbols[i+1:]...)
+			break
+		}
+	}
+	for handle, target := range s.handles {
+		if target.root == symbol {
+			delete(s.handles, handle)
+		}
+	}
+	s.symbolVersion++
+	s.mu.Unlock()
+
+	s.notifyChanges()
+	return nil
+}
+
+// SymbolVersion returns the symbol version counter (0xF008).
+func (s *Server) SymbolVersion() uint8 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.symbolVersion
+}
+
+// ReadSymbol returns the value of a symbol or struct field ("MAIN.st.field").
+func (s *Server) ReadSymbol(name string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target, _, ok := s.resolve(name)
+	if !ok {
+		return nil, ads.ErrDeviceSymbolNotFound
+	}
+	data, code := s.readMemory(target.IndexGroup, target.IndexOffset, target.Size)
+	if code != ads.ErrNoError {
+		return nil, code
+	}
+	return data, nil
+}
+
+// WriteSymbol changes the value of a symbol or struct field from the PLC side.
+// Notifications are sent as for a write by a client.
+func (s *Server) WriteSymbol(name string, data []byte) error {
+	s.mu.Lock()
+	target, _, ok := s.resolve(name)
+	if !ok {
+		s.mu.Unlock()
+		return ads.ErrDeviceSymbolNotFound
+	}
+	code := ads.ErrDeviceInvalidSize
+	if uint32(len(data)) <= target.Size {
+		code = s.writeMemory(target.IndexGroup, target.IndexOffset, data)
+	}
+	s.mu.Unlock()
+
+	if code != ads.ErrNoError {
+		return code
+	}
+	s.notifyChanges()
+	return nil
+}
+
+// addSymbol completes a symbol and adds it to the table. s.mu must be held.
+func (s *Server) addSymbol(symbol Symbol) error {
+	if symbol.Name == "" {
+		return fmt.Errorf("adssim: symbol name cannot be empty")
+	}
+	if _, exists := s.symbolIndex[lowerName(symbol.Name)]; exists {
+		return fmt.Errorf("adssim: duplicate symbol %q", symbol.Name)
+	}
+
+	if dataType, size, ok := s.lookupType(symbol.Type); ok {
+		if symbol.DataType == symbols.DataTypeVoid {
+			symbol.DataType = dataType
+		}
+		if symbol.Size == 0 {
+			symbol.Size = size
+		}
+	}
+	if symbol.Size == 0 {
+		return fmt.Errorf("adssim: symbol %q of type %q needs a size", symbol.Name, symbol.Type)
+	}
+	if uint32(len(symbol.Value)) > symbol.Size {
+		return fmt.Errorf("adssim: initial value of %q exceeds %d bytes", symbol.Name, symbol.Size)
+	}
+
+	if symbol.IndexGroup == 0 {
+		symbol.IndexGroup = ads.IndexGroupPLCMemory
+		end := uint32(len(s.memory[symbol.IndexGroup]))
+		symbol.IndexOffset = (end + symbolAlignment - 1) / symbolAlignment * symbolAlignment
+	}
+
+	// Grow the memory area to hold the symbol
+	end := symbol.IndexOffset + symbol.Size
+	if area := s.memory[symbol.IndexGroup]; uint32(len(area)) < end {
+		s.memory[symbol.IndexGroup] = append(area, make([]byte, int(end)-len(area))...)
+	}
+	copy(s.memory[symbol.IndexGroup][symbol.IndexOffset:], symbol.Value)
+	symbol.Value = nil
+
+	sym := &symbol
+	s.symbols = append(s.symbols, sym)
+	s.symbolIndex[lowerName(symbol.Name)] = sym
+	return nil
+}
+
+// addDataType adds an entry to the data type table. s.mu must be held.
+func (s *Server) addDataType(entry symbols.DataTypeEntry) error {
+	if entry.Name == "" {
+		return fmt.Errorf("adssim: data type name cannot be empty")
+	}
+	key := lowerName(entry.Name)
+	if _, exists := s.dataTypes[key]; exists {
+		return fmt.Errorf("adssim: duplicate data type %q", entry.Name)
+	}
+	s.dataTypes[key] = entry
+	s.dataTypeOrder = append(s.dataTypeOrder, key)
+	return nil
+}
+
+// resolve finds a symbol or a field of a struct symbol ("MAIN.st.field"), using
+// the sub-items of the data type table. Pointers are dereferenced with "^"
+// ("MAIN.pAxis^.Status") and references are followed implicitly; a path ending
+// in a reference keeps its REFERENCE TO type, as the symbol info (0xF009) of a
+// PLC describes it. Names are case-insensitive, as on a PLC.
+// It returns the target and the symbol the path starts with.
+func (s *Server) resolve(path string) (Symbol, *Symbol, bool) {
+	parts := strings.Split(path, ".")
+
+	// Longest symbol prefix first, the rest of the path are struct fields
+	for n := len(parts); n > 0; n-- {
+		name := strings.Join(parts[:n], ".")
+		root, ok := s.symbolIndex[lowerName(strings.TrimRight(name, "^"))]
+		if !ok {
+			continue
+		}
+
+		target := *root
+		target.Name = path
+		if !s.follow(&target, name, n == len(parts)) {
+			return Symbol{}, nil, false
+		}
+		for i, part := range parts[n:] {
+			entry, ok := s.dataTypes[lowerName(target.Type)]
+			if !ok {
+				return Symbol{}, nil, false
+			}
+			field := strings.TrimRight(part, "^")
+			found := false
+			for _, sub := range entry.SubItems {
+				if strings.EqualFold(sub.Name, field) {
+					target.Type = sub.Type
+					target.DataType = sub.DataType
+					target.Size = sub.Size
+					target.IndexOffset += sub.Offset
+					target.Comment = sub.Comment
+					found = true
+					break
+				}
+			}
+			if !found || !s.follow(&target, part, n+i+1 == len(parts)) {
+				return Symbol{}, nil, false
+			}
+		}
+		return target, root, true
+	}
+	return Symbol{}, nil, false
+}
+
+// follow applies the dereferences ("^") at the end of a path part to target and
+// follows a reference it ends on. s.mu must be held.
+func (s *Server) follow(target *Symbol, part string, last bool) bool {
+	for ; strings.HasSuffix(part, "^"); part = part[:len(part)-1] {
+		pointee, ok := cutPrefixFold(target.Type, "POINTER TO ")
+		if !ok || !s.dereference(target, pointee) {
+			return false
+		}
+	}
+	if pointee, ok := cutPrefixFold(target.Type, "REFERENCE TO "); ok {
+		typeName := target.Type
+		if !s.dereference(target, pointee) {
+			return false
+		}
+		if last {
+			target.Type = typeName
+		}
+	}
+	return true
+}
+
+// dereference moves target to the value its pointer or reference points to.
+// s.mu must be held.
+func (s *Server) dereference(target *Symbol, pointee string) bool {
+	data, code := s.readMemory(target.IndexGroup, target.IndexOffset, target.Size)
+	if code != ads.ErrNoError || len(data) != addressSize {
+		return false
+	}
+	address := binary.LittleEndian.Uint64(data)
+	dataType, size, ok := s.lookupType(pointee)
+	if address == 0 || address > math.MaxUint32 || !ok {
+		return false
+	}
+
+	target.Type = pointee
+	target.DataType = dataType
+	target.Size = size
+	target.IndexGroup = ads.IndexGroupPLCMemory
+	target.IndexOffset = uint32(address)
+	target.Comment = ""
+	return true
+}
+
+// symbolTable encodes the symbol upload (0xF00B). s.mu must be held.
+func (s *Server) symbolTable() []byte {
+	var table []byte
+	for _, symbol := range s.symbols {
+		table = append(table, symbol.encode()...)
+	}
+	return table
+}
+
+// dataTypeTable encodes the data type upload (0xF011). s.mu must be held.
+func (s *Server) dataTypeTable() []byte {
+	var table []byte
+	for _, key := range s.dataTypeOrder {
+		table = append(table, encodeDataType(s.dataTypes[key])...)
+	}
+	return table
+}
+
+// encodeDataType encodes a data type table entry.
+func encodeDataType(entry symbols.DataTypeEntry) []byte {
+	return symbols.EncodeDataTypeEntry(entry)
+}
+
+// lowerName returns the lookup key of a symbol or type name.
+func lowerName(name string) string {
+	return strings.ToLower(name)
+}
@@ -0,0 +1,266 @@
+// Package capture records AMS traffic to a file and replays it, so problems
+// seen at a customer site can be reproduced without access to their PLC.
+//
+// Record the traffic of a client:
+//
+//	w, err := capture.Create("field.adscap")
+//	defer w.Close()
+//
+//	client, err := goadstc.New(
+//	    goadstc.WithTarget("192.168.1.100:48898"),
+//	    goadstc.WithAMSNetID(plcNetID),
+//	    goadstc.WithCapture(w),
+//	)
+//
+// Answer the same requests later from the recording:
+//
+//	records, err := capture.ReadFile("field.adscap")
+//	server, err := capture.StartReplayServer("127.0.0.1:0", records)
+//
+// # File Format
+//
+// All integers are little-endian. A capture starts with a 16-byte file header:
+//
+//	offset 0   8 bytes  magic "GOADSCAP"
+//	offset 8   uint16   format version (2)
+//	offset 10  6 bytes  reserved, zero
+//
+// followed by one record per packet:
+//
+//	offset 0   int64    time in nanoseconds since the Unix epoch
+//	offset 8   uint8    direction: 1 sent, 2 received
+//	offset 9   uint32   connection number, see Writer.NewConn
+//	offset 13           AMS/TCP frame: 6-byte AMS/TCP header, 32-byte AMS
+//	                    header and ADS data, as sent over TCP
+//
+// Packets of the UDP transport are stored with an AMS/TCP header as well.
+// Invoke IDs and notification handles are only unique per connection, so the
+// replay pairs requests with responses by connection number and invoke ID.
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+const (
+	magic   = "GOADSCAP"
+	version = 2
+
+	fileHeaderSize   = 16
+	recordHeaderSize = 13
+)
+
+// Direction tells whether a packet was sent or received by the recording side.
+type Direction uint8
+
+const (
+	Sent     Direction = 1
+	Received Direction = 2
+)
+
+// String returns the name of the direction.
+func (d Direction) String() string {
+	switch d {
+	case Sent:
+		return "sent"
+	case Received:
+		return "received"
+	default:
+		return "unknown"
+	}
+}
+
+// Record is a captured packet.
+type Record struct {
+	Time      time.Time
+	Direction Direction
+	Conn      uint32 // connection the packet was sent or received on
+	Packet    *ams.Packet
+}
+
+// Writer writes a capture. It is safe for concurrent use.
+type Writer struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	closer io.Closer // nil unless created by Create
+	err    error     // first write error
+	conns  uint32    // connection numbers handed out
+}
+
+// NewWriter writes the file header to w and returns a writer for records.
+func NewWriter(w io.Writer) (*Writer, error) {
+	cw := &Writer{w: bufio.NewWriter(w)}
+
+	header := make([]byte, fileHeaderSize)
+	copy(header, magic)
+	binary.LittleEndian.PutUint16(header[8:10], version)
+	if _, err := cw.w.Write(header); err != nil {
+		return nil, fmt.Errorf("capture: write header: %w", err)
+	}
+	return cw, nil
+}
+
+// Create creates a capture file. Close flushes and closes it.
+func Create(path string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("capture: %w", err)
+	}
+	w, err := NewWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.closer = f
+	return w, nil
+}
+
+// Write appends a record. After a failed write all further writes fail with
+// the same error.
+func (w *Writer) Write(r Record) error {
+	header := make([]byte, recordHeaderSize)
+	binary.LittleEndian.PutUint64(header[0:8], uint64(r.Time.UnixNano()))
+	header[8] = byte(r.Direction)
+	binary.LittleEndian.PutUint32(header[9:13], r.Conn)
+
+	// The AMS/TCP length is derived from the packet, UDP packets have none
+	packet := *r.Packet
+	packet.TCPHeader = ams.TCPHeader{Length: 32 + uint32(len(packet.Data))}
+	packet.Header.DataLength = uint32(len(packet.Data))
+	frame, err := packet.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("capture: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+	if _, err := w.w.Write(header); err != nil {
+		w.err = fmt.Errorf("capture: write record: %w", err)
+		return w.err
+	}
+	if _, err := w.w.Write(frame); err != nil {
+		w.err = fmt.Errorf("capture: write record: %w", err)
+		return w.err
+	}
+	return nil
+}
+
+// NewConn returns the number of a new connection to record, starting at 1.
+// Records of each connection carry its own number, so that a reconnect or
+// several clients writing to the same capture do not mix up invoke IDs.
+func (w *Writer) NewConn() uint32 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.conns++
+	return w.conns
+}
+
+// Flush writes buffered records to the underlying writer.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.w.Flush(); err != nil {
+		w.err = fmt.Errorf("capture: flush: %w", err)
+	}
+	return w.err
+}
+
+// Close flushes the capture and closes the file of a writer from Create. It
+// returns the first error of any write.
+func (w *Writer) Close() error {
+	err := w.Flush()
+	if w.closer != nil {
+		if cerr := w.closer.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("capture: %w", cerr)
+		}
+	}
+	return err
+}
+
+// Reader reads the records of a capture.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader checks the file header of a capture and returns a reader for its
+// records.
+func NewReader(r io.Reader) (*Reader, error) {
+	cr := &Reader{r: bufio.NewReader(r)}
+
+	header := make([]byte, fileHeaderSize)
+	if _, err := io.ReadFull(cr.r, header); err != nil {
+		return nil, fmt.Errorf("capture: read header: %w", err)
+	}
+	if !bytes.Equal(header[0:8], []byte(magic)) {
+		return nil, fmt.Errorf("capture: not a capture file")
+	}
+	if v := binary.LittleEndian.Uint16(header[8:10]); v != version {
+		return nil, fmt.Errorf("capture: unsupported format version %d", v)
+	}
+	return cr, nil
+}
+
+// Next returns the next record, or io.EOF at the end of the capture.
+func (r *Reader) Next() (Record, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Record{}, io.EOF
+		}
+		return Record{}, fmt.Errorf("capture: read record: %w", err)
+	}
+
+	packet, err := ams.ReadPacket(r.r)
+	if err != nil {
+		return Record{}, fmt.Errorf("capture: read record: %w", err)
+	}
+	return Record{
+		Time:      time.Unix(0, int64(binary.LittleEndian.Uint64(header[0:8]))),
+		Direction: Direction(header[8]),
+		Conn:      binary.LittleEndian.Uint32(header[9:13]),
+		Packet:    packet,
+	}, nil
+}
+
+// ReadFile reads all records of a capture file.
+func ReadFile(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("capture: %w", err)
+	}
+	defer f.Close()
+
+	r, err := NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	for {
+		record, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
@@ -0,0 +1,216 @@
+package capture_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc"
+	"github.com/mrpasztoradam/goadstc/adssim"
+	"github.com/mrpasztoradam/goadstc/capture"
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+func TestWriterReader(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := capture.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+
+	stamp := time.Unix(1700000000, 123456789)
+	packet := ams.NewRequestPacket(ams.NetID{1, 2, 3, 4, 1, 1}, 851, ams.NetID{5, 6, 7, 8, 1, 1}, 32905,
+		uint16(ads.CmdRead), 7, []byte{0x20, 0x40, 0, 0, 0, 0, 0, 0, 4, 0, 0, 0})
+	if err := w.Write(capture.Record{Time: stamp, Direction: capture.Sent, Conn: 3, Packet: packet}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	r, err := capture.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	record, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if !record.Time.Equal(stamp) || record.Direction != capture.Sent || record.Conn != 3 ||
+		record.Packet.Header != packet.Header || !bytes.Equal(record.Packet.Data, packet.Data) {
+		t.Errorf("record = %+v, packet %+v", record, record.Packet)
+	}
+	if _, err := r.Next(); err == nil {
+		t.Error("Next() at end of capture succeeded")
+	}
+
+	if _, err := capture.NewReader(bytes.NewReader([]byte("NOTACAPTUREFILE!"))); err == nil {
+		t.Error("NewReader() accepted a file without magic")
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	server, err := adssim.StartServer("127.0.0.1:0", adssim.Config{
+		Symbols: []adssim.Symbol{{Name: "MAIN.counter", Type: "DINT", Value: []byte{42, 0, 0, 0}}},
+	})
+	if err != nil {
+		t.Fatalf("StartServer() error = %v", err)
+	}
+	defer server.Close()
+
+	var buf bytes.Buffer
+	w, err := capture.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+
+	// The session to reproduce: a read and a subscription seeing one change.
+	// The live session is recorded and changes the value after the first sample.
+	session := func(address string, change func()) (interface{}, [][]byte) {
+		t.Helper()
+		opts := []goadstc.Option{
+			goadstc.WithTarget(address),
+			goadstc.WithAMSNetID(server.NetID()),
+			goadstc.WithTimeout(2 * time.Second),
+		}
+		if change != nil {
+			opts = append(opts, goadstc.WithCapture(w))
+		}
+		client, err := goadstc.New(opts...)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Close()
+		ctx := context.Background()
+
+		if err := client.RefreshSymbols(ctx); err != nil {
+			t.Fatalf("RefreshSymbols() error = %v", err)
+		}
+		value, err := client.ReadSymbolValue(ctx, "MAIN.counter")
+		if err != nil {
+			t.Fatalf("ReadSymbolValue() error = %v", err)
+		}
+
+		sub, err := client.Subscribe(ctx, goadstc.NotificationOptions{
+			IndexGroup:       0x4020,
+			Length:           4,
+			TransmissionMode: ads.TransModeOnChange,
+		})
+		if err != nil {
+			t.Fatalf("Subscribe() error = %v", err)
+		}
+		defer sub.Close()
+
+		var samples [][]byte
+		for len(samples) < 2 {
+			select {
+			case notif := <-sub.Notifications():
+				samples = append(samples, notif.Data)
+				if len(samples) == 1 && change != nil {
+					change()
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("got %d notifications, want 2", len(samples))
+			}
+		}
+		return value, samples
+	}
+
+	// The replay keeps the recorded delays; the change comes well after the first
+	// sample, as notifications are not ordered within the same instant
+	value, samples := session(server.Addr(), func() {
+		time.Sleep(20 * time.Millisecond)
+		server.WriteSymbol("MAIN.counter", []byte{43, 0, 0, 0})
+	})
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	r, err := capture.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	var records []capture.Record
+	for {
+		record, err := r.Next()
+		if err != nil {
+			break
+		}
+		records = append(records, record)
+	}
+
+	replay, err := capture.StartReplayServer("127.0.0.1:0", records)
+	if err != nil {
+		t.Fatalf("StartReplayServer() error = %v", err)
+	}
+	defer replay.Close()
+	server.Close()
+
+	// The replayed session sees the same values without the PLC
+	replayValue, replaySamples := session(replay.Addr(), nil)
+	if replayValue != value {
+		t.Errorf("replayed value = %v, want %v", replayValue, value)
+	}
+	for i := range samples {
+		if !bytes.Equal(replaySamples[i], samples[i]) {
+			t.Errorf("replayed sample %d = %v, want %v", i, replaySamples[i], samples[i])
+		}
+	}
+
+	// Requests that were not recorded are rejected
+	client, err := goadstc.New(goadstc.WithTarget(replay.Addr()), goadstc.WithAMSNetID(server.NetID()))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+	if _, err := client.Read(context.Background(), 0x4020, 0, 2); !errors.Is(err, ads.ErrDeviceServiceNotSupported) {
+		t.Errorf("Read() of unrecorded request error = %v, want service not supported", err)
+	}
+}
+
+func TestReplayPairsByConnection(t *testing.T) {
+	plc, local := ams.NetID{5, 6, 7, 8, 1, 1}, ams.NetID{1, 2, 3, 4, 1, 1}
+	record := func(direction capture.Direction, conn uint32, command ads.CommandID, data []byte) capture.Record {
+		packet := ams.NewRequestPacket(plc, 851, local, 32905, uint16(command), 1, data)
+		if direction == capture.Received {
+			packet = ams.NewRequestPacket(local, 32905, plc, 851, uint16(command), 1, data)
+			packet.Header.StateFlags = ams.StateFlagsTCPResponse
+		}
+		return capture.Record{Direction: direction, Conn: conn, Packet: packet}
+	}
+	read := func(conn uint32, offset uint32) capture.Record {
+		req := ads.ReadRequest{IndexGroup: 0x4020, IndexOffset: offset, Length: 1}
+		data, _ := req.MarshalBinary()
+		return record(capture.Sent, conn, ads.CmdRead, data)
+	}
+	value := func(conn uint32, value byte) capture.Record {
+		return record(capture.Received, conn, ads.CmdRead, []byte{0, 0, 0, 0, 1, 0, 0, 0, value})
+	}
+
+	// All requests use invoke ID 1; the read response of the first connection
+	// arrives after the second connection sent its read
+	replay, err := capture.StartReplayServer("127.0.0.1:0", []capture.Record{
+		record(capture.Sent, 1, ads.CmdReadState, nil),
+		record(capture.Received, 1, ads.CmdReadState, []byte{0, 0, 0, 0, 5, 0, 0, 0}),
+		read(1, 0), read(2, 1), value(1, 10), value(2, 11),
+	})
+	if err != nil {
+		t.Fatalf("StartReplayServer() error = %v", err)
+	}
+	defer replay.Close()
+
+	client, err := goadstc.New(goadstc.WithTarget(replay.Addr()), goadstc.WithAMSNetID(plc))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+	for offset, want := range []byte{10, 11} {
+		data, err := client.Read(context.Background(), 0x4020, uint32(offset), 1)
+		if err != nil || len(data) != 1 || data[0] != want {
+			t.Errorf("Read(offset %d) = %v, %v; want [%d]", offset, data, err, want)
+		}
+	}
+}
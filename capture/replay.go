@@ -0,0 +1,372 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+// requestKey identifies equal requests: same command, target port and data.
+type requestKey struct {
+	command uint16
+	port    ams.Port
+	data    string
+}
+
+// connID identifies an invoke ID or notification handle within the connection
+// it was used on.
+type connID struct {
+	conn uint32
+	id   uint32
+}
+
+// recordedResponse is a recorded response together with the notification
+// samples recorded for it, if it added a notification.
+type recordedResponse struct {
+	packet  *ams.Packet
+	handle  uint32
+	samples []recordedSample
+}
+
+// recordedSample is a notification sample and its delay after the response
+// that added the notification.
+type recordedSample struct {
+	delay time.Duration
+	stamp uint64
+	data  []byte
+}
+
+// ReplayServer answers AMS/TCP requests with the responses recorded for
+// equal requests.
+//
+// Requests match a recorded request with the same command, target port and
+// data. Recorded responses to equal requests are returned in recorded order;
+// once they are used up, the last one is repeated. Requests without a
+// recording are answered with ADS error 0x701 (service not supported).
+// Notification samples recorded after an AddDeviceNotification are sent again
+// with their recorded delay and PLC timestamp until the notification is
+// deleted.
+type ReplayServer struct {
+	listener  net.Listener
+	responses map[requestKey][]*recordedResponse
+
+	mu     sync.Mutex
+	served map[requestKey]int // responses used per request
+	conns  map[*replayConn]struct{}
+	closed bool
+
+	wg sync.WaitGroup
+}
+
+// StartReplayServer listens on address and answers requests from records,
+// the traffic of a client as captured by a Writer.
+func StartReplayServer(address string, records []Record) (*ReplayServer, error) {
+	s := &ReplayServer{
+		responses: pairRecords(records),
+		served:    make(map[requestKey]int),
+		conns:     make(map[*replayConn]struct{}),
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("capture: listen %s: %w", address, err)
+	}
+	s.listener = listener
+
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// pairRecords matches the recorded requests with their responses and the
+// notification samples with the responses that added them.
+func pairRecords(records []Record) map[requestKey][]*recordedResponse {
+	responses := make(map[requestKey][]*recordedResponse)
+	requests := make(map[connID]requestKey)          // by invoke ID
+	added := make(map[connID]*recordedResponse)      // by notification handle
+	addedAt := make(map[*recordedResponse]time.Time) // time of the add response
+
+	for _, r := range records {
+		packet := r.Packet
+		switch {
+		case r.Direction == Sent && packet.Header.IsRequest():
+			requests[connID{r.Conn, packet.Header.InvokeID}] = keyOf(packet)
+
+		case r.Direction == Received && !packet.Header.IsRequest():
+			invoke := connID{r.Conn, packet.Header.InvokeID}
+			key, ok := requests[invoke]
+			if !ok || key.command != packet.Header.CommandID {
+				continue
+			}
+			delete(requests, invoke)
+
+			resp := &recordedResponse{packet: packet}
+			responses[key] = append(responses[key], resp)
+
+			if ads.CommandID(packet.Header.CommandID) == ads.CmdAddDeviceNotification && packet.Header.ErrorCode == 0 {
+				var addResp ads.AddDeviceNotificationResponse
+				if addResp.UnmarshalBinary(packet.Data) == nil && addResp.Result == 0 {
+					resp.handle = addResp.NotificationHandle
+					added[connID{r.Conn, resp.handle}] = resp
+					addedAt[resp] = r.Time
+				}
+			}
+
+		case r.Direction == Received && ads.CommandID(packet.Header.CommandID) == ads.CmdDeviceNotification:
+			var notif ads.DeviceNotificationRequest
+			if notif.UnmarshalBinary(packet.Data) != nil {
+				continue
+			}
+			for _, stamp := range notif.StampHeaders {
+				for _, sample := range stamp.Samples {
+					resp, ok := added[connID{r.Conn, sample.NotificationHandle}]
+					if !ok {
+						continue
+					}
+					resp.samples = append(resp.samples, recordedSample{
+						delay: r.Time.Sub(addedAt[resp]),
+						stamp: stamp.Timestamp,
+						data:  sample.Data,
+					})
+				}
+			}
+		}
+	}
+	return responses
+}
+
+// keyOf returns the key of a request.
+func keyOf(packet *ams.Packet) requestKey {
+	return requestKey{
+		command: packet.Header.CommandID,
+		port:    packet.Header.TargetPort,
+		data:    string(packet.Data),
+	}
+}
+
+// Addr returns the address the server listens on.
+func (s *ReplayServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server and closes all connections.
+func (s *ReplayServer) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	conns := make([]*replayConn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	for _, conn := range conns {
+		conn.netConn.Close()
+	}
+	s.wg.Wait()
+	return err
+}
+
+// serve accepts connections until the listener is closed.
+func (s *ReplayServer) serve() {
+	defer s.wg.Done()
+
+	for {
+		netConn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		conn := &replayConn{
+			server:        s,
+			netConn:       netConn,
+			notifications: make(map[uint32]chan struct{}),
+			done:          make(chan struct{}),
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			netConn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go conn.run()
+	}
+}
+
+// response returns the next recorded response to a request.
+func (s *ReplayServer) response(packet *ams.Packet) *recordedResponse {
+	key := keyOf(packet)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	responses := s.responses[key]
+	if len(responses) == 0 {
+		return nil
+	}
+	i := s.served[key]
+	if i >= len(responses) {
+		return responses[len(responses)-1]
+	}
+	s.served[key] = i + 1
+	return responses[i]
+}
+
+// replayConn is a client connection to the replay server.
+type replayConn struct {
+	server  *ReplayServer
+	netConn net.Conn
+	writeMu sync.Mutex
+
+	mu            sync.Mutex
+	notifications map[uint32]chan struct{} // stop channels by handle
+	invokeID      uint32                   // of the last notification sent
+
+	done chan struct{} // closed when the connection ends
+}
+
+// run answers the requests of the connection until it is closed.
+func (c *replayConn) run() {
+	defer c.server.wg.Done()
+	defer func() {
+		close(c.done)
+		c.netConn.Close()
+		c.server.mu.Lock()
+		delete(c.server.conns, c)
+		c.server.mu.Unlock()
+	}()
+
+	for {
+		packet, err := ams.ReadPacket(c.netConn)
+		if err != nil {
+			return
+		}
+		if !packet.Header.IsRequest() {
+			continue
+		}
+
+		if ads.CommandID(packet.Header.CommandID) == ads.CmdDelDeviceNotification {
+			var req ads.DeleteDeviceNotificationRequest
+			if req.UnmarshalBinary(packet.Data) == nil {
+				c.stopNotification(req.NotificationHandle)
+			}
+		}
+
+		resp := &ams.Packet{Header: ams.Header{
+			TargetNetID: packet.Header.SourceNetID,
+			TargetPort:  packet.Header.SourcePort,
+			SourceNetID: packet.Header.TargetNetID,
+			SourcePort:  packet.Header.TargetPort,
+			CommandID:   packet.Header.CommandID,
+			StateFlags:  ams.StateFlagsTCPResponse,
+			InvokeID:    packet.Header.InvokeID,
+		}}
+		recorded := c.server.response(packet)
+		if recorded != nil {
+			resp.Header.ErrorCode = recorded.packet.Header.ErrorCode
+			resp.Data = recorded.packet.Data
+		} else {
+			resp.Header.ErrorCode = uint32(ads.ErrDeviceServiceNotSupported)
+		}
+		resp.Header.DataLength = uint32(len(resp.Data))
+		resp.TCPHeader.Length = 32 + resp.Header.DataLength
+
+		if err := c.write(resp); err != nil {
+			return
+		}
+		if recorded != nil && len(recorded.samples) > 0 {
+			c.startNotification(packet.Header, recorded)
+		}
+	}
+}
+
+// write sends a packet; responses and notifications may be sent concurrently.
+func (c *replayConn) write(packet *ams.Packet) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return ams.WritePacket(c.netConn, packet)
+}
+
+// startNotification replays the samples of an added notification.
+func (c *replayConn) startNotification(req ams.Header, recorded *recordedResponse) {
+	stop := make(chan struct{})
+	c.mu.Lock()
+	if old, ok := c.notifications[recorded.handle]; ok {
+		close(old)
+	}
+	c.notifications[recorded.handle] = stop
+	c.mu.Unlock()
+
+	c.server.wg.Add(1)
+	go func() {
+		defer c.server.wg.Done()
+
+		start := time.Now()
+		for _, sample := range recorded.samples {
+			timer := time.NewTimer(time.Until(start.Add(sample.delay)))
+			select {
+			case <-timer.C:
+			case <-stop:
+				timer.Stop()
+				return
+			case <-c.done:
+				timer.Stop()
+				return
+			}
+			if err := c.sendNotification(req, recorded.handle, sample); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// stopNotification ends the replay of a notification.
+func (c *replayConn) stopNotification(handle uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stop, ok := c.notifications[handle]; ok {
+		close(stop)
+		delete(c.notifications, handle)
+	}
+}
+
+// sendNotification pushes a DeviceNotification with a single sample to the
+// sender of the add request.
+func (c *replayConn) sendNotification(req ams.Header, handle uint32, sample recordedSample) error {
+	notif := ads.DeviceNotificationRequest{StampHeaders: []ads.StampHeader{{
+		Timestamp: sample.stamp,
+		Samples:   []ads.NotificationSample{{NotificationHandle: handle, Data: sample.data}},
+	}}}
+	payload, err := notif.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.invokeID++
+	invokeID := c.invokeID
+	c.mu.Unlock()
+
+	packet := ams.NewRequestPacket(
+		req.SourceNetID, req.SourcePort,
+		req.TargetNetID, req.TargetPort,
+		uint16(ads.CmdDeviceNotification), invokeID, payload,
+	)
+	return c.write(packet)
+}
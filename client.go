@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/mrpasztoradam/goadstc/capture"
 	"github.com/mrpasztoradam/goadstc/internal/ads"
 	"github.com/mrpasztoradam/goadstc/internal/ams"
 	"github.com/mrpasztoradam/goadstc/internal/symbols"
@@ -30,19 +31,47 @@ const (
 // ConnectionStateCallback is called when connection state changes.
 type ConnectionStateCallback func(oldState, newState ConnectionState, err error)
 
+// SymbolChangeCallback is called after the PLC symbol version changed (online change
+// or configuration activation) and the symbol table was reloaded.
+// err is non-nil if reloading the symbol table failed.
+type SymbolChangeCallback func(oldVersion, newVersion uint8, err error)
+
+// amsConn is the connection requests are sent over: a transport.Conn of the
+// client or its port on a shared Router connection.
+type amsConn interface {
+	NextInvokeID() uint32
+	SendRequest(ctx context.Context, req *ams.Packet) (*ams.Packet, error)
+	SetNotificationHandler(handler transport.NotificationHandler)
+	Close() error
+}
+
 // Client represents an ADS client connection.
 type Client struct {
-	conn            *transport.Conn
+	conn            amsConn // nil while disconnected (guarded by connMu)
+	connMu          sync.RWMutex
 	targetNetID     ams.NetID
 	targetPort      ams.Port
 	sourceNetID     ams.NetID
 	sourcePort      ams.Port
 	subscriptions   map[uint32]*Subscription
+	suspended       map[*Subscription]struct{} // subscriptions whose re-establishment failed (guarded by subscriptionsMu)
 	subscriptionsMu sync.RWMutex
 	symbolTable     *symbols.Table
 	symbolTableMu   sync.RWMutex
 	typeRegistry    *symbols.TypeRegistry
 	typeRegistryMu  sync.RWMutex
+	typesLoaded     bool                       // data type table uploaded (guarded by typeRegistryMu)
+	typesRetryAt    time.Time                  // no upload before, after a failed one (guarded by typeRegistryMu)
+	handles         *handleCache               // nil unless WithHandleAccess is set
+	dynamicSymbols  map[string]*symbols.Symbol // PLC-resolved paths through pointers/references (guarded by symbolTableMu)
+	symbolGen       uint64                     // incremented on every symbol table load (guarded by symbolTableMu)
+
+	// Online change detection
+	symbolWatch          *Subscription
+	symbolWatchMu        sync.Mutex
+	symbolVersion        uint8
+	symbolVersionKnown   bool
+	symbolChangeCallback SymbolChangeCallback
 
 	// Reconnection support
 	config            *clientConfig
@@ -60,6 +89,9 @@ type Client struct {
 	// Observability
 	logger  Logger
 	metrics Metrics
+
+	// Notification callbacks
+	dispatcher *dispatcher
 }
 
 // DeviceInfo represents device information returned by ReadDeviceInfo.
@@ -90,8 +122,16 @@ type clientConfig struct {
 	maxReconnectDelay time.Duration
 	healthCheckPeriod time.Duration
 	stateCallback     ConnectionStateCallback
+	handleAccess      bool
+	symbolCallback    SymbolChangeCallback
 	logger            Logger
 	metrics           Metrics
+	dispatch          DispatcherConfig
+	udp               bool
+	udpRetransmit     time.Duration
+	route             *RouteOptions
+	router            *Router
+	capture           *capture.Writer
 }
 
 // WithTarget sets the target TCP address (required).
@@ -148,6 +188,38 @@ func WithTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithUDPTransport sends AMS over UDP instead of TCP (optional).
+// The target address then names the UDP port of the AMS router, usually
+// 48899. Requests that stay unanswered for retransmitInterval are sent again
+// until the request timeout (0 selects the default of 200ms), and duplicate
+// datagrams are dropped. Use it for lightweight polling where a TCP session
+// through the router is not available; a retransmitted write may be executed
+// twice if only its response was lost.
+func WithUDPTransport(retransmitInterval time.Duration) Option {
+	return func(c *clientConfig) error {
+		if retransmitInterval < 0 {
+			return fmt.Errorf("goadstc: retransmit interval cannot be negative")
+		}
+		c.udp = true
+		c.udpRetransmit = retransmitInterval
+		return nil
+	}
+}
+
+// WithCapture records every packet the client sends and receives to w with a
+// timestamp (optional), for example to replay field problems with
+// capture.StartReplayServer. The writer is shared across reconnects, each
+// connection recorded with its own number, and not closed with the client. Connections of a Router are not recorded.
+func WithCapture(w *capture.Writer) Option {
+	return func(c *clientConfig) error {
+		if w == nil {
+			return fmt.Errorf("goadstc: capture writer cannot be nil")
+		}
+		c.capture = w
+		return nil
+	}
+}
+
 // WithAutoReconnect enables automatic reconnection on connection loss (optional).
 // When enabled, the client will automatically attempt to reconnect with exponential backoff.
 // Subscriptions will be re-established after successful reconnection.
@@ -192,6 +264,34 @@ func WithStateCallback(callback ConnectionStateCallback) Option {
 	}
 }
 
+// WithHandleAccess enables handle-based symbol access (optional).
+// ReadSymbol and WriteSymbol (and everything built on them) then acquire a symbol
+// handle per symbol path on first use, cache it, and access the value by handle
+// (0xF005) instead of by the IndexGroup/IndexOffset from the uploaded symbol table.
+// The PLC resolves the current address, so values stay correct after an online change.
+// ReadMultipleSymbolValues and WriteMultipleSymbolValues send sum requests of
+// values by handle, and BIT struct members are accessed through the handle of
+// the struct holding them.
+// The cache is dropped when the PLC symbol version changes or a handle is rejected
+// with ADS error 0x710/0x711, and handles are re-acquired transparently.
+func WithHandleAccess() Option {
+	return func(c *clientConfig) error {
+		c.handleAccess = true
+		return nil
+	}
+}
+
+// WithSymbolChangeCallback sets a callback invoked after an online change (optional).
+// Once symbols are loaded, the client watches the PLC symbol version (0xF008) and
+// reloads the symbol table and drops fetched type information when it changes,
+// whether or not a callback is set.
+func WithSymbolChangeCallback(callback SymbolChangeCallback) Option {
+	return func(c *clientConfig) error {
+		c.symbolCallback = callback
+		return nil
+	}
+}
+
 // New creates a new ADS client with the given options.
 func New(opts ...Option) (*Client, error) {
 	cfg := &clientConfig{
@@ -213,24 +313,56 @@ func New(opts ...Option) (*Client, error) {
 		return nil, fmt.Errorf("goadstc: target address is required")
 	}
 
+	if cfg.router != nil {
+		if cfg.udp {
+			return nil, fmt.Errorf("goadstc: the UDP transport cannot be used with a router")
+		}
+		if cfg.capture != nil {
+			return nil, fmt.Errorf("goadstc: a capture cannot be used with a router")
+		}
+		port, err := cfg.router.allocatePort()
+		if err != nil {
+			return nil, err
+		}
+		cfg.sourcePort = port
+	}
+
+	// Register the route before anything uses the source NetID
+	if cfg.route != nil {
+		if err := registerRoute(cfg); err != nil {
+			cfg.logger.Error("route registration failed", "error", err)
+			if cfg.router != nil {
+				cfg.router.releasePort(cfg.sourcePort)
+			}
+			return nil, fmt.Errorf("goadstc: add route: %w", err)
+		}
+	}
+
 	shutdownCtx, shutdownCancel := context.WithCancel(context.Background())
 
 	client := &Client{
-		config:            cfg,
-		targetNetID:       cfg.targetNetID,
-		targetPort:        cfg.targetPort,
-		sourceNetID:       cfg.sourceNetID,
-		sourcePort:        cfg.sourcePort,
-		subscriptions:     make(map[uint32]*Subscription),
-		symbolTable:       symbols.NewTable(),
-		typeRegistry:      symbols.NewTypeRegistry(),
-		autoReconnect:     cfg.autoReconnect,
-		maxReconnectDelay: cfg.maxReconnectDelay,
-		stateCallback:     cfg.stateCallback,
-		shutdownCtx:       shutdownCtx,
-		shutdownCancel:    shutdownCancel,
-		logger:            cfg.logger,
-		metrics:           cfg.metrics,
+		config:               cfg,
+		targetNetID:          cfg.targetNetID,
+		targetPort:           cfg.targetPort,
+		sourceNetID:          cfg.sourceNetID,
+		sourcePort:           cfg.sourcePort,
+		subscriptions:        make(map[uint32]*Subscription),
+		suspended:            make(map[*Subscription]struct{}),
+		symbolTable:          symbols.NewTable(),
+		typeRegistry:         symbols.NewTypeRegistry(),
+		autoReconnect:        cfg.autoReconnect,
+		maxReconnectDelay:    cfg.maxReconnectDelay,
+		stateCallback:        cfg.stateCallback,
+		symbolChangeCallback: cfg.symbolCallback,
+		shutdownCtx:          shutdownCtx,
+		shutdownCancel:       shutdownCancel,
+		logger:               cfg.logger,
+		metrics:              cfg.metrics,
+	}
+	client.dispatcher = newDispatcher(shutdownCtx, cfg.dispatch, cfg.logger, cfg.metrics)
+
+	if cfg.handleAccess {
+		client.handles = newHandleCache()
 	}
 
 	client.logger.Info("creating new ADS client",
@@ -242,6 +374,9 @@ func New(opts ...Option) (*Client, error) {
 	// Initial connection
 	if err := client.connect(); err != nil {
 		if !cfg.autoReconnect {
+			if cfg.router != nil {
+				cfg.router.releasePort(cfg.sourcePort)
+			}
 			return nil, fmt.Errorf("goadstc: connection failed: %w", err)
 		}
 		// With auto-reconnect, start in disconnected state
@@ -265,7 +400,24 @@ func (c *Client) connect() error {
 	ctx, cancel := context.WithTimeout(context.Background(), c.config.timeout)
 	defer cancel()
 
-	conn, err := transport.Dial(ctx, c.config.address, c.config.timeout)
+	var conn amsConn
+	var err error
+	if c.config.router != nil {
+		conn, err = c.config.router.open(ctx, c.config.address, c.config.timeout, c.sourcePort)
+	} else {
+		var tc *transport.Conn
+		if c.config.udp {
+			tc, err = transport.DialUDP(ctx, c.config.address, c.config.timeout, c.config.udpRetransmit)
+		} else {
+			tc, err = transport.Dial(ctx, c.config.address, c.config.timeout)
+		}
+		if err == nil {
+			if c.config.capture != nil {
+				tc.SetRecorder(captureRecorder(c.config.capture))
+			}
+			conn = tc
+		}
+	}
 	if err != nil {
 		c.logger.Error("connection failed", "error", err)
 		c.metrics.ConnectionFailures()
@@ -274,7 +426,7 @@ func (c *Client) connect() error {
 		return err
 	}
 
-	c.conn = conn
+	c.setConn(conn)
 
 	// Set up notification handler
 	conn.SetNotificationHandler(c.handleNotification)
@@ -296,7 +448,7 @@ func (c *Client) connect() error {
 	if err != nil {
 		c.logger.Error("connection verification failed", "error", err)
 		conn.Close()
-		c.conn = nil
+		c.setConn(nil)
 		c.metrics.ConnectionFailures()
 		ce := ClassifyError(err, "connect")
 		c.metrics.ErrorOccurred(ce.Category, "connect")
@@ -308,7 +460,7 @@ func (c *Client) connect() error {
 		adsErr := ads.Error(respPacket.Header.ErrorCode)
 		c.logger.Error("connection verification failed", "adsError", adsErr)
 		conn.Close()
-		c.conn = nil
+		c.setConn(nil)
 		c.metrics.ConnectionFailures()
 		c.metrics.ErrorOccurred(ErrorCategoryADS, "connect")
 		return fmt.Errorf("connection verification failed: %w", adsErr)
@@ -337,6 +489,17 @@ func (c *Client) Close() error {
 		}
 	}
 
+	// Release cached symbol handles
+	if c.handles != nil {
+		if handles := c.handles.reset(); len(handles) > 0 && c.currentConn() != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), c.config.timeout)
+			if err := c.ReleaseSymbolHandles(ctx, handles...); err != nil {
+				c.logger.Warn("failed to release symbol handles", "error", err)
+			}
+			cancel()
+		}
+	}
+
 	// Close all subscriptions
 	c.subscriptionsMu.Lock()
 	subs := make([]*Subscription, 0, len(c.subscriptions))
@@ -346,16 +509,53 @@ func (c *Client) Close() error {
 	c.subscriptionsMu.Unlock()
 
 	for _, sub := range subs {
+		if sub.group != nil {
+			// Closes the group channel as well as all members
+			sub.group.Close()
+			continue
+		}
 		sub.Close()
 	}
 
 	c.metrics.ConnectionActive(false)
 	c.metrics.SubscriptionsActive(0)
 
-	if c.conn != nil {
-		return c.conn.Close()
+	var err error
+	if conn := c.currentConn(); conn != nil {
+		err = conn.Close()
 	}
-	return nil
+	if c.config.router != nil {
+		c.config.router.releasePort(c.sourcePort)
+	}
+	return err
+}
+
+// captureRecorder returns a transport recorder writing to a capture as a new
+// connection. Write errors are kept by the writer and reported when it is
+// closed.
+func captureRecorder(w *capture.Writer) transport.Recorder {
+	conn := w.NewConn()
+	return func(sent bool, packet *ams.Packet) {
+		direction := capture.Received
+		if sent {
+			direction = capture.Sent
+		}
+		w.Write(capture.Record{Time: time.Now(), Direction: direction, Conn: conn, Packet: packet})
+	}
+}
+
+// currentConn returns the current connection, nil while disconnected.
+func (c *Client) currentConn() amsConn {
+	c.connMu.RLock()
+	defer c.connMu.RUnlock()
+	return c.conn
+}
+
+// setConn replaces the current connection.
+func (c *Client) setConn(conn amsConn) {
+	c.connMu.Lock()
+	c.conn = conn
+	c.connMu.Unlock()
 }
 
 // reconnectLoop handles automatic reconnection with exponential backoff.
@@ -396,9 +596,17 @@ func (c *Client) reconnectLoop() {
 			// Reconnection successful!
 			c.logger.Info("reconnection successful", "attempt", attempt)
 
+			// Symbol handles do not survive a new connection
+			if c.handles != nil {
+				c.handles.reset()
+			}
+
 			// Re-establish subscriptions
 			c.reestablishSubscriptions()
 
+			// Catch online changes that happened while disconnected
+			c.restartSymbolVersionWatch()
+
 			// Reset attempts counter to 0 to allow future reconnections
 			c.reconnectMu.Lock()
 			c.reconnectAttempts = 0
@@ -438,51 +646,21 @@ func (c *Client) triggerReconnection(err error) {
 	}
 
 	// Close current connection if still present
-	if c.conn != nil {
-		c.conn.Close()
-		c.conn = nil
+	c.connMu.Lock()
+	conn := c.conn
+	c.conn = nil
+	c.connMu.Unlock()
+	if conn != nil {
+		conn.Close()
 	}
 
 	c.notifyStateChange(transport.StateConnected, transport.StateError, err)
+	c.suspendSubscriptions(err)
 
 	// Start reconnection loop in background
 	go c.reconnectLoop()
 }
 
-// reestablishSubscriptions re-creates all subscriptions after reconnection.
-func (c *Client) reestablishSubscriptions() {
-	c.subscriptionsMu.Lock()
-	oldSubs := make(map[uint32]NotificationOptions)
-	for handle, sub := range c.subscriptions {
-		oldSubs[handle] = sub.opts
-		// Close old subscription (just close channel, don't send delete to PLC)
-		sub.closeMu.Lock()
-		if !sub.closed {
-			sub.closed = true
-			close(sub.notifCh)
-		}
-		sub.closeMu.Unlock()
-	}
-	// Clear old subscriptions
-	c.subscriptions = make(map[uint32]*Subscription)
-	c.subscriptionsMu.Unlock()
-
-	// Re-create subscriptions
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	for _, opts := range oldSubs {
-		// Try to re-establish subscription
-		_, err := c.Subscribe(ctx, opts)
-		if err != nil {
-			// Log error but continue with other subscriptions
-			// Application should handle subscription failures via state callback
-			c.notifyStateChange(transport.StateConnected, transport.StateConnected,
-				fmt.Errorf("failed to re-establish subscription: %w", err))
-		}
-	}
-}
-
 // notifyStateChange calls the state callback if configured.
 func (c *Client) notifyStateChange(oldState, newState transport.ConnectionState, err error) {
 	c.stateCallbackMu.RLock()
@@ -550,14 +728,19 @@ func (c *Client) sendRequest(ctx context.Context, commandID ads.CommandID, reqDa
 		return c.sendRequestWithRetry(ctx, commandID, reqData, 3)
 	}
 
-	invokeID := c.conn.NextInvokeID()
+	conn := c.currentConn()
+	if conn == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	invokeID := conn.NextInvokeID()
 	reqPacket := ams.NewRequestPacket(
 		c.targetNetID, c.targetPort,
 		c.sourceNetID, c.sourcePort,
 		uint16(commandID), invokeID, reqData,
 	)
 
-	respPacket, err := c.conn.SendRequest(ctx, reqPacket)
+	respPacket, err := conn.SendRequest(ctx, reqPacket)
 	if err != nil {
 		return nil, err
 	}
@@ -574,18 +757,19 @@ func (c *Client) sendRequestWithRetry(ctx context.Context, commandID ads.Command
 	var lastErr error
 
 	for attempt := 0; attempt <= maxRetries; attempt++ {
-		if c.conn == nil {
+		conn := c.currentConn()
+		if conn == nil {
 			return nil, fmt.Errorf("not connected")
 		}
 
-		invokeID := c.conn.NextInvokeID()
+		invokeID := conn.NextInvokeID()
 		reqPacket := ams.NewRequestPacket(
 			c.targetNetID, c.targetPort,
 			c.sourceNetID, c.sourcePort,
 			uint16(commandID), invokeID, reqData,
 		)
 
-		respPacket, err := c.conn.SendRequest(ctx, reqPacket)
+		respPacket, err := conn.SendRequest(ctx, reqPacket)
 		if err == nil {
 			if respPacket.Header.ErrorCode != 0 {
 				return nil, ads.Error(respPacket.Header.ErrorCode)
@@ -736,6 +920,7 @@ func (c *Client) UploadSymbolTable(ctx context.Context) ([]byte, error) {
 // RefreshSymbols downloads and parses the symbol table from the PLC.
 // This method should be called before using symbol-based operations.
 // It can be called multiple times to refresh the cache if the PLC program changes.
+// After the first successful load, online changes are detected and reloaded automatically.
 func (c *Client) RefreshSymbols(ctx context.Context) error {
 	data, err := c.UploadSymbolTable(ctx)
 	if err != nil {
@@ -743,12 +928,15 @@ func (c *Client) RefreshSymbols(ctx context.Context) error {
 	}
 
 	c.symbolTableMu.Lock()
-	defer c.symbolTableMu.Unlock()
-
 	if err := c.symbolTable.Load(data); err != nil {
+		c.symbolTableMu.Unlock()
 		return fmt.Errorf("load symbols: %w", err)
 	}
+	c.dynamicSymbols = nil
+	c.symbolGen++
+	c.symbolTableMu.Unlock()
 
+	c.startSymbolVersionWatch(ctx)
 	return nil
 }
 
@@ -764,6 +952,14 @@ func (c *Client) ensureSymbolsLoaded(ctx context.Context) error {
 	return nil
 }
 
+// symbolGeneration returns a counter that changes whenever the symbol table is reloaded.
+// Callers caching resolved addresses compare it to detect online changes.
+func (c *Client) symbolGeneration() uint64 {
+	c.symbolTableMu.RLock()
+	defer c.symbolTableMu.RUnlock()
+	return c.symbolGen
+}
+
 // GetSymbol retrieves symbol information by name.
 func (c *Client) GetSymbol(name string) (*symbols.Symbol, error) {
 	c.symbolTableMu.RLock()
@@ -797,47 +993,6 @@ func (c *Client) FindSymbols(ctx context.Context, pattern string) ([]*symbols.Sy
 	return c.symbolTable.Find(pattern)
 }
 
-// parseArrayAccess parses array notation like "MAIN.myArray[5]" or "MAIN.matrix[2][3]"
-// Returns: base symbol name, array indices, error
-func parseArrayAccess(symbolName string) (string, []int, error) {
-	// Check if there's array notation
-	if !strings.Contains(symbolName, "[") {
-		return symbolName, nil, nil
-	}
-
-	// Find the base name (everything before first '[')
-	firstBracket := strings.Index(symbolName, "[")
-	baseName := symbolName[:firstBracket]
-
-	// Parse indices
-	var indices []int
-	remainder := symbolName[firstBracket:]
-
-	for len(remainder) > 0 && remainder[0] == '[' {
-		closeBracket := strings.Index(remainder, "]")
-		if closeBracket == -1 {
-			return "", nil, fmt.Errorf("invalid array notation: missing ']'")
-		}
-
-		indexStr := remainder[1:closeBracket]
-		var idx int
-		n, err := fmt.Sscanf(indexStr, "%d", &idx)
-		if err != nil || n != 1 {
-			return "", nil, fmt.Errorf("invalid array index: %q", indexStr)
-		}
-		indices = append(indices, idx)
-
-		remainder = remainder[closeBracket+1:]
-	}
-
-	// If there's a remainder (like ".fieldName"), add it to the base name
-	if len(remainder) > 0 {
-		baseName = baseName + remainder
-	}
-
-	return baseName, indices, nil
-}
-
 // extractArrayElementType extracts the element type from an array type name.
 // e.g., "ARRAY [0..9] OF INT" -> "INT"
 // e.g., "ARRAY [0..4] OF TestSt" -> "TestSt"
@@ -850,180 +1005,103 @@ func extractArrayElementType(arrayTypeName string) (string, bool) {
 	return elementType, true
 }
 
-// resolveArraySymbol resolves array element access and returns the adjusted symbol info.
-// For "MAIN.myArray[5]", it returns the base symbol with IndexOffset adjusted for element 5.
-// For "MAIN.myArray[5].field", it resolves both array and struct field offsets.
-func (c *Client) resolveArraySymbol(ctx context.Context, symbolName string) (indexGroup, indexOffset, size uint32, err error) {
-	// Check if this is array element with struct field access (e.g., "MAIN.aStruct[0].uiTest")
-	if hasStructFieldAfterArray(symbolName) {
-		return c.resolveArrayStructField(ctx, symbolName)
-	}
-
-	// Handle simple array access or no array access
-	return c.resolveSimpleArray(ctx, symbolName)
-}
-
-// hasStructFieldAfterArray checks if the symbol has a struct field after array notation.
-func hasStructFieldAfterArray(symbolName string) bool {
-	firstBracket := strings.Index(symbolName, "[")
-	if firstBracket == -1 {
-		return false
-	}
-	closeBracket := strings.Index(symbolName[firstBracket:], "]")
-	if closeBracket == -1 {
-		return false
-	}
-	afterBracket := firstBracket + closeBracket + 1
-	return afterBracket < len(symbolName) && symbolName[afterBracket] == '.'
-}
-
-// resolveArrayStructField handles array element with struct field access (e.g., "MAIN.aStruct[0].uiTest").
-func (c *Client) resolveArrayStructField(ctx context.Context, symbolName string) (indexGroup, indexOffset, size uint32, err error) {
-	// Split into array part and struct field
-	arrayPart, structField, err := splitArrayAndField(symbolName)
-	if err != nil {
-		return 0, 0, 0, err
-	}
-
-	// Parse array access
-	baseName, indices, err := parseArrayAccess(arrayPart)
-	if err != nil {
-		return 0, 0, 0, err
-	}
-
-	if len(indices) > 1 {
-		return 0, 0, 0, fmt.Errorf("multi-dimensional arrays not yet supported")
-	}
-
-	// Get base symbol
-	symbol, err := c.GetSymbol(baseName)
-	if err != nil {
-		return 0, 0, 0, fmt.Errorf("resolve symbol %q: %w", baseName, err)
-	}
-
-	// Get element type and calculate offsets
-	elementTypeName, isArray := extractArrayElementType(symbol.Type.Name)
-	if !isArray {
-		return 0, 0, 0, fmt.Errorf("%q is not an array type", baseName)
-	}
-
-	elementTypeInfo, err := c.getOrFetchTypeInfo(ctx, elementTypeName)
-	if err != nil {
-		return 0, 0, 0, fmt.Errorf("get element type info for %q: %w", elementTypeName, err)
-	}
-
-	// Calculate array element offset
-	elementOffset := uint32(indices[0]) * elementTypeInfo.Size
-
-	// Find the struct field offset within the element
-	fieldInfo, found := findFieldInType(elementTypeInfo, structField)
-	if !found {
-		return 0, 0, 0, fmt.Errorf("field %q not found in type %q", structField, elementTypeName)
-	}
-
-	return symbol.IndexGroup, symbol.IndexOffset + elementOffset + fieldInfo.Offset, fieldInfo.Type.Size, nil
-}
-
-// splitArrayAndField splits a symbol like "MAIN.aStruct[0].uiTest" into array part and field.
-func splitArrayAndField(symbolName string) (arrayPart, structField string, err error) {
-	firstBracket := strings.Index(symbolName, "[")
-	closeBracket := strings.Index(symbolName[firstBracket:], "]")
-	afterBracket := firstBracket + closeBracket + 1
-
-	arrayPart = symbolName[:afterBracket]
-	structField = symbolName[afterBracket+1:] // Skip the dot
-	return arrayPart, structField, nil
-}
-
-// resolveSimpleArray handles simple array access without struct fields.
-func (c *Client) resolveSimpleArray(ctx context.Context, symbolName string) (indexGroup, indexOffset, size uint32, err error) {
-	baseName, indices, err := parseArrayAccess(symbolName)
-	if err != nil {
-		return 0, 0, 0, err
+// ReadSymbol reads data from a PLC symbol by name.
+// Supports array element and struct field access: "MAIN.myArray[5]",
+// "GVL.grid[2,-3].x" or "MAIN.matrix[1][2]". Indices are checked against the
+// array bounds before anything is sent to the PLC.
+// Pointers are dereferenced with "^" ("MAIN.pAxis^.Status") and references are
+// followed implicitly; such paths are resolved by the PLC and accessed by handle.
+// A BIT struct member is returned as its value, LSB first, in the bytes
+// holding it.
+// Automatically loads symbol table on first call.
+func (c *Client) ReadSymbol(ctx context.Context, symbolName string) ([]byte, error) {
+	if err := c.ensureSymbolsLoaded(ctx); err != nil {
+		return nil, err
 	}
 
-	// Get the base symbol
-	symbol, err := c.GetSymbol(baseName)
+	target, err := c.resolveSymbolPath(ctx, symbolName)
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("resolve symbol %q: %w", baseName, err)
-	}
-
-	// If no array access, return as-is
-	if len(indices) == 0 {
-		return symbol.IndexGroup, symbol.IndexOffset, symbol.Size, nil
-	}
-
-	if len(indices) > 1 {
-		return 0, 0, 0, fmt.Errorf("multi-dimensional arrays not yet supported")
+		return nil, fmt.Errorf("read symbol %q: %w", symbolName, err)
 	}
 
-	// Get element type and calculate offset
-	elementTypeName := getElementTypeName(symbol.Type.Name)
-	elementTypeInfo, err := c.getOrFetchTypeInfo(ctx, elementTypeName)
+	data, err := c.readResolved(ctx, target)
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("get element type info for %q: %w", elementTypeName, err)
-	}
-
-	elementSize := elementTypeInfo.Size
-	offset := uint32(indices[0]) * elementSize
-
-	return symbol.IndexGroup, symbol.IndexOffset + offset, elementSize, nil
-}
-
-// getElementTypeName extracts the element type name from an array type.
-func getElementTypeName(typeName string) string {
-	elementTypeName, isArray := extractArrayElementType(typeName)
-	if !isArray {
-		return typeName
+		return nil, err
 	}
-	return elementTypeName
-}
-
-// findFieldInType searches for a field in a type's field list.
-func findFieldInType(typeInfo symbols.TypeInfo, fieldName string) (symbols.FieldInfo, bool) {
-	for _, field := range typeInfo.Fields {
-		if field.Name == fieldName {
-			return field, true
-		}
+	if field := target.bitField; field != nil {
+		return bitFieldBytes(extractBits(data, field.BitOffset, field.BitSize), target.size), nil
 	}
-	return symbols.FieldInfo{}, false
+	return data, nil
 }
 
-// ReadSymbol reads data from a PLC symbol by name.
-// Supports array element access using bracket notation: "MAIN.myArray[5]"
-// Automatically loads symbol table on first call.
-func (c *Client) ReadSymbol(ctx context.Context, symbolName string) ([]byte, error) {
-	if err := c.ensureSymbolsLoaded(ctx); err != nil {
-		return nil, err
+// readResolved reads the data of a resolved symbol path.
+// Paths through pointers or references are always read by handle.
+func (c *Client) readResolved(ctx context.Context, target *resolvedSymbol) ([]byte, error) {
+	if c.handles != nil && target.bitField != nil {
+		return c.readBitFieldByHandle(ctx, target)
 	}
 
-	indexGroup, indexOffset, size, err := c.resolveArraySymbol(ctx, symbolName)
-	if err != nil {
-		return nil, fmt.Errorf("read symbol %q: %w", symbolName, err)
+	if target.dynamic || c.handles != nil {
+		var data []byte
+		err := c.withPathHandle(ctx, target.name, func(handle uint32) error {
+			var readErr error
+			data, readErr = c.ReadByHandle(ctx, handle, target.size)
+			return readErr
+		})
+		return data, err
 	}
 
-	return c.Read(ctx, indexGroup, indexOffset, size)
+	return c.Read(ctx, target.indexGroup, target.indexOffset, target.size)
 }
 
 // WriteSymbol writes data to a PLC symbol by name.
-// Supports array element access using bracket notation: "MAIN.myArray[5]"
+// Supports the same path notation as ReadSymbol. Data for a BIT struct member
+// is its value as returned by ReadSymbol; the neighbouring bits in the same
+// bytes keep their PLC value.
 // Automatically loads symbol table on first call.
 func (c *Client) WriteSymbol(ctx context.Context, symbolName string, data []byte) error {
 	if err := c.ensureSymbolsLoaded(ctx); err != nil {
 		return err
 	}
 
-	indexGroup, indexOffset, size, err := c.resolveArraySymbol(ctx, symbolName)
+	target, err := c.resolveSymbolPath(ctx, symbolName)
 	if err != nil {
 		return fmt.Errorf("write symbol %q: %w", symbolName, err)
 	}
 
-	if uint32(len(data)) != size {
+	if uint32(len(data)) != target.size {
 		return fmt.Errorf("write symbol %q: data size mismatch (expected %d bytes, got %d)",
-			symbolName, size, len(data))
+			symbolName, target.size, len(data))
+	}
+
+	if target.bitField != nil {
+		var buf [8]byte
+		copy(buf[:], data)
+		if err := c.writeResolvedBitField(ctx, target, binary.LittleEndian.Uint64(buf[:])); err != nil {
+			return fmt.Errorf("write symbol %q: %w", symbolName, err)
+		}
+		return nil
+	}
+	return c.writeResolved(ctx, target, data)
+}
+
+// writeResolved writes the data of a resolved symbol path.
+// Paths through pointers or references are always written by handle.
+func (c *Client) writeResolved(ctx context.Context, target *resolvedSymbol, data []byte) error {
+	if c.handles != nil && target.bitField != nil {
+		return c.modifyBitFieldByHandle(ctx, target, func(fieldData []byte) error {
+			copy(fieldData, data)
+			return nil
+		})
+	}
+
+	if target.dynamic || c.handles != nil {
+		return c.withPathHandle(ctx, target.name, func(handle uint32) error {
+			return c.WriteByHandle(ctx, handle, data)
+		})
 	}
 
-	return c.Write(ctx, indexGroup, indexOffset, data)
+	return c.Write(ctx, target.indexGroup, target.indexOffset, data)
 }
 
 // ReadDeviceInfo reads the device name and version.
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/mrpasztoradam/goadstc/internal/ads"
 	"github.com/mrpasztoradam/goadstc/internal/symbols"
 )
 
@@ -23,26 +24,21 @@ func (c *Client) ReadSymbolValue(ctx context.Context, symbolName string) (interf
 
 	c.logger.Debug("reading symbol value with auto-detection", "symbol", symbolName)
 
-	// Parse array notation if present (e.g., "MAIN.array[5]")
-	baseName, arrayIndices, err := parseArrayAccess(symbolName)
-	if err != nil {
-		return nil, ClassifyError(err, "read_symbol_value")
-	}
-
-	// Get symbol information
-	symbol, err := c.symbolTable.Get(baseName)
+	// Resolve the path (e.g., "MAIN.array[5]" or "GVL.grid[2,-3].x")
+	target, err := c.resolveSymbolPath(ctx, symbolName)
 	if err != nil {
 		return nil, ClassifyError(err, "read_symbol_value")
 	}
+	symbol := target.symbol
 
 	// Read the raw data
-	data, err := c.ReadSymbol(ctx, symbolName)
+	data, err := c.readResolved(ctx, target)
 	if err != nil {
-		return nil, ClassifyError(err, "read_symbol_value")
+		return nil, ClassifyError(fmt.Errorf("read symbol %q: %w", symbolName, err), "read_symbol_value")
 	}
 
 	// Auto-detect and parse based on type
-	value, err := c.parseSymbolValue(ctx, data, symbol, len(arrayIndices) > 0)
+	value, err := c.parseResolvedValue(ctx, data, target)
 	if err != nil {
 		return nil, ClassifyError(err, "read_symbol_value")
 	}
@@ -51,52 +47,125 @@ func (c *Client) ReadSymbolValue(ctx context.Context, symbolName string) (interf
 	return value, nil
 }
 
-// ReadMultipleSymbolValues reads multiple symbols in individual requests and returns a map of results.
-// TODO: Future enhancement - use SumCommand (0xF080) for batched reads in single request.
+// ReadMultipleSymbolValues reads multiple symbols and returns a map of parsed values.
+// All resolvable symbols are packed into ADS sum read requests (0xF080), split
+// automatically at the 500 sub-command limit of the PLC.
+// Symbols that cannot be read are reported per entry: the map value is then an
+// error (a *ClassifiedError whose ADSError holds the PLC error code, if any).
+// Targets without sum command support fall back to one request per symbol.
 func (c *Client) ReadMultipleSymbolValues(ctx context.Context, symbolNames ...string) (map[string]interface{}, error) {
 	if len(symbolNames) == 0 {
 		return make(map[string]interface{}), nil
 	}
 
+	if err := c.ensureSymbolsLoaded(ctx); err != nil {
+		return nil, ClassifyError(err, "read_symbol_value")
+	}
+
 	c.logger.Debug("reading multiple symbol values", "count", len(symbolNames))
 
 	results := make(map[string]interface{}, len(symbolNames))
+	resolved := make([]*resolvedSymbol, 0, len(symbolNames))
+	items := make([]ads.SumReadItem, 0, len(symbolNames))
+
 	for _, name := range symbolNames {
-		value, err := c.ReadSymbolValue(ctx, name)
+		target, err := c.resolveSymbolPath(ctx, name)
 		if err != nil {
-			// Include error in results instead of failing completely
-			results[name] = fmt.Errorf("read failed: %w", err)
-			c.logger.Warn("failed to read symbol", "symbol", name, "error", err)
-		} else {
+			results[name] = fmt.Errorf("read failed: %w", ClassifyError(err, "read_symbol_value"))
+			c.logger.Warn("failed to resolve symbol", "symbol", name, "error", err)
+			continue
+		}
+		// Paths through pointers or references have no fixed address for the sum read
+		if target.dynamic {
+			value, err := c.ReadSymbolValue(ctx, name)
+			if err != nil {
+				results[name] = fmt.Errorf("read failed: %w", err)
+				continue
+			}
 			results[name] = value
+			continue
+		}
+		resolved = append(resolved, target)
+		items = append(items, ads.SumReadItem{
+			IndexGroup:  target.indexGroup,
+			IndexOffset: target.indexOffset,
+			Length:      target.size,
+		})
+	}
+
+	if len(items) == 0 {
+		return results, nil
+	}
+
+	var sumResults []SumResult
+	var err error
+	if c.handles != nil {
+		sumResults, err = c.sumReadByHandle(ctx, resolved)
+	} else {
+		sumResults, err = c.sumRead(ctx, items)
+	}
+	if err != nil {
+		if !isSumCommandUnsupported(err) {
+			return nil, err
+		}
+		c.logger.Warn("sum read not supported by target, reading symbols individually", "error", err)
+		for _, target := range resolved {
+			value, err := c.ReadSymbolValue(ctx, target.name)
+			if err != nil {
+				results[target.name] = fmt.Errorf("read failed: %w", err)
+				continue
+			}
+			results[target.name] = value
+		}
+		return results, nil
+	}
+
+	for i, target := range resolved {
+		if sumResults[i].Err != nil {
+			results[target.name] = fmt.Errorf("read failed: %w", sumResults[i].Err)
+			c.logger.Warn("failed to read symbol", "symbol", target.name, "error", sumResults[i].Err)
+			continue
+		}
+
+		value, err := c.parseResolvedValue(ctx, sumResults[i].Data, target)
+		if err != nil {
+			results[target.name] = fmt.Errorf("read failed: %w", ClassifyError(err, "read_symbol_value"))
+			continue
 		}
+		results[target.name] = value
 	}
 
 	return results, nil
 }
 
+// parseResolvedValue parses the data read for a resolved symbol path.
+func (c *Client) parseResolvedValue(ctx context.Context, data []byte, target *resolvedSymbol) (interface{}, error) {
+	if field := target.bitField; field != nil {
+		return bitFieldValue(extractBits(data, field.BitOffset, field.BitSize), field.BitSize), nil
+	}
+	return c.parseSymbolValue(ctx, data, target.symbol)
+}
+
 // parseSymbolValue determines the appropriate parser based on symbol type information.
-func (c *Client) parseSymbolValue(ctx context.Context, data []byte, symbol *symbols.Symbol, isArrayElement bool) (interface{}, error) {
+// For array elements and struct fields, symbol describes the addressed element.
+func (c *Client) parseSymbolValue(ctx context.Context, data []byte, symbol *symbols.Symbol) (interface{}, error) {
 	typeInfo := symbol.Type
 
-	// Handle array access - element should be parsed as its base type
-	if isArrayElement && typeInfo.IsArray {
-		// Extract element type from array notation (e.g., "ARRAY [0..9] OF INT" -> "INT")
-		if elementTypeName, isArray := extractArrayElementType(typeInfo.Name); isArray {
-			// Parse as simple type or struct depending on element type
-			if isSimpleTypeName(elementTypeName) {
-				return c.parseSimpleTypeByName(data, elementTypeName)
-			}
-			// Element is a struct - fetch its type info and parse
-			return c.parseStructWithTypeInfo(ctx, data, elementTypeName)
-		}
+	// BIT variables and symbols in bit index groups
+	if isBitSymbol(symbol) && !typeInfo.IsArray {
+		return decodeBitSymbol(data, symbol), nil
 	}
 
-	// Handle full arrays (not array element access)
-	if typeInfo.IsArray && !isArrayElement {
+	// Handle arrays
+	if typeInfo.IsArray {
 		return c.parseArrayValue(ctx, data, typeInfo)
 	}
 
+	// Handle enums (simple base type, but a named type with members)
+	if enumType, ok := c.lookupEnumType(ctx, typeInfo.Name); ok {
+		return decodeEnumValue(data, enumType), nil
+	}
+
 	// Handle structs
 	if typeInfo.IsStruct || strings.Contains(typeInfo.Name, "STRUCT") {
 		return c.parseStructWithTypeInfo(ctx, data, typeInfo.Name)
@@ -184,6 +253,10 @@ func (c *Client) parseStructWithTypeInfo(ctx context.Context, data []byte, typeN
 		}, nil
 	}
 
+	if len(typeInfo.EnumValues) > 0 {
+		return decodeEnumValue(data, typeInfo), nil
+	}
+
 	if len(typeInfo.Fields) == 0 {
 		return map[string]interface{}{
 			"_raw":  data,
@@ -194,13 +267,7 @@ func (c *Client) parseStructWithTypeInfo(ctx context.Context, data []byte, typeN
 
 	// Parse all fields
 	result := make(map[string]interface{}, len(typeInfo.Fields))
-	for _, field := range typeInfo.Fields {
-		if int(field.Offset)+int(field.Type.Size) > len(data) {
-			continue
-		}
-		fieldData := data[field.Offset : field.Offset+field.Type.Size]
-		result[field.Name] = parseFieldValue(fieldData, field.Type)
-	}
+	parseFieldsFromTypeInfo(result, data, typeInfo.Fields)
 
 	return result, nil
 }
@@ -404,16 +471,20 @@ func (c *Client) WriteSymbolValue(ctx context.Context, symbolName string, value
 
 	c.logger.Debug("writing symbol value with auto-encoding", "symbol", symbolName)
 
-	// Parse array notation if present
-	baseName, _, err := parseArrayAccess(symbolName)
+	// Resolve the path (e.g., "MAIN.array[5]" or "GVL.grid[2,-3].x")
+	target, err := c.resolveSymbolPath(ctx, symbolName)
 	if err != nil {
 		return ClassifyError(err, "write_symbol_value")
 	}
+	symbol := target.symbol
 
-	// Get symbol information
-	symbol, err := c.symbolTable.Get(baseName)
-	if err != nil {
-		return ClassifyError(err, "write_symbol_value")
+	// BIT struct members share their bytes with other members
+	if target.bitField != nil {
+		if err := c.writeResolvedBitField(ctx, target, value); err != nil {
+			return ClassifyError(err, "write_symbol_value")
+		}
+		c.logger.Debug("successfully wrote symbol value", "symbol", symbolName, "type", symbol.Type.Name)
+		return nil
 	}
 
 	// Encode value based on Go type
@@ -423,14 +494,105 @@ func (c *Client) WriteSymbolValue(ctx context.Context, symbolName string, value
 	}
 
 	// Write the encoded data
-	if err := c.WriteSymbol(ctx, symbolName, data); err != nil {
-		return ClassifyError(err, "write_symbol_value")
+	if err := c.writeResolved(ctx, target, data); err != nil {
+		return ClassifyError(fmt.Errorf("write symbol %q: %w", symbolName, err), "write_symbol_value")
 	}
 
 	c.logger.Debug("successfully wrote symbol value", "symbol", symbolName, "type", symbol.Type.Name)
 	return nil
 }
 
+// WriteMultipleSymbolValues encodes and writes several symbols in a single ADS sum
+// write request (0xF081), using the same encoding rules as WriteSymbolValue.
+// Requests with more than 500 symbols are split into several sum requests.
+// The returned map has an entry for every symbol: nil on success, otherwise the
+// encoding error or a *ClassifiedError carrying the ADS error code of that item.
+// Bit fields and paths through pointers or references cannot be part of a sum
+// request; they are written individually once the sum request succeeded.
+// Nothing is written if the request itself fails.
+func (c *Client) WriteMultipleSymbolValues(ctx context.Context, values map[string]any) (map[string]error, error) {
+	results := make(map[string]error, len(values))
+	if len(values) == 0 {
+		return results, nil
+	}
+
+	if err := c.ensureSymbolsLoaded(ctx); err != nil {
+		return nil, ClassifyError(err, "write_symbol_value")
+	}
+
+	c.logger.Debug("writing multiple symbol values", "count", len(values))
+
+	targets := make([]*resolvedSymbol, 0, len(values))
+	items := make([]ads.SumWriteItem, 0, len(values))
+	var individual []string
+
+	for name, value := range values {
+		target, err := c.resolveSymbolPath(ctx, name)
+		if err != nil {
+			results[name] = ClassifyError(err, "write_symbol_value")
+			continue
+		}
+
+		// Bit fields need a read-modify-write and paths through pointers or
+		// references are written by handle, neither can be part of the sum request
+		if target.bitField != nil || target.dynamic {
+			individual = append(individual, name)
+			continue
+		}
+
+		data, err := c.encodeSymbolValue(ctx, value, target.symbol)
+		if err != nil {
+			results[name] = ClassifyError(err, "write_symbol_value")
+			continue
+		}
+
+		if uint32(len(data)) != target.size {
+			results[name] = ClassifyError(fmt.Errorf("data size mismatch (expected %d bytes, got %d)",
+				target.size, len(data)), "write_symbol_value")
+			continue
+		}
+
+		targets = append(targets, target)
+		items = append(items, ads.SumWriteItem{
+			IndexGroup:  target.indexGroup,
+			IndexOffset: target.indexOffset,
+			Data:        data,
+		})
+	}
+
+	if len(items) > 0 {
+		var sumResults []SumResult
+		var err error
+		if c.handles != nil {
+			sumResults, err = c.sumWriteByHandle(ctx, targets, items)
+		} else {
+			sumResults, err = c.sumWrite(ctx, items)
+		}
+		switch {
+		case err == nil:
+			for i, target := range targets {
+				results[target.name] = sumResults[i].Err
+				if sumResults[i].Err != nil {
+					c.logger.Warn("failed to write symbol", "symbol", target.name, "error", sumResults[i].Err)
+				}
+			}
+		case isSumCommandUnsupported(err):
+			c.logger.Warn("sum write not supported by target, writing symbols individually", "error", err)
+			for i, target := range targets {
+				results[target.name] = c.writeResolved(ctx, target, items[i].Data)
+			}
+		default:
+			return nil, err
+		}
+	}
+
+	for _, name := range individual {
+		results[name] = c.WriteSymbolValue(ctx, name, values[name])
+	}
+
+	return results, nil
+}
+
 // encodeSymbolValue encodes a Go value into bytes based on the symbol's type.
 func (c *Client) encodeSymbolValue(ctx context.Context, value interface{}, symbol *symbols.Symbol) ([]byte, error) {
 	// Handle nil
@@ -438,6 +600,20 @@ func (c *Client) encodeSymbolValue(ctx context.Context, value interface{}, symbo
 		return make([]byte, symbol.Size), nil
 	}
 
+	// BIT variables and symbols in bit index groups
+	if isBitSymbol(symbol) && !symbol.Type.IsArray {
+		if _, raw := value.([]byte); !raw {
+			return encodeBitSymbol(value, symbol)
+		}
+	}
+
+	// Enums accept member names as well as numbers
+	if _, raw := value.([]byte); !raw {
+		if enumType, ok := c.symbolEnumType(ctx, symbol); ok {
+			return encodeEnumValue(value, enumType, symbol.Size)
+		}
+	}
+
 	// Try type-specific encoding based on Go type
 	switch v := value.(type) {
 	case bool:
@@ -612,6 +788,13 @@ func (c *Client) encodeNestedStruct(ctx context.Context, structMap map[string]in
 			return nil, fmt.Errorf("field '%s' not found in struct '%s'", fieldName, symbol.Type.Name)
 		}
 
+		if fieldInfo.BitSize > 0 {
+			if err := writeBitField(data, fieldInfo, fieldValue); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
 		// Encode the field value
 		fieldSymbol := &symbols.Symbol{
 			Type: fieldInfo.Type,
@@ -652,10 +835,11 @@ func (c *Client) WriteStructFields(ctx context.Context, symbolName string, field
 	c.logger.Debug("writing struct fields", "symbol", symbolName, "fieldCount", len(fieldValues))
 
 	// Get symbol information
-	symbol, err := c.symbolTable.Get(symbolName)
+	target, err := c.resolveSymbolPath(ctx, symbolName)
 	if err != nil {
 		return ClassifyError(fmt.Errorf("symbol '%s' not found: %w", symbolName, err), "write_struct_fields")
 	}
+	symbol := target.symbol
 
 	// Ensure it's a struct type
 	if symbol.Type.Name == "" {
@@ -693,6 +877,15 @@ func (c *Client) WriteStructFields(ctx context.Context, symbolName string, field
 			return ClassifyError(fmt.Errorf("field '%s' not found in struct '%s'", fieldName, symbolName), "write_struct_fields")
 		}
 
+		// BIT fields only change their own bits of the bytes read above
+		if fieldInfo.BitSize > 0 {
+			if err := writeBitField(structData, fieldInfo, fieldValue); err != nil {
+				return ClassifyError(err, "write_struct_fields")
+			}
+			c.logger.Debug("modified bit field in struct data", "field", fieldName, "offset", fieldInfo.Offset, "bitOffset", fieldInfo.BitOffset, "bits", fieldInfo.BitSize)
+			continue
+		}
+
 		// Encode the field value
 		fieldSymbol := &symbols.Symbol{
 			Type: fieldInfo.Type,
@@ -0,0 +1,182 @@
+package goadstc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mrpasztoradam/goadstc/adssim"
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+)
+
+// checkCounters checks the values read from counterSymbols; names[missing]
+// does not exist.
+func checkCounters(t *testing.T, names []string, values map[string]interface{}, missing int) {
+	t.Helper()
+	if len(values) != len(names) {
+		t.Fatalf("got %d results, want %d", len(values), len(names))
+	}
+	for i, name := range names {
+		if i == missing {
+			if _, ok := values[name].(error); !ok {
+				t.Errorf("%s = %v, want an error", name, values[name])
+			}
+			continue
+		}
+		if values[name] != int32(i) {
+			t.Errorf("%s = %v, want %d", name, values[name], i)
+		}
+	}
+}
+
+func TestReadMultipleSymbolValuesChunked(t *testing.T) {
+	const count = 2*ads.MaxSumCommands + 100
+	server, client := startSimulator(t, adssim.Config{Symbols: counterSymbols(count)})
+
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("MAIN.v%d", i)
+	}
+	const missing = 700
+	names[missing] = "MAIN.missing"
+
+	// Count the sum reads without changing them
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultDelay, Command: ads.CmdReadWrite, IndexGroup: ads.IndexGroupSumCommandRead})
+
+	values, err := client.ReadMultipleSymbolValues(context.Background(), names...)
+	if err != nil {
+		t.Fatalf("ReadMultipleSymbolValues() error = %v", err)
+	}
+	checkCounters(t, names, values, missing)
+	if n := server.FaultsTriggered(); n != 3 {
+		t.Errorf("%d sum reads sent for %d symbols, want 3", n, count-1)
+	}
+}
+
+func TestReadMultipleSymbolValuesWithoutSumCommands(t *testing.T) {
+	server, client := startSimulator(t, adssim.Config{Symbols: counterSymbols(10)})
+	server.InjectFault(adssim.Fault{
+		Kind: adssim.FaultError, Error: ads.ErrDeviceServiceNotSupported,
+		Command: ads.CmdReadWrite, IndexGroup: ads.IndexGroupSumCommandRead,
+	})
+
+	names := []string{"MAIN.v0", "MAIN.v1", "MAIN.missing", "MAIN.v3"}
+	values, err := client.ReadMultipleSymbolValues(context.Background(), names...)
+	if err != nil {
+		t.Fatalf("ReadMultipleSymbolValues() error = %v", err)
+	}
+	checkCounters(t, names, values, 2)
+	if n := server.FaultsTriggered(); n != 1 {
+		t.Errorf("sum read attempted %d times, want 1", n)
+	}
+}
+
+// lockedIndexGroup holds MAIN.locked, whose reads and writes the tests reject.
+const lockedIndexGroup = 0x4040
+
+func TestReadMultipleSymbolValuesItemError(t *testing.T) {
+	config := adssim.Config{Symbols: append(counterSymbols(5),
+		adssim.Symbol{Name: "MAIN.locked", Type: "DINT", IndexGroup: lockedIndexGroup})}
+	server, client := startSimulator(t, config)
+
+	// Only the read of MAIN.locked fails inside the sum read
+	server.InjectFault(adssim.Fault{
+		Kind: adssim.FaultError, Error: ads.ErrDeviceInvalidIndexOffset,
+		Command: ads.CmdRead, IndexGroup: lockedIndexGroup,
+	})
+
+	names := []string{"MAIN.v0", "MAIN.v1", "MAIN.locked", "MAIN.v3", "MAIN.v4"}
+	values, err := client.ReadMultipleSymbolValues(context.Background(), names...)
+	if err != nil {
+		t.Fatalf("ReadMultipleSymbolValues() error = %v", err)
+	}
+	checkCounters(t, names, values, 2)
+	var adsErr ads.Error
+	if err, _ := values["MAIN.locked"].(error); !errors.As(err, &adsErr) || adsErr != ads.ErrDeviceInvalidIndexOffset {
+		t.Errorf("MAIN.locked = %v, want ADS error %#x", values["MAIN.locked"], uint32(ads.ErrDeviceInvalidIndexOffset))
+	}
+	// An individual read of MAIN.locked after the sum read would fail again
+	if n := server.FaultsTriggered(); n != 1 {
+		t.Errorf("read of MAIN.locked failed %d times, want 1", n)
+	}
+}
+
+func TestWriteMultipleSymbolValuesChunked(t *testing.T) {
+	const count = 2*ads.MaxSumCommands + 100
+	config := adssim.Config{Symbols: append(counterSymbols(count),
+		adssim.Symbol{Name: "MAIN.locked", Type: "DINT", IndexGroup: lockedIndexGroup})}
+	server, client := startSimulator(t, config)
+
+	values := make(map[string]any, count+2)
+	for i := 0; i < count; i++ {
+		values[fmt.Sprintf("MAIN.v%d", i)] = int32(1000 + i)
+	}
+	values["MAIN.locked"] = int32(1)
+	values["MAIN.missing"] = int32(1)
+
+	// Count the sum writes without changing them, and reject the write of
+	// MAIN.locked inside its sum write
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultDelay, Command: ads.CmdReadWrite, IndexGroup: ads.IndexGroupSumCommandWrite})
+	server.InjectFault(adssim.Fault{
+		Kind: adssim.FaultError, Error: ads.ErrDeviceInvalidIndexOffset,
+		Command: ads.CmdWrite, IndexGroup: lockedIndexGroup,
+	})
+
+	results, err := client.WriteMultipleSymbolValues(context.Background(), values)
+	if err != nil {
+		t.Fatalf("WriteMultipleSymbolValues() error = %v", err)
+	}
+	if len(results) != len(values) {
+		t.Fatalf("got %d results, want %d", len(results), len(values))
+	}
+	var adsErr ads.Error
+	if !errors.As(results["MAIN.locked"], &adsErr) || adsErr != ads.ErrDeviceInvalidIndexOffset {
+		t.Errorf("MAIN.locked error = %v, want ADS error %#x", results["MAIN.locked"], uint32(ads.ErrDeviceInvalidIndexOffset))
+	}
+	if results["MAIN.missing"] == nil {
+		t.Error("MAIN.missing succeeded, want an error")
+	}
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("MAIN.v%d", i)
+		if results[name] != nil {
+			t.Errorf("%s error = %v", name, results[name])
+			continue
+		}
+		if data, _ := server.ReadSymbol(name); int32(binary.LittleEndian.Uint32(data)) != int32(1000+i) {
+			t.Errorf("%s = % x, want %d", name, data, 1000+i)
+		}
+	}
+	// Three sum writes and the rejected item
+	if n := server.FaultsTriggered(); n != 4 {
+		t.Errorf("%d faults triggered, want 3 sum writes and 1 rejected item", n)
+	}
+}
+
+func TestWriteMultipleSymbolValuesWithoutSumCommands(t *testing.T) {
+	for _, code := range []ads.Error{ads.ErrDeviceServiceNotSupported, ads.ErrDeviceInvalidIndexGroup} {
+		server, client := startSimulator(t, adssim.Config{Symbols: counterSymbols(4)})
+		server.InjectFault(adssim.Fault{
+			Kind: adssim.FaultError, Error: code,
+			Command: ads.CmdReadWrite, IndexGroup: ads.IndexGroupSumCommandWrite,
+		})
+
+		values := map[string]any{"MAIN.v0": int32(10), "MAIN.v1": int32(11), "MAIN.v3": int32(13)}
+		results, err := client.WriteMultipleSymbolValues(context.Background(), values)
+		if err != nil {
+			t.Fatalf("WriteMultipleSymbolValues() with sum write rejected by %#x error = %v", uint32(code), err)
+		}
+		for name, value := range values {
+			if results[name] != nil {
+				t.Errorf("%s error = %v", name, results[name])
+			}
+			if data, _ := server.ReadSymbol(name); int32(binary.LittleEndian.Uint32(data)) != value {
+				t.Errorf("%s = % x after individual write, want %d", name, data, value)
+			}
+		}
+		if n := server.FaultsTriggered(); n != 1 {
+			t.Errorf("sum write attempted %d times, want 1", n)
+		}
+	}
+}
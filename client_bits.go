@@ -0,0 +1,272 @@
+package goadstc
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+// isBitIndexGroup reports whether an index group addresses single bits.
+// Offsets in these groups are bit addresses and each value occupies one byte.
+func isBitIndexGroup(indexGroup uint32) bool {
+	switch indexGroup {
+	case ads.IndexGroupPLCMemoryBit, ads.IndexGroupPhysicalInputsBit, ads.IndexGroupPhysicalOutputsBit:
+		return true
+	default:
+		return false
+	}
+}
+
+// isBitSymbol reports whether a symbol holds bit values rather than bytes.
+func isBitSymbol(symbol *symbols.Symbol) bool {
+	return symbol.BitSize > 0 || isBitIndexGroup(symbol.IndexGroup)
+}
+
+// extractBits returns bitSize bits of data starting at bitOffset (LSB first).
+func extractBits(data []byte, bitOffset, bitSize uint8) uint64 {
+	var value uint64
+	for i := 0; i < int(bitSize); i++ {
+		bit := int(bitOffset) + i
+		if bit/8 >= len(data) {
+			break
+		}
+		if data[bit/8]&(1<<(bit%8)) != 0 {
+			value |= 1 << i
+		}
+	}
+	return value
+}
+
+// insertBits stores the low bitSize bits of value at bitOffset, leaving all other bits untouched.
+func insertBits(data []byte, bitOffset, bitSize uint8, value uint64) {
+	for i := 0; i < int(bitSize); i++ {
+		bit := int(bitOffset) + i
+		if bit/8 >= len(data) {
+			return
+		}
+		mask := byte(1 << (bit % 8))
+		if value&(1<<i) != 0 {
+			data[bit/8] |= mask
+		} else {
+			data[bit/8] &^= mask
+		}
+	}
+}
+
+// bitFieldValue converts raw bits into a Go value: bool for a single bit,
+// otherwise the smallest unsigned integer type holding bitSize bits.
+func bitFieldValue(bits uint64, bitSize uint8) interface{} {
+	switch {
+	case bitSize == 1:
+		return bits != 0
+	case bitSize <= 8:
+		return uint8(bits)
+	case bitSize <= 16:
+		return uint16(bits)
+	case bitSize <= 32:
+		return uint32(bits)
+	default:
+		return bits
+	}
+}
+
+// bitFieldBytes returns the value of a bit field as size bytes, LSB first, the
+// way ReadSymbol and WriteSymbol transfer BIT struct members.
+func bitFieldBytes(bits uint64, size uint32) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], bits)
+	data := make([]byte, size)
+	copy(data, buf[:])
+	return data
+}
+
+// bitsFromValue converts a Go value for a bit field or bit symbol into raw bits.
+func bitsFromValue(value interface{}, bitSize uint8) (uint64, error) {
+	var bits uint64
+
+	switch v := value.(type) {
+	case bool:
+		if v {
+			bits = 1
+		}
+	case int:
+		bits = uint64(v)
+	case int8:
+		bits = uint64(v)
+	case int16:
+		bits = uint64(v)
+	case int32:
+		bits = uint64(v)
+	case int64:
+		bits = uint64(v)
+	case uint8:
+		bits = uint64(v)
+	case uint16:
+		bits = uint64(v)
+	case uint32:
+		bits = uint64(v)
+	case uint64:
+		bits = v
+	case float64:
+		// JSON numbers arrive as float64
+		if v < 0 || v != math.Trunc(v) {
+			return 0, fmt.Errorf("bit value %v is not a non-negative whole number", v)
+		}
+		bits = uint64(v)
+	default:
+		return 0, fmt.Errorf("unsupported type for bit value: %T (use bool or unsigned integer)", value)
+	}
+
+	if bitSize < 64 && bits>>bitSize != 0 {
+		return 0, fmt.Errorf("value %d does not fit in %d bit(s)", bits, bitSize)
+	}
+	return bits, nil
+}
+
+// decodeBitSymbol decodes the value of a bit-valued symbol.
+// A single bit is transferred as one byte with the value in the LSB; wider
+// bit values are packed.
+func decodeBitSymbol(data []byte, symbol *symbols.Symbol) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	if symbol.BitSize <= 1 {
+		return data[0]&0x01 != 0
+	}
+	return bitFieldValue(extractBits(data, 0, uint8(symbol.BitSize)), uint8(symbol.BitSize))
+}
+
+// encodeBitSymbol encodes a value for a bit-valued symbol.
+func encodeBitSymbol(value interface{}, symbol *symbols.Symbol) ([]byte, error) {
+	bitSize := uint8(symbol.BitSize)
+	if bitSize == 0 {
+		bitSize = 1
+	}
+
+	bits, err := bitsFromValue(value, bitSize)
+	if err != nil {
+		return nil, err
+	}
+
+	size := symbol.Size
+	if size == 0 {
+		size = 1
+	}
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], bits)
+
+	data := make([]byte, size)
+	copy(data, buf[:])
+	return data, nil
+}
+
+// writeBitField stores an encoded bit field value in the struct data.
+// Only the field's bits change, so neighbouring bits in the same bytes are preserved.
+func writeBitField(structData []byte, field *symbols.FieldInfo, value interface{}) error {
+	bits, err := bitsFromValue(value, field.BitSize)
+	if err != nil {
+		return fmt.Errorf("encode bit field %q: %w", field.Name, err)
+	}
+	if field.Offset+field.Type.Size > uint32(len(structData)) {
+		return fmt.Errorf("bit field %q at byte %d exceeds struct size %d", field.Name, field.Offset, len(structData))
+	}
+	insertBits(structData[field.Offset:], field.BitOffset, field.BitSize, bits)
+	return nil
+}
+
+// writeResolvedBitField writes a BIT struct member addressed by a symbol path.
+// The bytes holding the member are read, updated and written back. With
+// WithHandleAccess the struct holding the member is accessed by handle instead.
+func (c *Client) writeResolvedBitField(ctx context.Context, target *resolvedSymbol, value interface{}) error {
+	if c.handles != nil {
+		return c.modifyBitFieldByHandle(ctx, target, func(data []byte) error {
+			return writeBitField(data, target.bitField, value)
+		})
+	}
+
+	data, err := c.Read(ctx, target.indexGroup, target.indexOffset, target.size)
+	if err != nil {
+		return fmt.Errorf("read bit field %q: %w", target.name, err)
+	}
+
+	if err := writeBitField(data, target.bitField, value); err != nil {
+		return err
+	}
+
+	if err := c.Write(ctx, target.indexGroup, target.indexOffset, data); err != nil {
+		return fmt.Errorf("write bit field %q: %w", target.name, err)
+	}
+	return nil
+}
+
+// bitFieldParent resolves the struct holding the BIT member addressed by
+// target, for access by handle: a handle addresses a whole value, not the bytes
+// of a bit field. It also returns the offset of the member bytes in the struct.
+func (c *Client) bitFieldParent(ctx context.Context, target *resolvedSymbol) (*resolvedSymbol, uint32, error) {
+	dot := strings.LastIndexByte(target.name, '.')
+	if dot <= 0 {
+		return nil, 0, fmt.Errorf("bit field %q has no parent struct", target.name)
+	}
+	parent, err := c.resolveSymbolPath(ctx, target.name[:dot])
+	if err != nil {
+		return nil, 0, err
+	}
+	if target.indexOffset < parent.indexOffset || target.indexOffset+target.size > parent.indexOffset+parent.size {
+		return nil, 0, fmt.Errorf("bit field %q lies outside of %q", target.name, parent.name)
+	}
+	return parent, target.indexOffset - parent.indexOffset, nil
+}
+
+// readBitFieldByHandle reads the bytes holding a bit field through the handle
+// of the struct holding it.
+func (c *Client) readBitFieldByHandle(ctx context.Context, target *resolvedSymbol) ([]byte, error) {
+	parent, offset, err := c.bitFieldParent(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	err = c.withSymbolHandle(ctx, parent.name, func(handle uint32) error {
+		var readErr error
+		data, readErr = c.ReadByHandle(ctx, handle, parent.size)
+		return readErr
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read bit field %q: %w", target.name, err)
+	}
+	if uint32(len(data)) < offset+target.size {
+		return nil, fmt.Errorf("read bit field %q: got %d bytes of %q", target.name, len(data), parent.name)
+	}
+	return data[offset : offset+target.size], nil
+}
+
+// modifyBitFieldByHandle reads the struct holding a bit field by handle, lets
+// update change the bytes holding the field and writes the struct back.
+func (c *Client) modifyBitFieldByHandle(ctx context.Context, target *resolvedSymbol, update func(data []byte) error) error {
+	parent, offset, err := c.bitFieldParent(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	return c.withSymbolHandle(ctx, parent.name, func(handle uint32) error {
+		data, err := c.ReadByHandle(ctx, handle, parent.size)
+		if err != nil {
+			return fmt.Errorf("read bit field %q: %w", target.name, err)
+		}
+		if uint32(len(data)) < offset+target.size {
+			return fmt.Errorf("read bit field %q: got %d bytes of %q", target.name, len(data), parent.name)
+		}
+		if err := update(data[offset : offset+target.size]); err != nil {
+			return err
+		}
+		if err := c.WriteByHandle(ctx, handle, data); err != nil {
+			return fmt.Errorf("write bit field %q: %w", target.name, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,110 @@
+package goadstc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/mrpasztoradam/goadstc/adssim"
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+func TestBitFieldReadModifyWrite(t *testing.T) {
+	data := []byte{0b1011_0001, 0b0000_0010}
+
+	// 3 bits starting at bit 6 of byte 0, spanning into byte 1
+	if got := extractBits(data, 6, 3); got != 0b010 {
+		t.Errorf("extractBits() = %03b, want 010", got)
+	}
+
+	field := &symbols.FieldInfo{Name: "nMode", Offset: 0, BitOffset: 6, BitSize: 3, Type: symbols.TypeInfo{Size: 2}}
+	if err := writeBitField(data, field, uint8(0b101)); err != nil {
+		t.Fatalf("writeBitField() error = %v", err)
+	}
+	want := []byte{0b0111_0001, 0b0000_0011}
+	if !bytes.Equal(data, want) {
+		t.Errorf("data = %08b, want %08b", data, want)
+	}
+
+	if err := writeBitField(data, field, 8); err == nil {
+		t.Error("writeBitField() expected error for value wider than the field")
+	}
+
+	flag := &symbols.FieldInfo{Name: "bDoor", Offset: 1, BitOffset: 1, BitSize: 1, Type: symbols.TypeInfo{Size: 1}}
+	if err := writeBitField(data, flag, false); err != nil {
+		t.Fatalf("writeBitField() error = %v", err)
+	}
+	if data[1] != 0b0000_0001 || data[0] != want[0] {
+		t.Errorf("neighbouring bits changed: %08b", data)
+	}
+}
+
+func TestWriteMultipleSymbolValuesBitFieldAfterSum(t *testing.T) {
+	server, client := startSimulator(t, adssim.Config{
+		DataTypes: []symbols.DataTypeEntry{flagsType},
+		Symbols: []adssim.Symbol{
+			{Name: "MAIN.counter", Type: "DINT"},
+			{Name: "MAIN.flags", Type: "ST_Flags"},
+		},
+	})
+	ctx := context.Background()
+
+	values := map[string]any{"MAIN.counter": int32(5), "MAIN.flags.bReady": true}
+
+	// A failed sum write leaves the bit field untouched as well
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultError, Error: ads.ErrDeviceInvalidSize, IndexGroup: 0xF081, Count: 1})
+	if _, err := client.WriteMultipleSymbolValues(ctx, values); err == nil {
+		t.Fatal("WriteMultipleSymbolValues() expected error for failed sum write")
+	}
+	if data, _ := server.ReadSymbol("MAIN.flags"); data[0] != 0 {
+		t.Errorf("bit field written before failed sum write: %08b", data[0])
+	}
+
+	results, err := client.WriteMultipleSymbolValues(ctx, values)
+	if err != nil {
+		t.Fatalf("WriteMultipleSymbolValues() error = %v", err)
+	}
+	for name, err := range results {
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if data, _ := server.ReadSymbol("MAIN.flags"); data[0] != 0b10 {
+		t.Errorf("flags = %08b, want 00000010", data[0])
+	}
+	if data, _ := server.ReadSymbol("MAIN.counter"); data[0] != 5 {
+		t.Errorf("counter = %v, want 5", data)
+	}
+}
+
+func TestReadWriteBoolBitField(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithHandleAccess()}} {
+		server, client := startSimulator(t, adssim.Config{
+			DataTypes: []symbols.DataTypeEntry{flagsType},
+			Symbols:   []adssim.Symbol{{Name: "MAIN.flags", Type: "ST_Flags", Value: []byte{0b01}}},
+		}, opts...)
+		ctx := context.Background()
+
+		// bEnable is set, bReady next to it is not
+		if value, err := client.ReadBool(ctx, "MAIN.flags.bReady"); err != nil || value {
+			t.Errorf("ReadBool(bReady) = %v, %v; want false", value, err)
+		}
+		if value, err := client.ReadBool(ctx, "MAIN.flags.bEnable"); err != nil || !value {
+			t.Errorf("ReadBool(bEnable) = %v, %v; want true", value, err)
+		}
+
+		if err := client.WriteBool(ctx, "MAIN.flags.bReady", true); err != nil {
+			t.Fatalf("WriteBool(bReady) error = %v", err)
+		}
+		if data, _ := server.ReadSymbol("MAIN.flags"); data[0] != 0b11 {
+			t.Errorf("flags = %08b after setting bReady, want 00000011", data[0])
+		}
+		if err := client.WriteBool(ctx, "MAIN.flags.bEnable", false); err != nil {
+			t.Fatalf("WriteBool(bEnable) error = %v", err)
+		}
+		if data, _ := server.ReadSymbol("MAIN.flags"); data[0] != 0b10 {
+			t.Errorf("flags = %08b after clearing bEnable, want 00000010", data[0])
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package goadstc
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+// EnumValue is the value of a PLC ENUM variable as returned by ReadSymbolValue.
+// It carries both the member name and the numeric value.
+type EnumValue struct {
+	Type  string `json:"type"`  // Enum type name, e.g. "E_MachineState"
+	Name  string `json:"name"`  // Member name, empty if the value matches no member
+	Value int64  `json:"value"` // Numeric value
+}
+
+// String returns the qualified member name ("E_MachineState.Running"),
+// or the number if the value matches no member.
+func (e EnumValue) String() string {
+	if e.Name == "" {
+		return strconv.FormatInt(e.Value, 10)
+	}
+	return e.Type + "." + e.Name
+}
+
+// EnumMember is a named value of a PLC ENUM type.
+type EnumMember struct {
+	Name  string
+	Value int64
+}
+
+// GetEnumMembers returns the members of an ENUM type from the data type table.
+// It returns nil without error if the type is not an enum.
+func (c *Client) GetEnumMembers(ctx context.Context, typeName string) ([]EnumMember, error) {
+	typeInfo, ok := c.lookupEnumType(ctx, typeName)
+	if !ok {
+		return nil, nil
+	}
+
+	members := make([]EnumMember, len(typeInfo.EnumValues))
+	for i, v := range typeInfo.EnumValues {
+		members[i] = EnumMember{Name: v.Name, Value: v.Value}
+	}
+	return members, nil
+}
+
+// lookupEnumType returns the type info of an ENUM type.
+// Only the data type table is consulted, so non-enum types cost no extra requests.
+func (c *Client) lookupEnumType(ctx context.Context, typeName string) (symbols.TypeInfo, bool) {
+	if typeName == "" || isSimpleTypeName(typeName) {
+		return symbols.TypeInfo{}, false
+	}
+
+	c.ensureTypesLoaded(ctx)
+
+	c.typeRegistryMu.RLock()
+	typeInfo, ok := c.typeRegistry.Get(typeName)
+	c.typeRegistryMu.RUnlock()
+
+	if !ok || len(typeInfo.EnumValues) == 0 {
+		return symbols.TypeInfo{}, false
+	}
+	return typeInfo, true
+}
+
+// symbolEnumType returns the enum type info of a symbol or struct field.
+// Field types resolved from the data type table already carry their members.
+func (c *Client) symbolEnumType(ctx context.Context, symbol *symbols.Symbol) (symbols.TypeInfo, bool) {
+	if len(symbol.Type.EnumValues) > 0 {
+		return symbol.Type, true
+	}
+	return c.lookupEnumType(ctx, symbol.Type.Name)
+}
+
+// decodeEnumValue decodes raw enum data into an EnumValue with its member name.
+func decodeEnumValue(data []byte, typeInfo symbols.TypeInfo) EnumValue {
+	size := int(typeInfo.Size)
+	if size == 0 || size > len(data) {
+		size = len(data)
+	}
+
+	value := EnumValue{
+		Type:  typeInfo.Name,
+		Value: symbols.DecodeEnumValue(data[:size], typeInfo.BaseType),
+	}
+	for _, member := range typeInfo.EnumValues {
+		if member.Value == value.Value {
+			value.Name = member.Name
+			break
+		}
+	}
+	return value
+}
+
+// encodeEnumValue encodes an enum member given as EnumValue, member name
+// ("Running" or "E_MachineState.Running") or number. Numbers must fit the size
+// and signedness of the base type of the enum.
+func encodeEnumValue(value interface{}, typeInfo symbols.TypeInfo, size uint32) ([]byte, error) {
+	var number int64
+
+	switch v := value.(type) {
+	case EnumValue:
+		number = v.Value
+	case string:
+		n, err := enumValueByName(v, typeInfo)
+		if err != nil {
+			return nil, err
+		}
+		number = n
+	case int:
+		number = int64(v)
+	case int8:
+		number = int64(v)
+	case int16:
+		number = int64(v)
+	case int32:
+		number = int64(v)
+	case int64:
+		number = v
+	case uint8:
+		number = int64(v)
+	case uint16:
+		number = int64(v)
+	case uint32:
+		number = int64(v)
+	case uint64:
+		if v > math.MaxInt64 {
+			if size != 8 || isSignedEnum(typeInfo) {
+				return nil, fmt.Errorf("enum %s: value %d out of range", typeInfo.Name, v)
+			}
+			// 64-bit unsigned values are carried in the bits of an int64
+		}
+		number = int64(v)
+	case float64:
+		// JSON numbers arrive as float64
+		if v != math.Trunc(v) {
+			return nil, fmt.Errorf("enum %s: %v is not a whole number", typeInfo.Name, v)
+		}
+		if v < math.MinInt64 || v >= math.MaxInt64 {
+			return nil, fmt.Errorf("enum %s: value %v out of range", typeInfo.Name, v)
+		}
+		number = int64(v)
+	default:
+		return nil, fmt.Errorf("enum %s: unsupported value type %T (use member name or integer)", typeInfo.Name, value)
+	}
+
+	if size < 8 {
+		bits := size * 8
+		low, high := int64(0), int64(1)<<bits-1
+		if isSignedEnum(typeInfo) {
+			low, high = -1<<(bits-1), 1<<(bits-1)-1
+		}
+		if number < low || number > high {
+			return nil, fmt.Errorf("enum %s: value %d out of range [%d, %d]", typeInfo.Name, number, low, high)
+		}
+	}
+
+	data := make([]byte, size)
+	switch size {
+	case 1:
+		data[0] = byte(number)
+	case 2:
+		binary.LittleEndian.PutUint16(data, uint16(number))
+	case 4:
+		binary.LittleEndian.PutUint32(data, uint32(number))
+	case 8:
+		binary.LittleEndian.PutUint64(data, uint64(number))
+	default:
+		return nil, fmt.Errorf("enum %s: unsupported size %d", typeInfo.Name, size)
+	}
+	return data, nil
+}
+
+// isSignedEnum reports whether the base type of an enum is signed, as
+// symbols.DecodeEnumValue decodes it.
+func isSignedEnum(typeInfo symbols.TypeInfo) bool {
+	switch typeInfo.BaseType {
+	case symbols.DataTypeInt8, symbols.DataTypeInt16, symbols.DataTypeInt32, symbols.DataTypeInt64:
+		return true
+	default:
+		return false
+	}
+}
+
+// enumValueByName resolves a member name, optionally qualified with the type name.
+// Plain numbers are accepted as well.
+func enumValueByName(name string, typeInfo symbols.TypeInfo) (int64, error) {
+	name = strings.TrimSpace(name)
+	if prefix, member, found := strings.Cut(name, "."); found && strings.EqualFold(prefix, typeInfo.Name) {
+		name = member
+	}
+
+	for _, member := range typeInfo.EnumValues {
+		if strings.EqualFold(member.Name, name) {
+			return member.Value, nil
+		}
+	}
+
+	if n, err := strconv.ParseInt(name, 10, 64); err == nil {
+		return n, nil
+	}
+
+	names := make([]string, len(typeInfo.EnumValues))
+	for i, member := range typeInfo.EnumValues {
+		names[i] = member.Name
+	}
+	return 0, fmt.Errorf("enum %s has no member %q (valid: %s)", typeInfo.Name, name, strings.Join(names, ", "))
+}
@@ -0,0 +1,66 @@
+package goadstc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+func TestEnumValueRoundTrip(t *testing.T) {
+	typeInfo := symbols.TypeInfo{
+		Name:     "E_MachineState",
+		BaseType: symbols.DataTypeInt16,
+		Size:     2,
+		EnumValues: []symbols.EnumValue{
+			{Name: "Idle", Value: 0},
+			{Name: "Running", Value: 1},
+			{Name: "Error", Value: -1},
+		},
+	}
+
+	tests := []struct {
+		input interface{}
+		want  []byte
+	}{
+		{"Running", []byte{0x01, 0x00}},
+		{"E_MachineState.Error", []byte{0xFF, 0xFF}},
+		{"e_machinestate.idle", []byte{0x00, 0x00}},
+		{float64(1), []byte{0x01, 0x00}},
+		{EnumValue{Type: "E_MachineState", Name: "Error", Value: -1}, []byte{0xFF, 0xFF}},
+	}
+
+	for _, tt := range tests {
+		got, err := encodeEnumValue(tt.input, typeInfo, 2)
+		if err != nil {
+			t.Errorf("encodeEnumValue(%v) error = %v", tt.input, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("encodeEnumValue(%v) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	if _, err := encodeEnumValue("Stopped", typeInfo, 2); err == nil {
+		t.Error("encodeEnumValue(\"Stopped\") expected error for unknown member")
+	}
+
+	// Numbers that do not fit the base type are rejected instead of truncated
+	for _, input := range []interface{}{40000, -40000, uint64(1 << 63)} {
+		if got, err := encodeEnumValue(input, typeInfo, 2); err == nil {
+			t.Errorf("encodeEnumValue(%v) = %v, expected out of range error", input, got)
+		}
+	}
+	unsigned := symbols.TypeInfo{Name: "E_Mode", BaseType: symbols.DataTypeUInt8, Size: 1}
+	if _, err := encodeEnumValue(-1, unsigned, 1); err == nil {
+		t.Error("encodeEnumValue(-1) expected error for unsigned enum")
+	}
+	if got, err := encodeEnumValue(255, unsigned, 1); err != nil || got[0] != 255 {
+		t.Errorf("encodeEnumValue(255) = %v, %v", got, err)
+	}
+
+	value := decodeEnumValue([]byte{0xFF, 0xFF}, typeInfo)
+	if value.Name != "Error" || value.Value != -1 || value.String() != "E_MachineState.Error" {
+		t.Errorf("decodeEnumValue() = %+v", value)
+	}
+}
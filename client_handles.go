@@ -0,0 +1,556 @@
+package goadstc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+)
+
+// SymbolHandle is the result of acquiring a handle for a single symbol name.
+type SymbolHandle struct {
+	Name   string
+	Handle uint32
+	Err    error // Non-nil if the handle could not be acquired
+}
+
+// GetSymbolHandles acquires handles for several symbols using ADS sum read/write
+// requests (0xF082 with 0xF003 sub-commands), 500 names per request.
+// The result contains one entry per name in the given order. Entries that
+// failed carry the ADS error of that name and a zero handle.
+// Successful handles should be released with ReleaseSymbolHandles.
+func (c *Client) GetSymbolHandles(ctx context.Context, names ...string) ([]SymbolHandle, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	items := make([]SumReadWriteItem, len(names))
+	for i, name := range names {
+		req := ads.GetSymbolHandleByNameRequest{SymbolName: name}
+		nameData, _ := req.MarshalBinary()
+		items[i] = SumReadWriteItem{
+			IndexGroup:  ads.IndexGroupSymbolHandleByName,
+			IndexOffset: 0,
+			ReadLength:  4,
+			WriteData:   nameData,
+		}
+	}
+
+	results, err := c.SumReadWrite(ctx, items)
+	if err != nil {
+		return nil, fmt.Errorf("get symbol handles: %w", err)
+	}
+
+	handles := make([]SymbolHandle, len(names))
+	for i, name := range names {
+		handles[i].Name = name
+		if results[i].Err != nil {
+			handles[i].Err = fmt.Errorf("get symbol handle for %q: %w", name, results[i].Err)
+			continue
+		}
+
+		var resp ads.GetSymbolHandleByNameResponse
+		if err := resp.UnmarshalBinary(results[i].Data); err != nil {
+			handles[i].Err = fmt.Errorf("parse symbol handle response for %q: %w", name, err)
+			continue
+		}
+		handles[i].Handle = resp.Handle
+	}
+
+	return handles, nil
+}
+
+// ReleaseSymbolHandles releases several symbol handles using ADS sum write
+// requests (0xF081 with 0xF006 sub-commands), 500 handles per request.
+// All handles are attempted; the returned error joins the failures, if any.
+func (c *Client) ReleaseSymbolHandles(ctx context.Context, handles ...uint32) error {
+	if len(handles) == 0 {
+		return nil
+	}
+
+	items := make([]ads.SumWriteItem, len(handles))
+	for i, handle := range handles {
+		req := ads.ReleaseSymbolHandleRequest{Handle: handle}
+		handleData, _ := req.MarshalBinary()
+		items[i] = ads.SumWriteItem{
+			IndexGroup:  ads.IndexGroupReleaseSymbolHandle,
+			IndexOffset: 0,
+			Data:        handleData,
+		}
+	}
+
+	results, err := c.sumWrite(ctx, items)
+	if err != nil {
+		return fmt.Errorf("release symbol handles: %w", err)
+	}
+
+	var errs []error
+	for i, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("release symbol handle %d: %w", handles[i], result.Err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ReadByHandle reads the value of a symbol using a handle from GetSymbolHandle(s).
+// It uses index group 0xF005 (value by handle) with the handle as index offset,
+// so the PLC resolves the current address of the symbol.
+func (c *Client) ReadByHandle(ctx context.Context, handle, length uint32) ([]byte, error) {
+	return c.Read(ctx, ads.IndexGroupSymbolValueByHandle, handle, length)
+}
+
+// WriteByHandle writes the value of a symbol using a handle from GetSymbolHandle(s).
+// It uses index group 0xF005 (value by handle) with the handle as index offset.
+func (c *Client) WriteByHandle(ctx context.Context, handle uint32, data []byte) error {
+	return c.Write(ctx, ads.IndexGroupSymbolValueByHandle, handle, data)
+}
+
+// ReadByHandles reads several symbol values by handle in sum read requests.
+// lengths must contain the value size for each handle.
+func (c *Client) ReadByHandles(ctx context.Context, handles []uint32, lengths []uint32) ([]SumResult, error) {
+	if len(handles) != len(lengths) {
+		return nil, fmt.Errorf("read by handles: got %d handles but %d lengths", len(handles), len(lengths))
+	}
+
+	items := make([]ads.SumReadItem, len(handles))
+	for i, handle := range handles {
+		items[i] = ads.SumReadItem{
+			IndexGroup:  ads.IndexGroupSymbolValueByHandle,
+			IndexOffset: handle,
+			Length:      lengths[i],
+		}
+	}
+
+	return c.sumRead(ctx, items)
+}
+
+// WriteByHandles writes several symbol values by handle in sum write requests.
+func (c *Client) WriteByHandles(ctx context.Context, handles []uint32, data [][]byte) ([]SumResult, error) {
+	if len(handles) != len(data) {
+		return nil, fmt.Errorf("write by handles: got %d handles but %d values", len(handles), len(data))
+	}
+
+	items := make([]ads.SumWriteItem, len(handles))
+	for i, handle := range handles {
+		items[i] = ads.SumWriteItem{
+			IndexGroup:  ads.IndexGroupSymbolValueByHandle,
+			IndexOffset: handle,
+			Data:        data[i],
+		}
+	}
+
+	return c.sumWrite(ctx, items)
+}
+
+// sumReadByHandle reads resolved symbol paths in sum read requests of values
+// by handle (0xF005), using the handle cache of WithHandleAccess. Bit fields
+// are read through the handle of the struct holding them.
+func (c *Client) sumReadByHandle(ctx context.Context, targets []*resolvedSymbol) ([]SumResult, error) {
+	names := make([]string, len(targets))
+	lengths := make([]uint32, len(targets))
+	offsets := make([]uint32, len(targets))
+	results := make([]SumResult, len(targets))
+	for i, target := range targets {
+		names[i], lengths[i] = target.name, target.size
+		if target.bitField != nil {
+			parent, offset, err := c.bitFieldParent(ctx, target)
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			names[i], lengths[i], offsets[i] = parent.name, parent.size, offset
+		}
+	}
+
+	sumResults, err := c.sumWithHandles(ctx, names, func(indices []int, handles []uint32) ([]SumResult, error) {
+		items := make([]ads.SumReadItem, len(indices))
+		for j, i := range indices {
+			items[j] = ads.SumReadItem{
+				IndexGroup:  ads.IndexGroupSymbolValueByHandle,
+				IndexOffset: handles[j],
+				Length:      lengths[i],
+			}
+		}
+		return c.sumRead(ctx, items)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, target := range targets {
+		if results[i].Err != nil {
+			continue
+		}
+		results[i] = sumResults[i]
+		if results[i].Err != nil || target.bitField == nil {
+			continue
+		}
+		if end := offsets[i] + target.size; uint32(len(results[i].Data)) >= end {
+			results[i].Data = results[i].Data[offsets[i]:end]
+		} else {
+			results[i] = SumResult{Err: fmt.Errorf("read bit field %q: got %d bytes of %q", target.name, len(results[i].Data), names[i])}
+		}
+	}
+	return results, nil
+}
+
+// sumWriteByHandle writes the data of items to resolved symbol paths in sum
+// write requests of values by handle (0xF005), using the handle cache of
+// WithHandleAccess. The paths must not be bit fields.
+func (c *Client) sumWriteByHandle(ctx context.Context, targets []*resolvedSymbol, items []ads.SumWriteItem) ([]SumResult, error) {
+	names := make([]string, len(targets))
+	for i, target := range targets {
+		names[i] = target.name
+	}
+
+	return c.sumWithHandles(ctx, names, func(indices []int, handles []uint32) ([]SumResult, error) {
+		byHandle := make([]ads.SumWriteItem, len(indices))
+		for j, i := range indices {
+			byHandle[j] = ads.SumWriteItem{
+				IndexGroup:  ads.IndexGroupSymbolValueByHandle,
+				IndexOffset: handles[j],
+				Data:        items[i].Data,
+			}
+		}
+		return c.sumWrite(ctx, byHandle)
+	})
+}
+
+// handleCache caches symbol handles by symbol path for WithHandleAccess.
+// The symbol version recorded at first use is compared against the PLC when a
+// handle is rejected, to tell an online change apart from a single stale handle.
+type handleCache struct {
+	mu           sync.Mutex
+	handles      map[string]uint32
+	version      uint8
+	versionKnown bool
+}
+
+func newHandleCache() *handleCache {
+	return &handleCache{handles: make(map[string]uint32)}
+}
+
+func (h *handleCache) get(name string) (uint32, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	handle, ok := h.handles[name]
+	return handle, ok
+}
+
+// add caches the handle of name unless a concurrent caller cached one first.
+// It returns the cached handle and whether it is the given one; a handle that
+// was not cached should be released.
+func (h *handleCache) add(name string, handle uint32) (uint32, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if cached, ok := h.handles[name]; ok {
+		return cached, cached == handle
+	}
+	h.handles[name] = handle
+	return handle, true
+}
+
+// remove drops the handle of name if it is still the cached one, so a handle
+// re-acquired meanwhile is kept. It reports whether the handle was dropped.
+func (h *handleCache) remove(name string, handle uint32) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if cached, ok := h.handles[name]; !ok || cached != handle {
+		return false
+	}
+	delete(h.handles, name)
+	return true
+}
+
+// reset drops all cached handles and the recorded symbol version.
+// It returns the dropped handles so the caller can release them on the PLC.
+func (h *handleCache) reset() []uint32 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	handles := make([]uint32, 0, len(h.handles))
+	for _, handle := range h.handles {
+		handles = append(handles, handle)
+	}
+	h.handles = make(map[string]uint32)
+	h.versionKnown = false
+	return handles
+}
+
+// ReadSymbolVersion reads the symbol version counter of the PLC (0xF008).
+// The counter is incremented by every online change or configuration activation
+// that changes the symbol table.
+func (c *Client) ReadSymbolVersion(ctx context.Context) (uint8, error) {
+	data, err := c.Read(ctx, ads.IndexGroupSymbolVersion, 0, 1)
+	if err != nil {
+		return 0, fmt.Errorf("read symbol version: %w", err)
+	}
+	if len(data) < 1 {
+		return 0, fmt.Errorf("invalid symbol version response: expected 1 byte, got %d", len(data))
+	}
+	return data[0], nil
+}
+
+// cachedSymbolHandle returns the cached handle for a symbol path or acquires a new one.
+func (c *Client) cachedSymbolHandle(ctx context.Context, symbolName string) (uint32, error) {
+	if handle, ok := c.handles.get(symbolName); ok {
+		return handle, nil
+	}
+
+	if err := c.recordHandleVersion(ctx); err != nil {
+		return 0, err
+	}
+
+	handle, err := c.GetSymbolHandle(ctx, symbolName)
+	if err != nil {
+		return 0, err
+	}
+
+	cached, stored := c.handles.add(symbolName, handle)
+	if !stored {
+		// Another call acquired a handle for the same path meanwhile
+		c.releaseDroppedHandles(ctx, handle)
+		return cached, nil
+	}
+	c.logger.Debug("acquired symbol handle", "symbol", symbolName, "handle", handle)
+	return handle, nil
+}
+
+// recordHandleVersion reads the symbol version the cached handles belong to,
+// unless it is known already.
+func (c *Client) recordHandleVersion(ctx context.Context) error {
+	c.handles.mu.Lock()
+	needVersion := !c.handles.versionKnown
+	c.handles.mu.Unlock()
+
+	if !needVersion {
+		return nil
+	}
+	version, err := c.ReadSymbolVersion(ctx)
+	if err != nil {
+		return err
+	}
+	c.handles.mu.Lock()
+	c.handles.version = version
+	c.handles.versionKnown = true
+	c.handles.mu.Unlock()
+	return nil
+}
+
+// cachedSymbolHandles returns the cached handles for several symbol paths,
+// acquiring the missing ones in sum requests. Entries that failed carry the
+// error of that path.
+func (c *Client) cachedSymbolHandles(ctx context.Context, symbolNames []string) ([]SymbolHandle, error) {
+	handles := make([]SymbolHandle, len(symbolNames))
+	var missing []string
+	seen := make(map[string]bool)
+	for i, name := range symbolNames {
+		handles[i].Name = name
+		if handle, ok := c.handles.get(name); ok {
+			handles[i].Handle = handle
+		} else if !seen[name] {
+			seen[name] = true
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return handles, nil
+	}
+
+	if err := c.recordHandleVersion(ctx); err != nil {
+		return nil, err
+	}
+	acquired, err := c.GetSymbolHandles(ctx, missing...)
+	if err != nil {
+		return nil, err
+	}
+
+	failed := make(map[string]error)
+	cached := make(map[string]uint32, len(acquired))
+	var duplicates []uint32
+	for _, h := range acquired {
+		if h.Err != nil {
+			failed[h.Name] = h.Err
+			continue
+		}
+		handle, stored := c.handles.add(h.Name, h.Handle)
+		cached[h.Name] = handle
+		if !stored {
+			// Another call acquired a handle for the same path meanwhile
+			duplicates = append(duplicates, h.Handle)
+			continue
+		}
+		c.logger.Debug("acquired symbol handle", "symbol", h.Name, "handle", h.Handle)
+	}
+	c.releaseDroppedHandles(ctx, duplicates...)
+
+	for i := range handles {
+		if !seen[handles[i].Name] {
+			continue
+		}
+		if err, ok := failed[handles[i].Name]; ok {
+			handles[i].Err = err
+			continue
+		}
+		handles[i].Handle = cached[handles[i].Name]
+	}
+	return handles, nil
+}
+
+// sumWithHandles runs a sum request on values accessed by the cached handles
+// of symbolNames. sum is called with the indices of the names that have a
+// handle and their handles, and returns one result per index. Items whose
+// handle the PLC rejects are retried once with fresh handles, as
+// withSymbolHandle does for a single value.
+func (c *Client) sumWithHandles(ctx context.Context, symbolNames []string, sum func(indices []int, handles []uint32) ([]SumResult, error)) ([]SumResult, error) {
+	results := make([]SumResult, len(symbolNames))
+	pending := make([]int, len(symbolNames))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	for attempt := 0; attempt < 2 && len(pending) > 0; attempt++ {
+		names := make([]string, len(pending))
+		for j, i := range pending {
+			names[j] = symbolNames[i]
+		}
+		handles, err := c.cachedSymbolHandles(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+
+		indices := make([]int, 0, len(pending))
+		values := make([]uint32, 0, len(pending))
+		for j, i := range pending {
+			if handles[j].Err != nil {
+				results[i].Err = handles[j].Err
+				continue
+			}
+			indices = append(indices, i)
+			values = append(values, handles[j].Handle)
+		}
+		if len(indices) == 0 {
+			break
+		}
+
+		sumResults, err := sum(indices, values)
+		if err != nil {
+			return nil, err
+		}
+
+		pending = pending[:0]
+		stale := make(map[string]uint32)
+		for j, i := range indices {
+			results[i] = sumResults[j]
+			if isStaleHandleError(sumResults[j].Err) {
+				pending = append(pending, i)
+				stale[symbolNames[i]] = values[j]
+			}
+		}
+		if attempt == 0 && len(stale) > 0 {
+			c.logger.Info("symbol handles rejected by PLC, re-acquiring", "count", len(stale))
+			c.invalidateStaleHandles(ctx, stale)
+		}
+	}
+	return results, nil
+}
+
+// withSymbolHandle runs fn with a cached handle for the symbol path.
+// If the PLC rejects the handle (symbol not found or symbol version invalid),
+// the cache is invalidated and fn is retried once with a freshly acquired handle.
+func (c *Client) withSymbolHandle(ctx context.Context, symbolName string, fn func(handle uint32) error) error {
+	handle, err := c.cachedSymbolHandle(ctx, symbolName)
+	if err != nil {
+		return err
+	}
+
+	err = fn(handle)
+	if !isStaleHandleError(err) {
+		return err
+	}
+
+	c.logger.Info("symbol handle rejected by PLC, re-acquiring", "symbol", symbolName, "handle", handle, "error", err)
+	c.invalidateStaleHandles(ctx, map[string]uint32{symbolName: handle})
+
+	handle, err = c.cachedSymbolHandle(ctx, symbolName)
+	if err != nil {
+		return err
+	}
+	return fn(handle)
+}
+
+// withPathHandle runs fn with a handle for the symbol path. The handle cache is
+// used if WithHandleAccess is set, otherwise a handle is acquired for this call
+// and released afterwards.
+func (c *Client) withPathHandle(ctx context.Context, symbolName string, fn func(handle uint32) error) error {
+	if c.handles != nil {
+		return c.withSymbolHandle(ctx, symbolName, fn)
+	}
+
+	handle, err := c.GetSymbolHandle(ctx, symbolName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Released even if ctx ended during fn, the handle would leak on the PLC
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.config.timeout)
+		defer cancel()
+		if err := c.ReleaseSymbolHandle(releaseCtx, handle); err != nil {
+			c.logger.Warn("failed to release symbol handle", "symbol", symbolName, "handle", handle, "error", err)
+		}
+	}()
+
+	return fn(handle)
+}
+
+// invalidateStaleHandles drops the rejected handles (by symbol path), or the
+// whole cache if the symbol version of the PLC changed since the handles were
+// acquired. The dropped handles are released.
+func (c *Client) invalidateStaleHandles(ctx context.Context, stale map[string]uint32) {
+	version, err := c.ReadSymbolVersion(ctx)
+
+	c.handles.mu.Lock()
+	changed := err != nil || !c.handles.versionKnown || c.handles.version != version
+	c.handles.mu.Unlock()
+
+	if !changed {
+		var dropped []uint32
+		for name, handle := range stale {
+			if c.handles.remove(name, handle) {
+				dropped = append(dropped, handle)
+			}
+		}
+		c.releaseDroppedHandles(ctx, dropped...)
+		return
+	}
+
+	c.logger.Info("symbol version changed, dropping symbol handle cache", "version", version)
+	c.releaseDroppedHandles(ctx, c.handles.reset()...)
+}
+
+// releaseDroppedHandles releases handles that are no longer cached, best-effort.
+// They are released even if ctx ended, they would leak on the PLC otherwise.
+// Handles from before an online change may be invalid already, so failures
+// are only logged.
+func (c *Client) releaseDroppedHandles(ctx context.Context, handles ...uint32) {
+	if len(handles) == 0 {
+		return
+	}
+	releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.config.timeout)
+	defer cancel()
+	if err := c.ReleaseSymbolHandles(releaseCtx, handles...); err != nil {
+		c.logger.Debug("failed to release dropped symbol handles", "count", len(handles), "error", err)
+	}
+}
+
+// isStaleHandleError reports whether err means a symbol handle is no longer valid.
+func isStaleHandleError(err error) bool {
+	var adsErr ads.Error
+	if !errors.As(err, &adsErr) {
+		return false
+	}
+	return adsErr == ads.ErrDeviceSymbolNotFound || adsErr == ads.ErrDeviceSymbolVersionInvalid
+}
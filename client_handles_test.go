@@ -0,0 +1,305 @@
+package goadstc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/mrpasztoradam/goadstc/adssim"
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+// flagsType is a struct of two BIT members.
+var flagsType = symbols.DataTypeEntry{
+	Name: "ST_Flags", Size: 1, DataType: symbols.DataTypeBigType, Flags: symbols.DataTypeFlagDataType,
+	SubItems: []symbols.DataTypeEntry{
+		{Name: "bEnable", Type: "BIT", Size: 1, Offset: 0, DataType: symbols.DataTypeBool, Flags: symbols.DataTypeFlagBitValues},
+		{Name: "bReady", Type: "BIT", Size: 1, Offset: 1, DataType: symbols.DataTypeBool, Flags: symbols.DataTypeFlagBitValues},
+	},
+}
+
+func TestHandleAccessOnlineChange(t *testing.T) {
+	server, client := startSimulator(t, adssim.Config{
+		DataTypes: []symbols.DataTypeEntry{flagsType},
+		Symbols: []adssim.Symbol{
+			{Name: "MAIN.counter", Type: "DINT", Value: []byte{1, 0, 0, 0}},
+			{Name: "MAIN.flags", Type: "ST_Flags", Value: []byte{0b01}},
+		},
+		// Without the symbol version watch, stale handles are only noticed
+		// when the PLC rejects them
+		Faults: []adssim.Fault{{
+			Kind: adssim.FaultError, Error: ads.ErrDeviceServiceNotSupported,
+			Command: ads.CmdAddDeviceNotification, IndexGroup: ads.IndexGroupSymbolVersion,
+		}},
+	}, WithHandleAccess())
+	ctx := context.Background()
+
+	// onlineChange re-creates the symbols at new addresses, which releases
+	// their handles on the PLC
+	onlineChange := func(counter int32, flags byte) {
+		t.Helper()
+		for _, symbol := range []adssim.Symbol{
+			{Name: "MAIN.counter", Type: "DINT", Value: []byte{byte(counter), 0, 0, 0}},
+			{Name: "MAIN.flags", Type: "ST_Flags", Value: []byte{flags}},
+		} {
+			if err := server.RemoveSymbol(symbol.Name); err != nil {
+				t.Fatalf("RemoveSymbol() error = %v", err)
+			}
+			if err := server.AddSymbol(symbol); err != nil {
+				t.Fatalf("AddSymbol() error = %v", err)
+			}
+		}
+	}
+	readAll := func() map[string]interface{} {
+		t.Helper()
+		values, err := client.ReadMultipleSymbolValues(ctx, "MAIN.counter", "MAIN.flags.bEnable", "MAIN.flags.bReady")
+		if err != nil {
+			t.Fatalf("ReadMultipleSymbolValues() error = %v", err)
+		}
+		return values
+	}
+
+	if values := readAll(); values["MAIN.counter"] != int32(1) || values["MAIN.flags.bEnable"] != true || values["MAIN.flags.bReady"] != false {
+		t.Fatalf("values = %v", values)
+	}
+
+	onlineChange(2, 0b10)
+	if values := readAll(); values["MAIN.counter"] != int32(2) || values["MAIN.flags.bEnable"] != false || values["MAIN.flags.bReady"] != true {
+		t.Fatalf("values after online change = %v", values)
+	}
+
+	onlineChange(2, 0b10)
+	results, err := client.WriteMultipleSymbolValues(ctx, map[string]any{"MAIN.counter": int32(3), "MAIN.flags.bEnable": true})
+	if err != nil {
+		t.Fatalf("WriteMultipleSymbolValues() error = %v", err)
+	}
+	for name, err := range results {
+		if err != nil {
+			t.Errorf("write %s: %v", name, err)
+		}
+	}
+	if data, _ := server.ReadSymbol("MAIN.counter"); data[0] != 3 {
+		t.Errorf("counter = %v, want 3", data)
+	}
+	if data, _ := server.ReadSymbol("MAIN.flags"); data[0] != 0b11 {
+		t.Errorf("flags = %08b, want 00000011", data[0])
+	}
+
+	onlineChange(3, 0b11)
+	if err := client.WriteSymbolValue(ctx, "MAIN.flags.bReady", false); err != nil {
+		t.Fatalf("WriteSymbolValue() error = %v", err)
+	}
+	if data, _ := server.ReadSymbol("MAIN.flags"); data[0] != 0b01 {
+		t.Errorf("flags = %08b, want 00000001", data[0])
+	}
+	if value, err := client.ReadSymbolValue(ctx, "MAIN.flags.bEnable"); err != nil || value != true {
+		t.Errorf("ReadSymbolValue() = %v, %v; want true", value, err)
+	}
+}
+
+func TestPathHandleReleasedAfterCancel(t *testing.T) {
+	server, client := startSimulator(t, adssim.Config{
+		Symbols: []adssim.Symbol{{Name: "MAIN.counter", Type: "DINT"}},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := client.withPathHandle(ctx, "MAIN.counter", func(handle uint32) error {
+		cancel()
+		return ctx.Err()
+	})
+	if err == nil {
+		t.Fatal("withPathHandle() expected the error of fn")
+	}
+	if n := server.Handles(); n != 0 {
+		t.Errorf("%d handles left on the PLC after cancellation", n)
+	}
+}
+
+// noVersionWatchConfig holds two symbols and rejects the symbol version watch,
+// so stale handles are only noticed when the PLC rejects them.
+var noVersionWatchConfig = adssim.Config{
+	Symbols: []adssim.Symbol{
+		{Name: "MAIN.counter", Type: "DINT", Value: []byte{1, 0, 0, 0}},
+		{Name: "MAIN.level", Type: "INT"},
+	},
+	Faults: []adssim.Fault{{
+		Kind: adssim.FaultError, Error: ads.ErrDeviceServiceNotSupported,
+		Command: ads.CmdAddDeviceNotification, IndexGroup: ads.IndexGroupSymbolVersion,
+	}},
+}
+
+func TestStaleHandlesReleased(t *testing.T) {
+	for _, onlineChange := range []bool{false, true} {
+		server, client := startSimulator(t, noVersionWatchConfig, WithHandleAccess())
+		ctx := context.Background()
+
+		for _, name := range []string{"MAIN.counter", "MAIN.level"} {
+			if _, err := client.ReadSymbolValue(ctx, name); err != nil {
+				t.Fatalf("ReadSymbolValue(%s) error = %v", name, err)
+			}
+		}
+		want := 2
+		if onlineChange {
+			// The handles stay valid on the simulator, but the client drops them all
+			if err := server.AddSymbol(adssim.Symbol{Name: "MAIN.added", Type: "INT"}); err != nil {
+				t.Fatalf("AddSymbol() error = %v", err)
+			}
+			want = 1
+		}
+
+		server.InjectFault(adssim.Fault{
+			Kind: adssim.FaultError, Error: ads.ErrDeviceSymbolNotFound,
+			Command: ads.CmdRead, IndexGroup: ads.IndexGroupSymbolValueByHandle, Count: 1,
+		})
+		if value, err := client.ReadSymbolValue(ctx, "MAIN.counter"); err != nil || value != int32(1) {
+			t.Fatalf("ReadSymbolValue() with rejected handle = %v, %v; want 1", value, err)
+		}
+		if n := server.Handles(); n != want {
+			t.Errorf("online change %v: %d handles on the PLC, want %d", onlineChange, n, want)
+		}
+	}
+}
+
+func TestCachedSymbolHandleConcurrent(t *testing.T) {
+	server, client := startSimulator(t, noVersionWatchConfig, WithHandleAccess())
+	ctx := context.Background()
+
+	const callers = 16
+	handles := make([]uint32, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				handles[i], errs[i] = client.cachedSymbolHandle(ctx, "MAIN.counter")
+				return
+			}
+			var result []SymbolHandle
+			result, errs[i] = client.cachedSymbolHandles(ctx, []string{"MAIN.counter"})
+			if errs[i] == nil {
+				handles[i], errs[i] = result[0].Handle, result[0].Err
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range handles {
+		if errs[i] != nil {
+			t.Fatalf("caller %d error = %v", i, errs[i])
+		}
+		if handles[i] != handles[0] {
+			t.Errorf("caller %d got handle %d, caller 0 got %d", i, handles[i], handles[0])
+		}
+	}
+	if n := server.Handles(); n != 1 {
+		t.Errorf("%d handles on the PLC, want 1", n)
+	}
+}
+
+func TestBulkSymbolHandles(t *testing.T) {
+	// More symbols than fit in one sum request
+	const count = 600
+	server, client := startSimulator(t, adssim.Config{Symbols: counterSymbols(count)})
+	ctx := context.Background()
+
+	// Every 100th name does not exist
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("MAIN.v%d", i)
+		if i%100 == 50 {
+			names[i] = fmt.Sprintf("MAIN.missing%d", i)
+		}
+	}
+	handles, err := client.GetSymbolHandles(ctx, names...)
+	if err != nil {
+		t.Fatalf("GetSymbolHandles() error = %v", err)
+	}
+	if len(handles) != count {
+		t.Fatalf("got %d handles, want %d", len(handles), count)
+	}
+
+	var valid []uint32
+	var indices []int
+	for i, h := range handles {
+		if h.Name != names[i] {
+			t.Fatalf("handle %d is for %q, want %q", i, h.Name, names[i])
+		}
+		missing := i%100 == 50
+		if missing != (h.Err != nil) {
+			t.Fatalf("%s: error = %v", h.Name, h.Err)
+		}
+		if missing {
+			if !errors.Is(h.Err, ads.ErrDeviceSymbolNotFound) {
+				t.Errorf("%s: error = %v, want symbol not found", h.Name, h.Err)
+			}
+			continue
+		}
+		valid = append(valid, h.Handle)
+		indices = append(indices, i)
+	}
+	if n := server.Handles(); n != len(valid) {
+		t.Errorf("PLC holds %d handles, want %d", n, len(valid))
+	}
+
+	// A released handle in the middle fails on its own
+	const staleHandle = 0xDEAD
+	readHandles := append(append(append([]uint32{}, valid[:300]...), staleHandle), valid[300:]...)
+	lengths := make([]uint32, len(readHandles))
+	for i := range lengths {
+		lengths[i] = 4
+	}
+	results, err := client.ReadByHandles(ctx, readHandles, lengths)
+	if err != nil {
+		t.Fatalf("ReadByHandles() error = %v", err)
+	}
+	for i, result := range results {
+		switch {
+		case i == 300:
+			if result.ADSErrorCode() != uint32(ads.ErrDeviceSymbolNotFound) {
+				t.Errorf("read of released handle: %v", result.Err)
+			}
+		case result.Err != nil:
+			t.Errorf("read %d: %v", i, result.Err)
+		default:
+			j := i
+			if i > 300 {
+				j--
+			}
+			if got := binary.LittleEndian.Uint32(result.Data); got != uint32(indices[j]) {
+				t.Errorf("read %s = %d", names[indices[j]], got)
+			}
+		}
+	}
+
+	data := make([][]byte, len(readHandles))
+	for i := range data {
+		data[i] = binary.LittleEndian.AppendUint32(nil, uint32(1000+i))
+	}
+	results, err = client.WriteByHandles(ctx, readHandles, data)
+	if err != nil {
+		t.Fatalf("WriteByHandles() error = %v", err)
+	}
+	for i, result := range results {
+		if (i == 300) != (result.Err != nil) {
+			t.Errorf("write %d: %v", i, result.Err)
+		}
+	}
+	if got, _ := server.ReadSymbol(names[indices[len(indices)-1]]); binary.LittleEndian.Uint32(got) != uint32(1000+len(readHandles)-1) {
+		t.Errorf("last value = %v after WriteByHandles", got)
+	}
+
+	// All handles are released, the failure of the unknown one is reported
+	err = client.ReleaseSymbolHandles(ctx, readHandles...)
+	if !errors.Is(err, ads.ErrDeviceSymbolNotFound) {
+		t.Errorf("ReleaseSymbolHandles() error = %v, want symbol not found for the released handle", err)
+	}
+	if n := server.Handles(); n != 0 {
+		t.Errorf("PLC holds %d handles after release", n)
+	}
+}
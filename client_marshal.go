@@ -0,0 +1,910 @@
+package goadstc
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+var (
+	durationType  = reflect.TypeOf(time.Duration(0))
+	timeType      = reflect.TypeOf(time.Time{})
+	enumValueType = reflect.TypeOf(EnumValue{})
+)
+
+// ReadInto reads a symbol and stores its value in the Go value pointed to by v.
+//
+// Go struct fields map to PLC struct members by `ads:"Name"` tags; untagged
+// exported fields match the member of the same name (case-insensitive) and
+// `ads:"-"` skips a field. PLC members without a Go field are ignored.
+// Nested structs, arrays (Go arrays or slices, multi-dimensional arrays as nested
+// or flat Go sequences), STRING(n)/WSTRING(n) as string, TIME/LTIME/TOD as
+// time.Duration, DATE/DT as time.Time, enums (integer, member name or EnumValue),
+// BIT members, []byte for raw data and interface{} for auto-detected values
+// are supported.
+//
+// Member offsets, sizes and types are checked against the PLC type information;
+// a mismatch is reported with the full member path, e.g.
+// "MAIN.stMachine.nCount: PLC type DINT (4 bytes) does not match Go type int16".
+//
+// Example:
+//
+//	type Axis struct {
+//	    Enabled  bool       `ads:"bEnabled"`
+//	    Position float64    `ads:"fPosition"`
+//	    Name     string     `ads:"sName"`
+//	    Limits   [2]float64 `ads:"aLimits"`
+//	}
+//	var axis Axis
+//	err := client.ReadInto(ctx, "MAIN.stAxis", &axis)
+func (c *Client) ReadInto(ctx context.Context, symbolName string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return ClassifyError(fmt.Errorf("read into %q: target must be a non-nil pointer, got %T", symbolName, v), "read_into")
+	}
+
+	if err := c.ensureSymbolsLoaded(ctx); err != nil {
+		return ClassifyError(err, "read_into")
+	}
+
+	target, err := c.resolveSymbolPath(ctx, symbolName)
+	if err != nil {
+		return ClassifyError(fmt.Errorf("read into %q: %w", symbolName, err), "read_into")
+	}
+
+	data, err := c.readResolved(ctx, target)
+	if err != nil {
+		return ClassifyError(fmt.Errorf("read into %q: %w", symbolName, err), "read_into")
+	}
+
+	if field := resolvedBitField(target); field != nil {
+		err = unmarshalBits(data, field, rv.Elem(), symbolName)
+	} else {
+		typeInfo := c.fullTypeInfo(ctx, target.symbol.Type)
+		err = c.unmarshalValue(ctx, data, typeInfo, rv.Elem(), symbolName)
+	}
+	if err != nil {
+		return ClassifyError(fmt.Errorf("read into %q: %w", symbolName, err), "read_into")
+	}
+	return nil
+}
+
+// WriteFrom writes a Go value to a symbol, using the same mapping rules as ReadInto.
+// The current value is read first, so PLC members without a Go field and
+// padding bytes keep their values. v may be a value or a pointer.
+func (c *Client) WriteFrom(ctx context.Context, symbolName string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return ClassifyError(fmt.Errorf("write from %q: value must not be nil", symbolName), "write_from")
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return ClassifyError(fmt.Errorf("write from %q: value must not be nil", symbolName), "write_from")
+	}
+
+	if err := c.ensureSymbolsLoaded(ctx); err != nil {
+		return ClassifyError(err, "write_from")
+	}
+
+	target, err := c.resolveSymbolPath(ctx, symbolName)
+	if err != nil {
+		return ClassifyError(fmt.Errorf("write from %q: %w", symbolName, err), "write_from")
+	}
+
+	data, err := c.readResolved(ctx, target)
+	if err != nil {
+		return ClassifyError(fmt.Errorf("write from %q: read current value: %w", symbolName, err), "write_from")
+	}
+
+	if field := resolvedBitField(target); field != nil {
+		err = marshalBits(data, field, rv, symbolName)
+	} else {
+		typeInfo := c.fullTypeInfo(ctx, target.symbol.Type)
+		err = c.marshalValue(ctx, data, typeInfo, rv, symbolName)
+	}
+	if err != nil {
+		return ClassifyError(fmt.Errorf("write from %q: %w", symbolName, err), "write_from")
+	}
+
+	if err := c.writeResolved(ctx, target, data); err != nil {
+		return ClassifyError(fmt.Errorf("write from %q: %w", symbolName, err), "write_from")
+	}
+	return nil
+}
+
+// resolvedBitField returns the bit layout of a path that addresses bits,
+// or nil for byte-aligned values.
+func resolvedBitField(target *resolvedSymbol) *symbols.FieldInfo {
+	if target.bitField != nil {
+		return target.bitField
+	}
+	if isBitSymbol(target.symbol) && !target.symbol.Type.IsArray {
+		bitSize := uint8(target.symbol.BitSize)
+		if bitSize == 0 {
+			bitSize = 1
+		}
+		return &symbols.FieldInfo{Name: target.name, Type: target.symbol.Type, BitSize: bitSize}
+	}
+	return nil
+}
+
+// fullTypeInfo completes the type information of a struct or enum type that
+// only carries its name, as symbol table entries do.
+func (c *Client) fullTypeInfo(ctx context.Context, typeInfo symbols.TypeInfo) symbols.TypeInfo {
+	switch {
+	case typeInfo.IsArray, len(typeInfo.Fields) > 0, len(typeInfo.EnumValues) > 0,
+		typeInfo.Name == "", isSimpleTypeName(typeInfo.Name), isStringTypeName(typeInfo.Name):
+		return typeInfo
+
+	case typeInfo.BaseType == symbols.DataTypeBigType:
+		fetched, err := c.getOrFetchTypeInfo(ctx, typeInfo.Name)
+		if err != nil {
+			return typeInfo
+		}
+		if fetched.Size == 0 {
+			fetched.Size = typeInfo.Size
+		}
+		return fetched
+
+	default:
+		if enumType, ok := c.lookupEnumType(ctx, typeInfo.Name); ok {
+			return enumType
+		}
+		return typeInfo
+	}
+}
+
+// unmarshalValue decodes PLC data of the given type into v.
+func (c *Client) unmarshalValue(ctx context.Context, data []byte, typeInfo symbols.TypeInfo, v reflect.Value, path string) error {
+	goType := v.Type()
+
+	switch {
+	case isByteSequence(goType):
+		return unmarshalRaw(data, v, path)
+	case goType == enumValueType:
+		if len(typeInfo.EnumValues) == 0 {
+			return typeMismatch(path, typeInfo, goType)
+		}
+		v.Set(reflect.ValueOf(decodeEnumValue(data, typeInfo)))
+		return nil
+	case goType == durationType:
+		d, err := decodeDuration(data, typeInfo)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case goType == timeType:
+		t, err := decodeTimestamp(data, typeInfo)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(goType.Elem()))
+		}
+		return c.unmarshalValue(ctx, data, typeInfo, v.Elem(), path)
+
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return typeMismatch(path, typeInfo, goType)
+		}
+		value, err := c.parseSymbolValue(ctx, data, &symbols.Symbol{Name: path, Type: typeInfo, Size: uint32(len(data))})
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if value != nil {
+			v.Set(reflect.ValueOf(value))
+		}
+		return nil
+
+	case reflect.Struct:
+		return c.unmarshalStruct(ctx, data, typeInfo, v, path)
+
+	case reflect.Array, reflect.Slice:
+		return c.unmarshalArray(ctx, data, typeInfo, v, path)
+
+	case reflect.Bool:
+		if classifyPLCType(typeInfo) != plcClassBool || len(data) != 1 {
+			return typeMismatch(path, typeInfo, goType)
+		}
+		v.SetBool(data[0] != 0)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if err := checkIntegerType(typeInfo, goType, len(data), path); err != nil {
+			return err
+		}
+		return setInteger(v, decodeInteger(data, isSignedPLCType(typeInfo)), path)
+
+	case reflect.Float32, reflect.Float64:
+		if classifyPLCType(typeInfo) != plcClassFloat || len(data) != int(goType.Size()) {
+			return typeMismatch(path, typeInfo, goType)
+		}
+		if len(data) == 4 {
+			v.SetFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(data))))
+		} else {
+			v.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(data)))
+		}
+		return nil
+
+	case reflect.String:
+		switch {
+		case len(typeInfo.EnumValues) > 0:
+			v.SetString(decodeEnumValue(data, typeInfo).Name)
+		case classifyPLCType(typeInfo) == plcClassString:
+			v.SetString(decodeString(data))
+		case classifyPLCType(typeInfo) == plcClassWString:
+			v.SetString(decodeWString(data))
+		default:
+			return typeMismatch(path, typeInfo, goType)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%s: unsupported Go type %s", path, goType)
+}
+
+// marshalValue encodes v into data, which holds the current PLC value of the given type.
+func (c *Client) marshalValue(ctx context.Context, data []byte, typeInfo symbols.TypeInfo, v reflect.Value, path string) error {
+	goType := v.Type()
+
+	switch {
+	case isByteSequence(goType):
+		return marshalRaw(data, v, path)
+	case goType == enumValueType:
+		if len(typeInfo.EnumValues) == 0 {
+			return typeMismatch(path, typeInfo, goType)
+		}
+		encoded, err := encodeEnumValue(v.Interface(), typeInfo, uint32(len(data)))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		copy(data, encoded)
+		return nil
+	case goType == durationType:
+		return encodeDuration(data, typeInfo, time.Duration(v.Int()), path)
+	case goType == timeType:
+		return encodeTimestamp(data, typeInfo, v.Interface().(time.Time), path)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil // keep the current PLC value
+		}
+		return c.marshalValue(ctx, data, typeInfo, v.Elem(), path)
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		encoded, err := c.encodeSymbolValue(ctx, v.Elem().Interface(), &symbols.Symbol{Name: path, Type: typeInfo, Size: uint32(len(data))})
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if len(encoded) != len(data) {
+			return fmt.Errorf("%s: encoded %d bytes, PLC type %s has %d", path, len(encoded), typeInfo.Name, len(data))
+		}
+		copy(data, encoded)
+		return nil
+
+	case reflect.Struct:
+		return c.marshalStruct(ctx, data, typeInfo, v, path)
+
+	case reflect.Array, reflect.Slice:
+		return c.marshalArray(ctx, data, typeInfo, v, path)
+
+	case reflect.Bool:
+		if classifyPLCType(typeInfo) != plcClassBool || len(data) != 1 {
+			return typeMismatch(path, typeInfo, goType)
+		}
+		data[0] = 0
+		if v.Bool() {
+			data[0] = 1
+		}
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if err := checkIntegerType(typeInfo, goType, len(data), path); err != nil {
+			return err
+		}
+		return encodeInteger(data, uint64(v.Int()), v.Int() < 0, isSignedPLCType(typeInfo), path)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if err := checkIntegerType(typeInfo, goType, len(data), path); err != nil {
+			return err
+		}
+		return encodeInteger(data, v.Uint(), false, isSignedPLCType(typeInfo), path)
+
+	case reflect.Float32, reflect.Float64:
+		if classifyPLCType(typeInfo) != plcClassFloat || len(data) != int(goType.Size()) {
+			return typeMismatch(path, typeInfo, goType)
+		}
+		if len(data) == 4 {
+			binary.LittleEndian.PutUint32(data, math.Float32bits(float32(v.Float())))
+		} else {
+			binary.LittleEndian.PutUint64(data, math.Float64bits(v.Float()))
+		}
+		return nil
+
+	case reflect.String:
+		switch {
+		case len(typeInfo.EnumValues) > 0:
+			encoded, err := encodeEnumValue(v.String(), typeInfo, uint32(len(data)))
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			copy(data, encoded)
+			return nil
+		case classifyPLCType(typeInfo) == plcClassString:
+			return encodeString(data, v.String(), path)
+		case classifyPLCType(typeInfo) == plcClassWString:
+			return encodeWString(data, v.String(), path)
+		default:
+			return typeMismatch(path, typeInfo, goType)
+		}
+	}
+
+	return fmt.Errorf("%s: unsupported Go type %s", path, goType)
+}
+
+// structBinding pairs a Go struct field with the PLC member it maps to.
+type structBinding struct {
+	index  int
+	member symbols.FieldInfo
+}
+
+// bindStruct maps the fields of a Go struct type to the members of a PLC struct.
+func bindStruct(goType reflect.Type, typeInfo symbols.TypeInfo, path string) ([]structBinding, error) {
+	if len(typeInfo.Fields) == 0 {
+		return nil, fmt.Errorf("%s: no member information for PLC type %s (Go type %s)", path, typeInfo.Name, goType)
+	}
+
+	bindings := make([]structBinding, 0, goType.NumField())
+	for i := 0; i < goType.NumField(); i++ {
+		field := goType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("ads"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+
+		member, ok := findMember(typeInfo, name)
+		if !ok {
+			return nil, fmt.Errorf("%s: PLC type %s has no member %q for Go field %s", path, typeInfo.Name, name, field.Name)
+		}
+		bindings = append(bindings, structBinding{index: i, member: member})
+	}
+	return bindings, nil
+}
+
+// findMember finds a struct member by name, preferring an exact match.
+func findMember(typeInfo symbols.TypeInfo, name string) (symbols.FieldInfo, bool) {
+	for _, field := range typeInfo.Fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	for _, field := range typeInfo.Fields {
+		if strings.EqualFold(field.Name, name) {
+			return field, true
+		}
+	}
+	return symbols.FieldInfo{}, false
+}
+
+// memberData returns the bytes of a struct member, checking that it lies within the struct.
+func memberData(data []byte, member symbols.FieldInfo, typeInfo symbols.TypeInfo, path string) ([]byte, error) {
+	end := uint64(member.Offset) + uint64(member.Type.Size)
+	if end > uint64(len(data)) {
+		return nil, fmt.Errorf("%s: member at offset %d with size %d exceeds %s size %d",
+			path, member.Offset, member.Type.Size, typeInfo.Name, len(data))
+	}
+	return data[member.Offset:end], nil
+}
+
+func (c *Client) unmarshalStruct(ctx context.Context, data []byte, typeInfo symbols.TypeInfo, v reflect.Value, path string) error {
+	bindings, err := bindStruct(v.Type(), typeInfo, path)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bindings {
+		memberPath := path + "." + b.member.Name
+		memberBytes, err := memberData(data, b.member, typeInfo, memberPath)
+		if err != nil {
+			return err
+		}
+		if b.member.BitSize > 0 {
+			err = unmarshalBits(memberBytes, &b.member, v.Field(b.index), memberPath)
+		} else {
+			err = c.unmarshalValue(ctx, memberBytes, c.fullTypeInfo(ctx, b.member.Type), v.Field(b.index), memberPath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) marshalStruct(ctx context.Context, data []byte, typeInfo symbols.TypeInfo, v reflect.Value, path string) error {
+	bindings, err := bindStruct(v.Type(), typeInfo, path)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bindings {
+		memberPath := path + "." + b.member.Name
+		memberBytes, err := memberData(data, b.member, typeInfo, memberPath)
+		if err != nil {
+			return err
+		}
+		if b.member.BitSize > 0 {
+			err = marshalBits(memberBytes, &b.member, v.Field(b.index), memberPath)
+		} else {
+			err = c.marshalValue(ctx, memberBytes, c.fullTypeInfo(ctx, b.member.Type), v.Field(b.index), memberPath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// arrayLayout describes the dimensions and element type of a PLC array.
+type arrayLayout struct {
+	dims        []symbols.ArrayDimension
+	element     symbols.TypeInfo
+	elementSize uint32
+}
+
+func (c *Client) arrayLayout(ctx context.Context, data []byte, typeInfo symbols.TypeInfo, goType reflect.Type, path string) (arrayLayout, error) {
+	dims := arrayDimensions(typeInfo)
+	if len(dims) == 0 {
+		return arrayLayout{}, typeMismatch(path, typeInfo, goType)
+	}
+
+	total := uint32(1)
+	for _, dim := range dims {
+		total *= dim.Elements
+	}
+	if total == 0 || uint32(len(data))%total != 0 {
+		return arrayLayout{}, fmt.Errorf("%s: PLC array %s of %d bytes cannot hold %d elements", path, typeInfo.Name, len(data), total)
+	}
+
+	elementSize := uint32(len(data)) / total
+	return arrayLayout{
+		dims:        dims,
+		element:     c.fullTypeInfo(ctx, c.arrayElementType(ctx, typeInfo, elementSize)),
+		elementSize: elementSize,
+	}, nil
+}
+
+func (c *Client) unmarshalArray(ctx context.Context, data []byte, typeInfo symbols.TypeInfo, v reflect.Value, path string) error {
+	layout, err := c.arrayLayout(ctx, data, typeInfo, v.Type(), path)
+	if err != nil {
+		return err
+	}
+	return layout.walk(data, v, path, 0, nil, true, func(elem []byte, ev reflect.Value, elemPath string) error {
+		return c.unmarshalValue(ctx, elem, layout.element, ev, elemPath)
+	})
+}
+
+func (c *Client) marshalArray(ctx context.Context, data []byte, typeInfo symbols.TypeInfo, v reflect.Value, path string) error {
+	layout, err := c.arrayLayout(ctx, data, typeInfo, v.Type(), path)
+	if err != nil {
+		return err
+	}
+	return layout.walk(data, v, path, 0, nil, false, func(elem []byte, ev reflect.Value, elemPath string) error {
+		return c.marshalValue(ctx, elem, layout.element, ev, elemPath)
+	})
+}
+
+// walk calls fn for every element of the array, matching the Go sequence v
+// against the dimensions starting at dim. A Go sequence whose elements are
+// sequences themselves takes one dimension per level; otherwise it takes all
+// remaining dimensions flattened in row-major order. With allocate set, empty
+// slices are allocated with the PLC length; otherwise lengths must match exactly.
+func (l arrayLayout) walk(data []byte, v reflect.Value, path string, dim int, prefix []int, allocate bool,
+	fn func(elem []byte, ev reflect.Value, elemPath string) error) error {
+	rest := l.dims[dim:]
+	nested := len(rest) > 1 && isSequenceType(v.Type().Elem())
+
+	count := rest[0].Elements
+	if !nested {
+		for _, d := range rest[1:] {
+			count *= d.Elements
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		if allocate && v.Len() == 0 {
+			v.Set(reflect.MakeSlice(v.Type(), int(count), int(count)))
+		}
+	case reflect.Array:
+	default:
+		return fmt.Errorf("%s: expected Go array or slice, got %s", path, v.Type())
+	}
+	if v.Len() != int(count) {
+		return fmt.Errorf("%s: Go %s has %d elements, PLC array %s has %d", path, v.Type(), v.Len(), formatBounds(rest, nested), count)
+	}
+
+	stride := l.elementSize * (uint32(len(data)) / l.elementSize / count)
+	for i := 0; i < int(count); i++ {
+		elem := data[uint32(i)*stride : uint32(i+1)*stride]
+		if nested {
+			indices := append(append([]int(nil), prefix...), int(rest[0].LowerBound)+i)
+			if err := l.walk(elem, v.Index(i), path, dim+1, indices, allocate, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		indices := append(append([]int(nil), prefix...), elementIndices(rest, i)...)
+		if err := fn(elem, v.Index(i), path+formatIndices(indices)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// elementIndices converts a row-major position into PLC indices.
+func elementIndices(dims []symbols.ArrayDimension, linear int) []int {
+	indices := make([]int, len(dims))
+	for i := len(dims) - 1; i >= 0; i-- {
+		n := int(dims[i].Elements)
+		indices[i] = int(dims[i].LowerBound) + linear%n
+		linear /= n
+	}
+	return indices
+}
+
+// formatBounds formats array bounds in PLC notation, e.g. "[1..10,-5..5]".
+func formatBounds(dims []symbols.ArrayDimension, firstOnly bool) string {
+	if firstOnly {
+		dims = dims[:1]
+	}
+	parts := make([]string, len(dims))
+	for i, dim := range dims {
+		parts[i] = fmt.Sprintf("%d..%d", dim.LowerBound, dim.UpperBound())
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+func isSequenceType(t reflect.Type) bool {
+	return t.Kind() == reflect.Array || t.Kind() == reflect.Slice
+}
+
+func isByteSequence(t reflect.Type) bool {
+	return isSequenceType(t) && t.Elem().Kind() == reflect.Uint8
+}
+
+func unmarshalRaw(data []byte, v reflect.Value, path string) error {
+	if v.Kind() == reflect.Slice {
+		v.SetBytes(append([]byte(nil), data...))
+		return nil
+	}
+	if v.Len() != len(data) {
+		return fmt.Errorf("%s: Go %s has %d bytes, PLC value has %d", path, v.Type(), v.Len(), len(data))
+	}
+	reflect.Copy(v, reflect.ValueOf(data))
+	return nil
+}
+
+func marshalRaw(data []byte, v reflect.Value, path string) error {
+	if v.Len() != len(data) {
+		return fmt.Errorf("%s: Go %s has %d bytes, PLC value has %d", path, v.Type(), v.Len(), len(data))
+	}
+	reflect.Copy(reflect.ValueOf(data), v)
+	return nil
+}
+
+// unmarshalBits decodes a bit field into a Go bool (single bit) or unsigned integer.
+func unmarshalBits(data []byte, field *symbols.FieldInfo, v reflect.Value, path string) error {
+	bits := extractBits(data, field.BitOffset, field.BitSize)
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if field.BitSize != 1 {
+			return fmt.Errorf("%s: %d-bit member does not fit Go type bool", path, field.BitSize)
+		}
+		v.SetBool(bits != 0)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return setInteger(v, bits, path)
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(bitFieldValue(bits, field.BitSize)))
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: %d-bit member does not fit Go type %s", path, field.BitSize, v.Type())
+}
+
+// marshalBits stores a Go bool or integer in a bit field, leaving other bits untouched.
+func marshalBits(data []byte, field *symbols.FieldInfo, v reflect.Value, path string) error {
+	var value interface{}
+	switch v.Kind() {
+	case reflect.Bool:
+		value = v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = v.Uint()
+	case reflect.Interface:
+		value = v.Interface()
+	default:
+		return fmt.Errorf("%s: %d-bit member does not fit Go type %s", path, field.BitSize, v.Type())
+	}
+
+	bits, err := bitsFromValue(value, field.BitSize)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	insertBits(data, field.BitOffset, field.BitSize, bits)
+	return nil
+}
+
+// plcTypeClass groups PLC types by the Go kinds they convert to.
+type plcTypeClass int
+
+const (
+	plcClassOther plcTypeClass = iota
+	plcClassBool
+	plcClassInteger
+	plcClassFloat
+	plcClassString
+	plcClassWString
+)
+
+// classifyPLCType determines the class of a PLC type from its data type ID or name.
+func classifyPLCType(typeInfo symbols.TypeInfo) plcTypeClass {
+	name := strings.ToUpper(strings.TrimSpace(typeInfo.Name))
+	switch {
+	case strings.HasPrefix(name, "WSTRING"):
+		return plcClassWString
+	case strings.HasPrefix(name, "STRING"):
+		return plcClassString
+	}
+
+	switch typeInfo.BaseType {
+	case symbols.DataTypeBool, symbols.DataTypeBit:
+		return plcClassBool
+	case symbols.DataTypeInt8, symbols.DataTypeUInt8, symbols.DataTypeInt16, symbols.DataTypeUInt16,
+		symbols.DataTypeInt32, symbols.DataTypeUInt32, symbols.DataTypeInt64, symbols.DataTypeUInt64:
+		return plcClassInteger
+	case symbols.DataTypeReal32, symbols.DataTypeReal64:
+		return plcClassFloat
+	case symbols.DataTypeString:
+		return plcClassString
+	case symbols.DataTypeWString:
+		return plcClassWString
+	}
+
+	switch name {
+	case "BOOL", "BIT":
+		return plcClassBool
+	case "SINT", "USINT", "BYTE", "INT", "UINT", "WORD", "DINT", "UDINT", "DWORD", "LINT", "ULINT", "LWORD",
+		"TIME", "LTIME", "TOD", "TIME_OF_DAY", "DATE", "DT", "DATE_AND_TIME":
+		return plcClassInteger
+	case "REAL", "LREAL":
+		return plcClassFloat
+	}
+	return plcClassOther
+}
+
+// isSignedPLCType reports whether a PLC integer type is signed.
+func isSignedPLCType(typeInfo symbols.TypeInfo) bool {
+	switch typeInfo.BaseType {
+	case symbols.DataTypeInt8, symbols.DataTypeInt16, symbols.DataTypeInt32, symbols.DataTypeInt64:
+		return true
+	case symbols.DataTypeUInt8, symbols.DataTypeUInt16, symbols.DataTypeUInt32, symbols.DataTypeUInt64:
+		return false
+	}
+	switch strings.ToUpper(typeInfo.Name) {
+	case "SINT", "INT", "DINT", "LINT", "TIME", "LTIME":
+		return true
+	}
+	return false
+}
+
+// isStringTypeName reports whether a type name is STRING or WSTRING, with or without length.
+func isStringTypeName(typeName string) bool {
+	name := strings.ToUpper(strings.TrimSpace(typeName))
+	return strings.HasPrefix(name, "STRING") || strings.HasPrefix(name, "WSTRING")
+}
+
+func typeMismatch(path string, typeInfo symbols.TypeInfo, goType reflect.Type) error {
+	return fmt.Errorf("%s: PLC type %s (%d bytes) does not match Go type %s", path, typeInfo.Name, typeInfo.Size, goType)
+}
+
+// checkIntegerType validates a PLC integer against a Go integer type.
+// int and uint accept every PLC integer size, sized Go types must match exactly.
+func checkIntegerType(typeInfo symbols.TypeInfo, goType reflect.Type, size int, path string) error {
+	if classifyPLCType(typeInfo) != plcClassInteger || size < 1 || size > 8 {
+		return typeMismatch(path, typeInfo, goType)
+	}
+	switch goType.Kind() {
+	case reflect.Int, reflect.Uint, reflect.Uintptr:
+		return nil
+	}
+	if int(goType.Size()) != size {
+		return typeMismatch(path, typeInfo, goType)
+	}
+	return nil
+}
+
+// decodeInteger decodes a little-endian integer of 1 to 8 bytes, sign-extended if signed.
+func decodeInteger(data []byte, signed bool) uint64 {
+	var buf [8]byte
+	copy(buf[:], data)
+	value := binary.LittleEndian.Uint64(buf[:])
+	if signed && len(data) < 8 {
+		shift := 64 - 8*uint(len(data))
+		value = uint64(int64(value<<shift) >> shift)
+	}
+	return value
+}
+
+// setInteger stores a decoded integer in a Go integer value, rejecting overflow.
+func setInteger(v reflect.Value, value uint64, path string) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.OverflowInt(int64(value)) {
+			return fmt.Errorf("%s: value %d overflows Go type %s", path, int64(value), v.Type())
+		}
+		v.SetInt(int64(value))
+	default:
+		if v.OverflowUint(value) {
+			return fmt.Errorf("%s: value %d overflows Go type %s", path, value, v.Type())
+		}
+		v.SetUint(value)
+	}
+	return nil
+}
+
+// encodeInteger stores an integer in a PLC integer of len(data) bytes, rejecting
+// values outside its range.
+func encodeInteger(data []byte, value uint64, negative, signed bool, path string) error {
+	bits := 8 * uint(len(data))
+	var fits bool
+	switch {
+	case signed && bits == 64, !signed && !negative && bits == 64:
+		fits = true
+	case signed:
+		n := int64(value)
+		fits = n >= -(1<<(bits-1)) && n < 1<<(bits-1)
+	default:
+		fits = !negative && value < 1<<bits
+	}
+	if !fits {
+		if negative {
+			return fmt.Errorf("%s: value %d out of range for %d-byte PLC integer", path, int64(value), len(data))
+		}
+		return fmt.Errorf("%s: value %d out of range for %d-byte PLC integer", path, value, len(data))
+	}
+
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], value)
+	copy(data, buf[:len(data)])
+	return nil
+}
+
+// decodeDuration decodes TIME and TIME_OF_DAY (milliseconds) and LTIME (nanoseconds).
+func decodeDuration(data []byte, typeInfo symbols.TypeInfo) (time.Duration, error) {
+	switch name := strings.ToUpper(typeInfo.Name); {
+	case name == "LTIME" && len(data) == 8:
+		return time.Duration(binary.LittleEndian.Uint64(data)), nil
+	case name == "TIME" && len(data) == 4:
+		return time.Duration(int32(binary.LittleEndian.Uint32(data))) * time.Millisecond, nil
+	case (name == "TOD" || name == "TIME_OF_DAY") && len(data) == 4:
+		return time.Duration(binary.LittleEndian.Uint32(data)) * time.Millisecond, nil
+	}
+	return 0, fmt.Errorf("PLC type %s (%d bytes) does not match Go type time.Duration", typeInfo.Name, len(data))
+}
+
+func encodeDuration(data []byte, typeInfo symbols.TypeInfo, d time.Duration, path string) error {
+	switch name := strings.ToUpper(typeInfo.Name); {
+	case name == "LTIME" && len(data) == 8:
+		binary.LittleEndian.PutUint64(data, uint64(d))
+		return nil
+	case (name == "TIME" || name == "TOD" || name == "TIME_OF_DAY") && len(data) == 4:
+		binary.LittleEndian.PutUint32(data, uint32(d/time.Millisecond))
+		return nil
+	}
+	return typeMismatch(path, typeInfo, durationType)
+}
+
+// decodeTimestamp decodes DATE and DATE_AND_TIME (seconds since 1970-01-01).
+func decodeTimestamp(data []byte, typeInfo symbols.TypeInfo) (time.Time, error) {
+	switch strings.ToUpper(typeInfo.Name) {
+	case "DATE", "DT", "DATE_AND_TIME":
+		if len(data) == 4 {
+			return time.Unix(int64(binary.LittleEndian.Uint32(data)), 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("PLC type %s (%d bytes) does not match Go type time.Time", typeInfo.Name, len(data))
+}
+
+func encodeTimestamp(data []byte, typeInfo symbols.TypeInfo, t time.Time, path string) error {
+	switch strings.ToUpper(typeInfo.Name) {
+	case "DATE", "DT", "DATE_AND_TIME":
+		if len(data) == 4 {
+			binary.LittleEndian.PutUint32(data, uint32(t.Unix()))
+			return nil
+		}
+	}
+	return typeMismatch(path, typeInfo, timeType)
+}
+
+// decodeString decodes a null-terminated STRING.
+func decodeString(data []byte) string {
+	for i, b := range data {
+		if b == 0 {
+			return string(data[:i])
+		}
+	}
+	return string(data)
+}
+
+// encodeString stores a null-terminated STRING, rejecting values that do not fit.
+func encodeString(data []byte, s, path string) error {
+	if len(s) > len(data)-1 {
+		return fmt.Errorf("%s: string of %d bytes exceeds STRING(%d)", path, len(s), len(data)-1)
+	}
+	copy(data, s)
+	clear(data[len(s):])
+	return nil
+}
+
+// decodeWString decodes a null-terminated UTF-16LE WSTRING.
+func decodeWString(data []byte) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		u := binary.LittleEndian.Uint16(data[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units))
+}
+
+// encodeWString stores a null-terminated UTF-16LE WSTRING, rejecting values that do not fit.
+func encodeWString(data []byte, s, path string) error {
+	units := utf16.Encode([]rune(s))
+	maxUnits := len(data)/2 - 1
+	if len(units) > maxUnits {
+		return fmt.Errorf("%s: string of %d characters exceeds WSTRING(%d)", path, len(units), maxUnits)
+	}
+	clear(data)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(data[i*2:], u)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package goadstc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+func testMachineType() symbols.TypeInfo {
+	inner := symbols.TypeInfo{
+		Name: "ST_Inner", BaseType: symbols.DataTypeBigType, Size: 8, IsStruct: true,
+		Fields: []symbols.FieldInfo{
+			{Name: "fValue", Offset: 0, Type: symbols.TypeInfo{Name: "LREAL", BaseType: symbols.DataTypeReal64, Size: 8}},
+		},
+	}
+	return symbols.TypeInfo{
+		Name: "ST_Machine", BaseType: symbols.DataTypeBigType, Size: 48, IsStruct: true,
+		Fields: []symbols.FieldInfo{
+			{Name: "bEnable", Offset: 0, Type: symbols.TypeInfo{Name: "BOOL", BaseType: symbols.DataTypeBool, Size: 1}},
+			{Name: "bDoor", Offset: 1, BitOffset: 2, BitSize: 1, Type: symbols.TypeInfo{Name: "BIT", BaseType: symbols.DataTypeBit, Size: 1}},
+			{Name: "nCount", Offset: 4, Type: symbols.TypeInfo{Name: "DINT", BaseType: symbols.DataTypeInt32, Size: 4}},
+			{Name: "sName", Offset: 8, Type: symbols.TypeInfo{Name: "STRING(10)", BaseType: symbols.DataTypeString, Size: 11}},
+			{Name: "aValues", Offset: 20, Type: symbols.ParseTypeInfo("ARRAY [1..2, 0..1] OF INT", symbols.DataTypeInt16, 8)},
+			{Name: "tCycle", Offset: 28, Type: symbols.TypeInfo{Name: "TIME", BaseType: symbols.DataTypeUInt32, Size: 4}},
+			{Name: "stInner", Offset: 32, Type: inner},
+			{Name: "nSpare", Offset: 40, Type: symbols.TypeInfo{Name: "LINT", BaseType: symbols.DataTypeInt64, Size: 8}},
+		},
+	}
+}
+
+type testInner struct {
+	Value float64 `ads:"fValue"`
+}
+
+type testMachine struct {
+	Enable bool          `ads:"bEnable"`
+	Door   bool          `ads:"bDoor"`
+	Count  int32         `ads:"nCount"`
+	Name   string        `ads:"sName"`
+	Values [2][2]int16   `ads:"aValues"`
+	Cycle  time.Duration `ads:"tCycle"`
+	Inner  testInner     `ads:"stInner"`
+	Note   string        `ads:"-"`
+}
+
+func TestMarshalStructRoundTrip(t *testing.T) {
+	c := &Client{}
+	ctx := context.Background()
+	typeInfo := testMachineType()
+
+	data := make([]byte, 48)
+	data[0] = 1
+	data[1] = 0b0000_0100
+	binary.LittleEndian.PutUint32(data[4:], uint32(0xFFFFFFF6)) // -10
+	copy(data[8:], "press")
+	for i, v := range []int16{1, 2, 3, -4} {
+		binary.LittleEndian.PutUint16(data[20+2*i:], uint16(v))
+	}
+	binary.LittleEndian.PutUint32(data[28:], 250)
+	binary.LittleEndian.PutUint64(data[32:], math.Float64bits(1.5))
+	data[40] = 0xAA // not mapped, must survive writes
+
+	var got testMachine
+	if err := c.unmarshalValue(ctx, data, typeInfo, reflect.ValueOf(&got).Elem(), "MAIN.st"); err != nil {
+		t.Fatalf("unmarshalValue() error = %v", err)
+	}
+	want := testMachine{
+		Enable: true, Door: true, Count: -10, Name: "press",
+		Values: [2][2]int16{{1, 2}, {3, -4}}, Cycle: 250 * time.Millisecond, Inner: testInner{Value: 1.5},
+	}
+	if got != want {
+		t.Errorf("unmarshalValue() = %+v, want %+v", got, want)
+	}
+
+	out := make([]byte, 48)
+	out[40] = 0xAA
+	if err := c.marshalValue(ctx, out, typeInfo, reflect.ValueOf(want), "MAIN.st"); err != nil {
+		t.Fatalf("marshalValue() error = %v", err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Errorf("marshalValue() =\n%v\nwant\n%v", out, data)
+	}
+
+	// Flat slice for the two-dimensional array
+	var flat struct {
+		Values []int16 `ads:"aValues"`
+	}
+	if err := c.unmarshalValue(ctx, data, typeInfo, reflect.ValueOf(&flat).Elem(), "MAIN.st"); err != nil {
+		t.Fatalf("unmarshalValue() flat error = %v", err)
+	}
+	if !reflect.DeepEqual(flat.Values, []int16{1, 2, 3, -4}) {
+		t.Errorf("flat values = %v", flat.Values)
+	}
+}
+
+func TestMarshalStructMismatch(t *testing.T) {
+	c := &Client{}
+	ctx := context.Background()
+	typeInfo := testMachineType()
+	data := make([]byte, 48)
+
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"size", &struct {
+			Count int16 `ads:"nCount"`
+		}{}, "MAIN.st.nCount: PLC type DINT (4 bytes) does not match Go type int16"},
+		{"member", &struct{ Missing bool }{}, `has no member "Missing"`},
+		{"array length", &struct {
+			Values [3]int16 `ads:"aValues"`
+		}{}, "MAIN.st.aValues: Go [3]int16 has 3 elements"},
+		{"class", &struct {
+			Name float32 `ads:"sName"`
+		}{}, "MAIN.st.sName: PLC type STRING(10)"},
+	}
+
+	for _, tt := range tests {
+		err := c.unmarshalValue(ctx, data, typeInfo, reflect.ValueOf(tt.v).Elem(), "MAIN.st")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	long := struct {
+		Name string `ads:"sName"`
+	}{Name: "much too long"}
+	if err := c.marshalValue(ctx, data, typeInfo, reflect.ValueOf(long), "MAIN.st"); err == nil {
+		t.Error("marshalValue() expected error for string exceeding STRING(10)")
+	}
+}
@@ -8,16 +8,73 @@ import (
 
 	"github.com/mrpasztoradam/goadstc/internal/ads"
 	"github.com/mrpasztoradam/goadstc/internal/ams"
+	"github.com/mrpasztoradam/goadstc/internal/transport"
 )
 
 // Subscribe creates a new notification subscription.
 // The returned Subscription will deliver notifications via its Notifications() channel.
 // Call Close() on the Subscription when done to clean up resources.
 func (c *Client) Subscribe(ctx context.Context, opts NotificationOptions) (*Subscription, error) {
+	return c.subscribe(ctx, opts, nil)
+}
+
+// subscribe creates a notification subscription. A decoder, if given, is attached
+// before the subscription is registered so that no sample bypasses it.
+func (c *Client) subscribe(ctx context.Context, opts NotificationOptions, decoder *notificationDecoder) (*Subscription, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
 	start := time.Now()
 	c.metrics.OperationStarted("subscribe")
 	c.logger.Debug("creating subscription", "indexGroup", opts.IndexGroup, "indexOffset", opts.IndexOffset)
 
+	handle, err := c.addNotification(ctx, opts)
+	c.metrics.OperationCompleted("subscribe", time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create subscription
+	sub := &Subscription{
+		handle:  handle,
+		client:  c,
+		notifCh: make(chan Notification, opts.bufferSize()),
+		events:  make(chan SubscriptionEvent, 8),
+		active:  true,
+		closed:  false,
+		closeMu: sync.Mutex{},
+		stop:    make(chan struct{}),
+		opts:    opts,
+		decoder: decoder,
+	}
+	if decoder != nil {
+		// Buffering and the overflow policy apply to the raw samples; the
+		// decoder hands each decoded value over without further buffering
+		decoder.rawCh = sub.notifCh
+		sub.notifCh = make(chan Notification)
+		decoder.done = make(chan struct{})
+		go sub.runDecoder()
+	}
+
+	c.registerSubscription(sub)
+	return sub, nil
+}
+
+// registerSubscription adds a new subscription to the registry, from where
+// notifications are routed to it.
+func (c *Client) registerSubscription(sub *Subscription) {
+	c.subscriptionsMu.Lock()
+	c.subscriptions[sub.handle] = sub
+	subCount := len(c.subscriptions)
+	c.subscriptionsMu.Unlock()
+
+	c.metrics.SubscriptionsActive(subCount)
+	c.logger.Info("subscription created", "handle", sub.handle, "activeSubscriptions", subCount)
+}
+
+// addNotification creates a device notification on the PLC and returns its handle.
+func (c *Client) addNotification(ctx context.Context, opts NotificationOptions) (uint32, error) {
 	req := ads.AddDeviceNotificationRequest{
 		IndexGroup:       opts.IndexGroup,
 		IndexOffset:      opts.IndexOffset,
@@ -31,89 +88,293 @@ func (c *Client) Subscribe(ctx context.Context, opts NotificationOptions) (*Subs
 	respPacket, err := c.sendRequest(ctx, ads.CmdAddDeviceNotification, reqData)
 	if err != nil {
 		c.logger.Error("subscribe failed", "error", err)
-		c.metrics.OperationCompleted("subscribe", time.Since(start), err)
 		ce := ClassifyError(err, "subscribe")
 		c.metrics.ErrorOccurred(ce.Category, "subscribe")
-		return nil, ce
+		return 0, ce
 	}
 
 	var resp ads.AddDeviceNotificationResponse
 	if err := resp.UnmarshalBinary(respPacket.Data); err != nil {
 		c.logger.Error("subscribe unmarshal failed", "error", err)
-		c.metrics.OperationCompleted("subscribe", time.Since(start), err)
 		c.metrics.ErrorOccurred(ErrorCategoryProtocol, "subscribe")
-		return nil, err
+		return 0, err
 	}
 
 	if resp.Result != 0 {
 		adsErr := ads.Error(resp.Result)
 		c.logger.Error("subscribe ADS error", "error", adsErr)
-		c.metrics.OperationCompleted("subscribe", time.Since(start), adsErr)
 		c.metrics.ErrorOccurred(ErrorCategoryADS, "subscribe")
-		return nil, NewADSError("subscribe", adsErr)
+		return 0, NewADSError("subscribe", adsErr)
 	}
 
-	// Create subscription
-	sub := &Subscription{
-		handle:  resp.NotificationHandle,
-		client:  c,
-		notifCh: make(chan Notification, 16),
-		closed:  false,
-		closeMu: sync.Mutex{},
-		opts:    opts,
+	return resp.NotificationHandle, nil
+}
+
+// deleteNotification deletes a device notification on the PLC.
+func (c *Client) deleteNotification(ctx context.Context, handle uint32) error {
+	req := ads.DeleteDeviceNotificationRequest{
+		NotificationHandle: handle,
 	}
 
-	// Register subscription
-	c.subscriptionsMu.Lock()
-	c.subscriptions[sub.handle] = sub
-	subCount := len(c.subscriptions)
-	c.subscriptionsMu.Unlock()
+	reqData, err := req.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("marshal delete notification request: %w", err)
+	}
 
-	c.metrics.SubscriptionsActive(subCount)
-	c.metrics.OperationCompleted("subscribe", time.Since(start), nil)
-	c.logger.Info("subscription created", "handle", sub.handle, "activeSubscriptions", subCount)
+	respPacket, err := c.sendRequest(ctx, ads.CmdDelDeviceNotification, reqData)
+	if err != nil {
+		return fmt.Errorf("delete notification: %w", err)
+	}
 
-	return sub, nil
+	var resp ads.DeleteDeviceNotificationResponse
+	if err := resp.UnmarshalBinary(respPacket.Data); err != nil {
+		return fmt.Errorf("unmarshal delete notification response: %w", err)
+	}
+
+	if resp.Result != 0 {
+		return ads.Error(resp.Result)
+	}
+	return nil
 }
 
 // SubscribeSymbol creates a notification subscription using a symbol name.
 // This is a convenience method that automatically looks up the symbol's index group,
 // offset, and length. The returned Subscription will deliver notifications via its
 // Notifications() channel. Call Close() on the Subscription when done.
+// Notifications carry the symbol name, PLC type and the value decoded like
+// ReadSymbolValue in addition to the raw data.
 func (c *Client) SubscribeSymbol(ctx context.Context, symbolName string, opts SymbolNotificationOptions) (*Subscription, error) {
 	// Ensure symbols are loaded
 	if err := c.ensureSymbolsLoaded(ctx); err != nil {
 		return nil, fmt.Errorf("load symbols: %w", err)
 	}
 
-	// Get the symbol
-	symbol, err := c.symbolTable.Get(symbolName)
+	// Resolve the symbol path, which may address an array element or field
+	target, err := c.resolveSymbolPath(ctx, symbolName)
 	if err != nil {
 		return nil, fmt.Errorf("get symbol %q: %w", symbolName, err)
 	}
+	if target.dynamic {
+		return nil, fmt.Errorf("subscribe %q: paths through pointers or references have no fixed address", symbolName)
+	}
 
 	// Create notification options with symbol information
-	notifOpts := NotificationOptions{
-		IndexGroup:       symbol.IndexGroup,
-		IndexOffset:      symbol.IndexOffset,
-		Length:           symbol.Size,
-		TransmissionMode: opts.TransmissionMode,
-		MaxDelay:         opts.MaxDelay,
-		CycleTime:        opts.CycleTime,
+	notifOpts := opts.notificationOptions(target.indexGroup, target.indexOffset, target.size)
+
+	decoder := &notificationDecoder{
+		symbol:   symbolName,
+		typeName: target.symbol.Type.Name,
+		decode: func(ctx context.Context, data []byte) (interface{}, error) {
+			return c.parseResolvedValue(ctx, data, target)
+		},
+	}
+	return c.subscribe(ctx, notifOpts, decoder)
+}
+
+// SubscribeGroup subscribes to several symbols at once and delivers their
+// samples grouped by PLC timestamp: every GroupNotification holds the values
+// of all members that the PLC sent in the same stamp, i.e. sampled in the same
+// cycle. With TransModeOnChange only the changed members are included.
+// Buffering and overflow options apply to the group channel. Call Close() on
+// the GroupSubscription when done.
+func (c *Client) SubscribeGroup(ctx context.Context, symbolNames []string, opts SymbolNotificationOptions) (*GroupSubscription, error) {
+	if len(symbolNames) == 0 {
+		return nil, NewValidationError("subscribe_group", "at least one symbol is required")
+	}
+	notifOpts := opts.notificationOptions(0, 0, 0)
+	if err := notifOpts.validate(); err != nil {
+		return nil, err
 	}
 
-	return c.Subscribe(ctx, notifOpts)
+	if err := c.ensureSymbolsLoaded(ctx); err != nil {
+		return nil, fmt.Errorf("load symbols: %w", err)
+	}
+
+	// Resolve all symbols first, samples may arrive as soon as the first
+	// member is registered
+	members := make([]groupMember, len(symbolNames))
+	seen := make(map[string]bool, len(symbolNames))
+	for i, name := range symbolNames {
+		if seen[name] {
+			return nil, NewValidationError("subscribe_group", fmt.Sprintf("duplicate symbol %q", name))
+		}
+		seen[name] = true
+
+		target, err := c.resolveSymbolPath(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("get symbol %q: %w", name, err)
+		}
+		if target.dynamic {
+			return nil, fmt.Errorf("subscribe %q: paths through pointers or references have no fixed address", name)
+		}
+
+		members[i] = groupMember{
+			symbol:   name,
+			typeName: target.symbol.Type.Name,
+			opts:     opts.notificationOptions(target.indexGroup, target.indexOffset, target.size),
+			decode: func(ctx context.Context, data []byte) (interface{}, error) {
+				return c.parseResolvedValue(ctx, data, target)
+			},
+		}
+	}
+
+	group := newGroupSubscription(c, members, notifOpts)
+	for i, member := range members {
+		start := time.Now()
+		c.metrics.OperationStarted("subscribe")
+		handle, err := c.addNotification(ctx, member.opts)
+		c.metrics.OperationCompleted("subscribe", time.Since(start), err)
+		if err != nil {
+			group.Close()
+			return nil, fmt.Errorf("subscribe %q: %w", member.symbol, err)
+		}
+
+		// Member samples are routed to the group, the member channel stays unused
+		sub := &Subscription{
+			handle:     handle,
+			client:     c,
+			notifCh:    make(chan Notification),
+			active:     true,
+			opts:       member.opts,
+			group:      group,
+			groupIndex: i,
+		}
+		group.addSubscription(sub)
+		c.registerSubscription(sub)
+	}
+
+	return group, nil
+}
+
+// SubscribeTyped creates a notification subscription for a symbol that delivers
+// values of type T, decoded with the rules of ReadInto. T is validated against
+// the PLC type before subscribing. It is a shorthand for NewTag followed by
+// Tag.Subscribe.
+func SubscribeTyped[T any](ctx context.Context, c *Client, symbolName string, opts SymbolNotificationOptions) (*TagSubscription[T], error) {
+	tag, err := NewTag[T](ctx, c, symbolName)
+	if err != nil {
+		return nil, err
+	}
+	return tag.Subscribe(ctx, opts)
 }
 
 // unregisterSubscription removes a subscription from the registry.
-func (c *Client) unregisterSubscription(handle uint32) {
+func (c *Client) unregisterSubscription(sub *Subscription) {
+	c.subscriptionsMu.Lock()
+	if c.subscriptions[sub.handle] == sub {
+		delete(c.subscriptions, sub.handle)
+	}
+	delete(c.suspended, sub)
+	subCount := len(c.subscriptions)
+	c.subscriptionsMu.Unlock()
+
+	c.metrics.SubscriptionsActive(subCount)
+	c.logger.Debug("subscription unregistered", "handle", sub.handle, "activeSubscriptions", subCount)
+}
+
+// suspendSubscriptions marks all subscriptions as suspended after the connection was lost.
+func (c *Client) suspendSubscriptions(err error) {
+	c.subscriptionsMu.RLock()
+	subs := make([]*Subscription, 0, len(c.subscriptions))
+	for _, sub := range c.subscriptions {
+		if !sub.internal {
+			subs = append(subs, sub)
+		}
+	}
+	c.subscriptionsMu.RUnlock()
+
+	for _, sub := range subs {
+		sub.closeMu.Lock()
+		if !sub.closed && sub.active {
+			sub.active = false
+			sub.emit(SubscriptionEvent{Type: SubscriptionSuspended, OldHandle: sub.handle, Err: err})
+		}
+		sub.closeMu.Unlock()
+	}
+}
+
+// reestablishSubscriptions re-creates all subscriptions on the PLC after reconnection.
+// Subscription objects and their channels stay the same, only the PLC handle changes.
+// Internal subscriptions are closed, their owners restart them.
+func (c *Client) reestablishSubscriptions() {
 	c.subscriptionsMu.Lock()
-	delete(c.subscriptions, handle)
+	subs := make([]*Subscription, 0, len(c.subscriptions)+len(c.suspended))
+	var internal []*Subscription
+	for _, sub := range c.subscriptions {
+		if sub.internal {
+			internal = append(internal, sub)
+		} else {
+			subs = append(subs, sub)
+		}
+	}
+	for sub := range c.suspended {
+		subs = append(subs, sub)
+	}
+	c.subscriptions = make(map[uint32]*Subscription)
+	c.suspended = make(map[*Subscription]struct{})
+	c.subscriptionsMu.Unlock()
+
+	for _, sub := range internal {
+		// Just close the channel, the old handle is gone with the connection
+		sub.closeMu.Lock()
+		if !sub.closed {
+			sub.closed = true
+			sub.closeChannel()
+		}
+		sub.closeMu.Unlock()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, sub := range subs {
+		c.restoreSubscription(ctx, sub)
+	}
+}
+
+// restoreSubscription re-creates a subscription on the PLC with a new handle.
+// If that fails, the subscription stays suspended and is retried after the next reconnect.
+func (c *Client) restoreSubscription(ctx context.Context, sub *Subscription) {
+	handle, err := c.addNotification(ctx, sub.opts)
+
+	sub.closeMu.Lock()
+	if sub.closed {
+		// Closed while re-establishing
+		sub.closeMu.Unlock()
+		if err == nil {
+			c.deleteNotification(ctx, handle)
+		}
+		return
+	}
+	defer sub.closeMu.Unlock()
+
+	oldHandle := sub.handle
+	if err != nil {
+		sub.active = false
+		sub.emit(SubscriptionEvent{Type: SubscriptionRestoreFailed, OldHandle: oldHandle, Err: err})
+
+		c.subscriptionsMu.Lock()
+		c.suspended[sub] = struct{}{}
+		c.subscriptionsMu.Unlock()
+
+		c.logger.Warn("failed to re-establish subscription", "handle", oldHandle, "error", err)
+		// Application should handle subscription failures via state callback or Events()
+		c.notifyStateChange(transport.StateConnected, transport.StateConnected,
+			fmt.Errorf("failed to re-establish subscription: %w", err))
+		return
+	}
+
+	sub.handle = handle
+	sub.active = true
+	sub.emit(SubscriptionEvent{Type: SubscriptionRestored, Handle: handle, OldHandle: oldHandle})
+
+	c.subscriptionsMu.Lock()
+	c.subscriptions[handle] = sub
 	subCount := len(c.subscriptions)
 	c.subscriptionsMu.Unlock()
 
 	c.metrics.SubscriptionsActive(subCount)
-	c.logger.Debug("subscription unregistered", "handle", handle, "activeSubscriptions", subCount)
+	c.logger.Info("subscription re-established", "oldHandle", oldHandle, "handle", handle)
 }
 
 // handleNotification processes incoming notification packets and routes them to subscriptions.
@@ -133,19 +394,35 @@ func (c *Client) handleNotification(packet *ams.Packet) {
 		unixNano := int64(stamp.Timestamp-fileTimeEpoch) * 100
 		timestamp := time.Unix(0, unixNano)
 
+		// Samples of group members are collected and delivered per stamp, so
+		// values sampled in the same PLC cycle arrive together
+		var groups map[*GroupSubscription][]groupSample
+
 		for _, sample := range stamp.Samples {
 			c.subscriptionsMu.RLock()
 			sub, exists := c.subscriptions[sample.NotificationHandle]
 			c.subscriptionsMu.RUnlock()
 
-			if exists {
-				c.metrics.NotificationReceived()
-				c.logger.Debug("notification received", "handle", sample.NotificationHandle, "bytes", len(sample.Data))
-				sub.notify(sample.Data, timestamp)
-			} else {
+			if !exists {
 				c.logger.Warn("notification for unknown handle", "handle", sample.NotificationHandle)
 				c.metrics.NotificationDropped()
+				continue
 			}
+
+			c.metrics.NotificationReceived()
+			c.logger.Debug("notification received", "handle", sample.NotificationHandle, "bytes", len(sample.Data))
+			if sub.group != nil {
+				if groups == nil {
+					groups = make(map[*GroupSubscription][]groupSample)
+				}
+				groups[sub.group] = append(groups[sub.group], groupSample{index: sub.groupIndex, data: sample.Data})
+				continue
+			}
+			sub.notify(sample.Data, timestamp)
+		}
+
+		for group, samples := range groups {
+			group.notify(samples, timestamp)
 		}
 	}
 }
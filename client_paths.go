@@ -0,0 +1,449 @@
+package goadstc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+// pathSegment is one dot-separated part of a symbol path with the array
+// indices and dereferences following its name.
+type pathSegment struct {
+	name string
+	ops  []pathOp
+}
+
+// pathOp is either a set of array indices or a pointer dereference ("^").
+type pathOp struct {
+	indices []int
+	deref   bool
+}
+
+// resolvedSymbol holds the address of a symbol path resolved against the symbol table.
+type resolvedSymbol struct {
+	name        string
+	symbol      *symbols.Symbol // table symbol, or a symbol describing the addressed element or field
+	indexGroup  uint32
+	indexOffset uint32
+	size        uint32
+	bitField    *symbols.FieldInfo // set if the path ends at a BIT struct member
+	dynamic     bool               // path goes through a pointer or reference, access by handle
+}
+
+// parseSymbolPath splits a symbol path like "GVL.grid[2,-3].x" into segments.
+// "a[1][2]" and "a[1,2]" both yield the indices [1 2]; it is up to the type
+// of the symbol whether these address one two-dimensional array or nested arrays.
+// "^" dereferences a pointer, as in "MAIN.pAxis^.Status".
+func parseSymbolPath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	var current pathSegment
+	var name strings.Builder
+
+	for i := 0; i < len(path); i++ {
+		switch ch := path[i]; ch {
+		case '.':
+			if name.Len() == 0 {
+				return nil, fmt.Errorf("invalid symbol path %q: empty name", path)
+			}
+			current.name = name.String()
+			segments = append(segments, current)
+			current = pathSegment{}
+			name.Reset()
+
+		case '[':
+			if name.Len() == 0 {
+				return nil, fmt.Errorf("invalid symbol path %q: index without name", path)
+			}
+			end := strings.IndexByte(path[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid array notation in %q: missing ']'", path)
+			}
+			// Consecutive brackets ("[1][2]") form one set of indices
+			if n := len(current.ops); n == 0 || current.ops[n-1].deref {
+				current.ops = append(current.ops, pathOp{})
+			}
+			op := &current.ops[len(current.ops)-1]
+			for _, part := range strings.Split(path[i+1:i+end], ",") {
+				idx, err := strconv.Atoi(strings.TrimSpace(part))
+				if err != nil {
+					return nil, fmt.Errorf("invalid array index %q in %q", strings.TrimSpace(part), path)
+				}
+				op.indices = append(op.indices, idx)
+			}
+			i += end
+
+		case '^':
+			if name.Len() == 0 {
+				return nil, fmt.Errorf("invalid symbol path %q: dereference without name", path)
+			}
+			current.ops = append(current.ops, pathOp{deref: true})
+
+		case ']':
+			return nil, fmt.Errorf("invalid array notation in %q: unexpected ']'", path)
+
+		default:
+			if len(current.ops) > 0 {
+				return nil, fmt.Errorf("invalid symbol path %q: unexpected %q after %q", path, ch, path[i-1])
+			}
+			name.WriteByte(ch)
+		}
+	}
+
+	if name.Len() == 0 {
+		return nil, fmt.Errorf("invalid symbol path %q: empty name", path)
+	}
+	current.name = name.String()
+	return append(segments, current), nil
+}
+
+// resolveSymbolPath resolves a symbol path to its address and type.
+// The path starts with a symbol from the symbol table and may continue with
+// array indices ("arr[3]", "grid[2,-3]", "a[1][2]") and struct fields
+// ("st.inner.value"), in any combination. Indices are checked against the
+// array bounds, so out-of-range access fails before any request is sent.
+// Paths through a pointer ("pAxis^.Status") or reference have no static
+// address; they are resolved by the PLC, see resolveDynamicPath.
+func (c *Client) resolveSymbolPath(ctx context.Context, symbolName string) (*resolvedSymbol, error) {
+	if symbol, err := c.GetSymbol(symbolName); err == nil && !isReferenceType(symbol.Type) {
+		return &resolvedSymbol{
+			name:        symbolName,
+			symbol:      symbol,
+			indexGroup:  symbol.IndexGroup,
+			indexOffset: symbol.IndexOffset,
+			size:        symbol.Size,
+		}, nil
+	}
+
+	segments, err := parseSymbolPath(symbolName)
+	if err != nil {
+		return nil, err
+	}
+
+	symbol, rest, err := c.findBaseSymbol(segments)
+	if err != nil {
+		return nil, err
+	}
+
+	target := &resolvedSymbol{
+		name:        symbolName,
+		indexGroup:  symbol.IndexGroup,
+		indexOffset: symbol.IndexOffset,
+		size:        symbol.Size,
+	}
+	typeInfo := symbol.Type
+	path := symbol.Name
+
+	for i, seg := range rest {
+		if i > 0 {
+			if target.bitField != nil {
+				return nil, fmt.Errorf("%q is a bit field and has no members", path)
+			}
+			field, err := c.lookupField(ctx, typeInfo, seg.name)
+			if err != nil {
+				return nil, err
+			}
+			path += "." + field.Name
+			target.indexOffset += field.Offset
+			target.size = field.Type.Size
+			typeInfo = field.Type
+			if field.BitSize > 0 {
+				target.bitField = &symbols.FieldInfo{
+					Name:      field.Name,
+					Type:      field.Type,
+					BitOffset: field.BitOffset,
+					BitSize:   field.BitSize,
+				}
+			}
+		}
+
+		for _, op := range seg.ops {
+			if isReferenceType(typeInfo) {
+				return c.resolveDynamicPath(ctx, symbolName)
+			}
+			if op.deref {
+				if !isPointerType(typeInfo) {
+					return nil, fmt.Errorf("%q is not a pointer and cannot be dereferenced", path)
+				}
+				return c.resolveDynamicPath(ctx, symbolName)
+			}
+
+			offset, elementType, err := c.indexArray(ctx, typeInfo, target.size, op.indices, path)
+			if err != nil {
+				return nil, err
+			}
+			path += formatIndices(op.indices)
+			target.indexOffset += offset
+			target.size = elementType.Size
+			typeInfo = elementType
+		}
+
+		// References are followed implicitly, for members and for the value itself
+		if isReferenceType(typeInfo) {
+			return c.resolveDynamicPath(ctx, symbolName)
+		}
+	}
+
+	target.symbol = &symbols.Symbol{
+		Name:        symbolName,
+		Type:        typeInfo,
+		IndexGroup:  target.indexGroup,
+		IndexOffset: target.indexOffset,
+		Size:        target.size,
+		Flags:       symbol.Flags,
+	}
+	return target, nil
+}
+
+// resolveDynamicPath resolves a path through a pointer or reference. Its
+// address can change at runtime, so the PLC resolves the path: type and size
+// come from the symbol info by name (0xF009) and values are accessed by handle.
+// The symbol info is cached until the symbol table is reloaded.
+func (c *Client) resolveDynamicPath(ctx context.Context, symbolName string) (*resolvedSymbol, error) {
+	c.symbolTableMu.RLock()
+	symbol, ok := c.dynamicSymbols[symbolName]
+	c.symbolTableMu.RUnlock()
+
+	if !ok {
+		var err error
+		symbol, err = c.fetchSymbolInfo(ctx, symbolName)
+		if err != nil {
+			return nil, err
+		}
+
+		c.symbolTableMu.Lock()
+		if c.dynamicSymbols == nil {
+			c.dynamicSymbols = make(map[string]*symbols.Symbol)
+		}
+		c.dynamicSymbols[symbolName] = symbol
+		c.symbolTableMu.Unlock()
+	}
+
+	return &resolvedSymbol{
+		name:        symbolName,
+		symbol:      symbol,
+		indexGroup:  symbol.IndexGroup,
+		indexOffset: symbol.IndexOffset,
+		size:        symbol.Size,
+		dynamic:     true,
+	}, nil
+}
+
+// fetchSymbolInfo retrieves the symbol entry of a path from the PLC using
+// ADSIGRP_SYM_INFOBYNAMEEX (0xF009). A reference is described by its target type.
+func (c *Client) fetchSymbolInfo(ctx context.Context, symbolName string) (*symbols.Symbol, error) {
+	req := ads.SymbolInfoByNameRequest{SymbolName: symbolName}
+	nameData, _ := req.MarshalBinary()
+
+	data, err := c.ReadWrite(ctx, ads.IndexGroupSymbolInfoByNameEx, 0, 0xFFFF, nameData)
+	if err != nil {
+		return nil, fmt.Errorf("get symbol info for %q: %w", symbolName, err)
+	}
+
+	entries, err := symbols.ParseSymbolTable(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse symbol info for %q: %w", symbolName, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("get symbol info for %q: empty response", symbolName)
+	}
+
+	symbol := entries[0]
+	symbol.Name = symbolName
+	if isReferenceType(symbol.Type) {
+		symbol.Type = symbols.ParseTypeInfo(pointeeTypeName(symbol.Type.Name), symbol.Type.BaseType, symbol.Size)
+	}
+	return &symbol, nil
+}
+
+// isPointerType reports whether a type is a POINTER TO type.
+func isPointerType(typeInfo symbols.TypeInfo) bool {
+	return strings.HasPrefix(strings.ToUpper(typeInfo.Name), "POINTER TO ")
+}
+
+// isReferenceType reports whether a type is a REFERENCE TO type.
+func isReferenceType(typeInfo symbols.TypeInfo) bool {
+	return strings.HasPrefix(strings.ToUpper(typeInfo.Name), "REFERENCE TO ")
+}
+
+// pointeeTypeName returns the target type of a pointer or reference type name
+// ("REFERENCE TO FB_Axis" -> "FB_Axis").
+func pointeeTypeName(typeName string) string {
+	_, pointee, found := strings.Cut(typeName, " TO ")
+	if !found {
+		return typeName
+	}
+	return strings.TrimSpace(pointee)
+}
+
+// findBaseSymbol finds the symbol table entry the path starts with, trying the
+// longest dotted prefix first ("MAIN.st.a" before "MAIN.st"). It returns the
+// symbol and the remaining segments; the first of them is the symbol's own
+// segment, carrying its array indices and dereferences.
+func (c *Client) findBaseSymbol(segments []pathSegment) (*symbols.Symbol, []pathSegment, error) {
+	for n := len(segments); n > 0; n-- {
+		// Only the last segment of the symbol name may be indexed or dereferenced
+		indexed := false
+		for _, seg := range segments[:n-1] {
+			if len(seg.ops) > 0 {
+				indexed = true
+				break
+			}
+		}
+		if indexed {
+			continue
+		}
+
+		name := strings.Join(segmentNames(segments[:n]), ".")
+		if symbol, err := c.GetSymbol(name); err == nil {
+			return symbol, segments[n-1:], nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("symbol %q not found", segments[0].name)
+}
+
+// segmentNames returns the names of path segments.
+func segmentNames(segments []pathSegment) []string {
+	names := make([]string, len(segments))
+	for i, seg := range segments {
+		names[i] = seg.name
+	}
+	return names
+}
+
+// lookupField finds a struct field by name. PLC identifiers are case-insensitive,
+// so an exact match is preferred but not required.
+func (c *Client) lookupField(ctx context.Context, typeInfo symbols.TypeInfo, fieldName string) (symbols.FieldInfo, error) {
+	if len(typeInfo.Fields) == 0 && !typeInfo.IsArray && !isSimpleTypeName(typeInfo.Name) {
+		if fetched, err := c.getOrFetchTypeInfo(ctx, typeInfo.Name); err == nil {
+			typeInfo = fetched
+		}
+	}
+
+	if len(typeInfo.Fields) == 0 {
+		return symbols.FieldInfo{}, fmt.Errorf("type %q has no field %q", typeInfo.Name, fieldName)
+	}
+
+	for _, field := range typeInfo.Fields {
+		if field.Name == fieldName {
+			return field, nil
+		}
+	}
+	for _, field := range typeInfo.Fields {
+		if strings.EqualFold(field.Name, fieldName) {
+			return field, nil
+		}
+	}
+	return symbols.FieldInfo{}, fmt.Errorf("field %q not found in type %q", fieldName, typeInfo.Name)
+}
+
+// indexArray applies array indices to an array of the given type and size.
+// Indices are consumed per array level, so "[1,2]" addresses a two-dimensional
+// array and "[1][2]" an array of arrays as well. It returns the byte offset of
+// the addressed element and its type.
+func (c *Client) indexArray(ctx context.Context, typeInfo symbols.TypeInfo, size uint32, indices []int, path string) (uint32, symbols.TypeInfo, error) {
+	var offset uint32
+
+	for len(indices) > 0 {
+		dims := arrayDimensions(typeInfo)
+		if len(dims) == 0 {
+			return 0, symbols.TypeInfo{}, fmt.Errorf("%q is not an array", path)
+		}
+		if len(indices) < len(dims) {
+			return 0, symbols.TypeInfo{}, fmt.Errorf("%q has %d dimensions, got %d indices", path, len(dims), len(indices))
+		}
+
+		linear, err := linearIndex(dims, indices[:len(dims)], path)
+		if err != nil {
+			return 0, symbols.TypeInfo{}, err
+		}
+
+		total := uint32(1)
+		for _, dim := range dims {
+			total *= dim.Elements
+		}
+		if total == 0 || size < total {
+			return 0, symbols.TypeInfo{}, fmt.Errorf("%q has invalid size %d for %d elements", path, size, total)
+		}
+		elementSize := size / total
+
+		offset += linear * elementSize
+		typeInfo = c.arrayElementType(ctx, typeInfo, elementSize)
+		size = elementSize
+		path += formatIndices(indices[:len(dims)])
+		indices = indices[len(dims):]
+	}
+
+	return offset, typeInfo, nil
+}
+
+// linearIndex converts indices into the row-major position of the element,
+// validating each index against the bounds of its dimension.
+func linearIndex(dims []symbols.ArrayDimension, indices []int, path string) (uint32, error) {
+	var linear uint32
+	for i, dim := range dims {
+		idx := indices[i]
+		if idx < int(dim.LowerBound) || idx > int(dim.UpperBound()) {
+			return 0, fmt.Errorf("invalid array index %d for %q: out of bounds [%d..%d] in dimension %d",
+				idx, path, dim.LowerBound, dim.UpperBound(), i+1)
+		}
+		linear = linear*dim.Elements + uint32(idx-int(dim.LowerBound))
+	}
+	return linear, nil
+}
+
+// arrayDimensions returns the bounds of an array type. Types registered
+// without bounds are treated as zero-based.
+func arrayDimensions(typeInfo symbols.TypeInfo) []symbols.ArrayDimension {
+	if len(typeInfo.ArrayInfo) > 0 {
+		return typeInfo.ArrayInfo
+	}
+	if len(typeInfo.ArrayDims) > 0 {
+		dims := make([]symbols.ArrayDimension, len(typeInfo.ArrayDims))
+		for i, n := range typeInfo.ArrayDims {
+			dims[i] = symbols.ArrayDimension{Elements: n}
+		}
+		return dims
+	}
+	if typeInfo.IsArray || strings.Contains(typeInfo.Name, "ARRAY") {
+		return symbols.ParseTypeInfo(typeInfo.Name, typeInfo.BaseType, typeInfo.Size).ArrayInfo
+	}
+	return nil
+}
+
+// arrayElementType returns the type info of an array's elements.
+// Simple and nested array element types are derived from the names; other
+// types come from the type registry or the PLC.
+func (c *Client) arrayElementType(ctx context.Context, typeInfo symbols.TypeInfo, elementSize uint32) symbols.TypeInfo {
+	elementName := typeInfo.ElementType
+	if elementName == "" {
+		elementName, _ = extractArrayElementType(typeInfo.Name)
+	}
+
+	if elementName == "" || isSimpleTypeName(elementName) || isStringTypeName(elementName) || strings.HasPrefix(elementName, "ARRAY") {
+		return symbols.ParseTypeInfo(elementName, typeInfo.BaseType, elementSize)
+	}
+
+	elementType, err := c.getOrFetchTypeInfo(ctx, elementName)
+	if err != nil {
+		c.logger.Debug("element type info not available", "type", elementName, "error", err)
+		return symbols.TypeInfo{Name: elementName, BaseType: typeInfo.BaseType, Size: elementSize, IsStruct: true}
+	}
+	if elementType.Size == 0 {
+		elementType.Size = elementSize
+	}
+	return elementType
+}
+
+// formatIndices formats indices in PLC notation, e.g. "[2,-3]".
+func formatIndices(indices []int) string {
+	parts := make([]string, len(indices))
+	for i, idx := range indices {
+		parts[i] = strconv.Itoa(idx)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
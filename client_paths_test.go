@@ -0,0 +1,183 @@
+package goadstc
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mrpasztoradam/goadstc/adssim"
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+// Axes at fixed offsets in PLC memory, the targets of pointers and references.
+const (
+	axisOffset  = 0x100
+	axis2Offset = 0x200
+)
+
+// pointerConfig holds a pointer, references and a struct with a reference
+// member, all pointing to MAIN.axis.
+var pointerConfig = adssim.Config{
+	DataTypes: []symbols.DataTypeEntry{
+		{Name: "ST_Status", Size: 16, DataType: symbols.DataTypeBigType, SubItems: []symbols.DataTypeEntry{
+			{Name: "Position", Type: "LREAL", DataType: symbols.DataTypeReal64, Size: 8, Offset: 0},
+			{Name: "Velocity", Type: "LREAL", DataType: symbols.DataTypeReal64, Size: 8, Offset: 8},
+		}},
+		{Name: "ST_Axis", Size: 24, DataType: symbols.DataTypeBigType, SubItems: []symbols.DataTypeEntry{
+			{Name: "Id", Type: "DINT", DataType: symbols.DataTypeInt32, Size: 4, Offset: 0},
+			{Name: "Status", Type: "ST_Status", DataType: symbols.DataTypeBigType, Size: 16, Offset: 8},
+		}},
+		{Name: "ST_Drive", Size: 8, DataType: symbols.DataTypeBigType, SubItems: []symbols.DataTypeEntry{
+			{Name: "axis", Type: "REFERENCE TO ST_Axis", DataType: symbols.DataTypeUInt64, Size: 8, Offset: 0},
+		}},
+	},
+	Symbols: []adssim.Symbol{
+		{Name: "MAIN.axis", Type: "ST_Axis", IndexGroup: ads.IndexGroupPLCMemory, IndexOffset: axisOffset, Value: axisValue(7, 1.5)},
+		{Name: "MAIN.axis2", Type: "ST_Axis", IndexGroup: ads.IndexGroupPLCMemory, IndexOffset: axis2Offset, Value: axisValue(8, -3)},
+		{Name: "MAIN.pAxis", Type: "POINTER TO ST_Axis", Value: address(axisOffset)},
+		{Name: "MAIN.refAxis", Type: "REFERENCE TO ST_Axis", Value: address(axisOffset)},
+		{Name: "MAIN.refId", Type: "REFERENCE TO DINT", Value: address(axisOffset)},
+		{Name: "MAIN.drive", Type: "ST_Drive", Value: address(axisOffset)},
+	},
+}
+
+// axisValue encodes an ST_Axis.
+func axisValue(id int32, position float64) []byte {
+	data := make([]byte, 24)
+	binary.LittleEndian.PutUint32(data[0:], uint32(id))
+	binary.LittleEndian.PutUint64(data[8:], math.Float64bits(position))
+	return data
+}
+
+// address encodes the value of a pointer or reference to an offset in PLC memory.
+func address(offset uint64) []byte {
+	return binary.LittleEndian.AppendUint64(nil, offset)
+}
+
+func TestParseSymbolPath(t *testing.T) {
+	segments, err := parseSymbolPath("GVL.grid[2, -3].x")
+	if err != nil {
+		t.Fatalf("parseSymbolPath() error = %v", err)
+	}
+	want := []pathSegment{{name: "GVL"}, {name: "grid", ops: []pathOp{{indices: []int{2, -3}}}}, {name: "x"}}
+	if !reflect.DeepEqual(segments, want) {
+		t.Errorf("parseSymbolPath() = %+v, want %+v", segments, want)
+	}
+
+	segments, err = parseSymbolPath("MAIN.a[1][2]")
+	if err != nil {
+		t.Fatalf("parseSymbolPath() error = %v", err)
+	}
+	if got := segments[1].ops; len(got) != 1 || !reflect.DeepEqual(got[0].indices, []int{1, 2}) {
+		t.Errorf("ops = %+v, want one op with indices [1 2]", got)
+	}
+
+	segments, err = parseSymbolPath("MAIN.pAxes[2]^.Status")
+	if err != nil {
+		t.Fatalf("parseSymbolPath() error = %v", err)
+	}
+	want = []pathSegment{{name: "MAIN"}, {name: "pAxes", ops: []pathOp{{indices: []int{2}}, {deref: true}}}, {name: "Status"}}
+	if !reflect.DeepEqual(segments, want) {
+		t.Errorf("parseSymbolPath() = %+v, want %+v", segments, want)
+	}
+
+	for _, path := range []string{"MAIN.a[1", "MAIN.a[x]", "MAIN..a", "MAIN.a[1]b", "MAIN.p^x", "MAIN.^"} {
+		if _, err := parseSymbolPath(path); err == nil {
+			t.Errorf("parseSymbolPath(%q) expected error", path)
+		}
+	}
+}
+
+func TestIndexArray(t *testing.T) {
+	c := &Client{}
+	ctx := context.Background()
+
+	grid := symbols.ParseTypeInfo("ARRAY [1..10, -5..5] OF REAL", symbols.DataTypeReal32, 440)
+	offset, elem, err := c.indexArray(ctx, grid, grid.Size, []int{2, -3}, "GVL.grid")
+	if err != nil {
+		t.Fatalf("indexArray() error = %v", err)
+	}
+	// Row 2 is the second row of 11 elements, column -3 the third
+	if offset != (11+2)*4 || elem.Size != 4 || elem.Name != "REAL" {
+		t.Errorf("indexArray() = offset %d, element %+v", offset, elem)
+	}
+
+	nested := symbols.ParseTypeInfo("ARRAY [0..1] OF ARRAY [0..2] OF INT", symbols.DataTypeInt16, 12)
+	offset, elem, err = c.indexArray(ctx, nested, nested.Size, []int{1, 2}, "MAIN.a")
+	if err != nil {
+		t.Fatalf("indexArray() nested error = %v", err)
+	}
+	if offset != (3+2)*2 || elem.Size != 2 {
+		t.Errorf("indexArray() nested = offset %d, element %+v", offset, elem)
+	}
+
+	_, _, err = c.indexArray(ctx, grid, grid.Size, []int{11, 0}, "GVL.grid")
+	if err == nil || !strings.Contains(err.Error(), "out of bounds [1..10]") {
+		t.Errorf("indexArray() out of bounds error = %v", err)
+	}
+	if _, _, err = c.indexArray(ctx, grid, grid.Size, []int{2}, "GVL.grid"); err == nil {
+		t.Error("indexArray() expected error for missing index")
+	}
+}
+
+func TestDynamicPaths(t *testing.T) {
+	server, client := startSimulator(t, pointerConfig)
+	ctx := context.Background()
+
+	// Count the symbol info requests without changing them
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultDelay, Command: ads.CmdReadWrite, IndexGroup: ads.IndexGroupSymbolInfoByNameEx})
+
+	reads := []struct {
+		path string
+		want interface{}
+	}{
+		{"MAIN.pAxis^.Status.Position", 1.5},     // dereferenced pointer
+		{"MAIN.refAxis.Id", int32(7)},            // reference followed implicitly
+		{"MAIN.refId", int32(7)},                 // reference as the value itself
+		{"MAIN.drive.axis.Status.Position", 1.5}, // reference member
+	}
+	for _, tt := range reads {
+		for i := 0; i < 2; i++ {
+			value, err := client.ReadSymbolValue(ctx, tt.path)
+			if err != nil || value != tt.want {
+				t.Errorf("ReadSymbolValue(%s) = %v, %v; want %v", tt.path, value, err, tt.want)
+			}
+		}
+	}
+	// The symbol info of each path is fetched once
+	if n := server.FaultsTriggered(); n != len(reads) {
+		t.Errorf("%d symbol info requests for %d paths, want %d", n, len(reads), len(reads))
+	}
+
+	if err := client.WriteSymbolValue(ctx, "MAIN.pAxis^.Status.Position", 2.5); err != nil {
+		t.Fatalf("WriteSymbolValue(pointer) error = %v", err)
+	}
+	if data, _ := server.ReadSymbol("MAIN.axis.Status.Position"); math.Float64frombits(binary.LittleEndian.Uint64(data)) != 2.5 {
+		t.Errorf("MAIN.axis.Status.Position = % x after write through pointer, want 2.5", data)
+	}
+	if err := client.WriteSymbolValue(ctx, "MAIN.refId", int32(9)); err != nil {
+		t.Fatalf("WriteSymbolValue(reference) error = %v", err)
+	}
+	if data, _ := server.ReadSymbol("MAIN.axis.Id"); binary.LittleEndian.Uint32(data) != 9 {
+		t.Errorf("MAIN.axis.Id = % x after write through reference, want 9", data)
+	}
+
+	// Values are accessed by handle, so a moved pointer is followed
+	if err := server.WriteSymbol("MAIN.pAxis", address(axis2Offset)); err != nil {
+		t.Fatalf("WriteSymbol() error = %v", err)
+	}
+	if value, err := client.ReadSymbolValue(ctx, "MAIN.pAxis^.Status.Position"); err != nil || value != -3.0 {
+		t.Errorf("ReadSymbolValue() after moving the pointer = %v, %v; want -3", value, err)
+	}
+	if n := server.Handles(); n != 0 {
+		t.Errorf("%d handles left on the PLC, want 0", n)
+	}
+
+	if _, err := client.ReadSymbolValue(ctx, "MAIN.axis^.Id"); err == nil {
+		t.Error("ReadSymbolValue() dereferencing a struct succeeded")
+	}
+}
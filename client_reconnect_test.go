@@ -0,0 +1,205 @@
+package goadstc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/adssim"
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+)
+
+// counterConfig is a PLC with a single DINT symbol MAIN.counter holding 1.
+var counterConfig = adssim.Config{
+	Symbols: []adssim.Symbol{{Name: "MAIN.counter", Type: "DINT", Value: []byte{1, 0, 0, 0}}},
+}
+
+// waitReconnected waits until the client has reconnected and answers requests.
+func waitReconnected(t *testing.T, client *Client) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if client.currentConn() != nil && client.ReconnectAttempts() == 0 {
+			if _, err := client.ReadState(context.Background()); err == nil {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("client did not reconnect")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestReconnectAfterReset(t *testing.T) {
+	server, client := startSimulator(t, counterConfig, WithTimeout(300*time.Millisecond), WithAutoReconnect(true))
+	ctx := context.Background()
+
+	sub, err := client.Subscribe(ctx, NotificationOptions{
+		IndexGroup:       0x4020,
+		Length:           4,
+		TransmissionMode: ads.TransModeOnChange,
+	})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	next := func() Notification {
+		t.Helper()
+		select {
+		case notif := <-sub.Notifications():
+			return notif
+		case <-time.After(5 * time.Second):
+			t.Fatal("no notification received")
+			return Notification{}
+		}
+	}
+	next() // Initial sample
+
+	// The request fails on the dead connection until the retries run out,
+	// then the client reconnects in the background
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultReset, Count: 1})
+	if _, err := client.ReadState(ctx); err == nil {
+		t.Fatal("ReadState() on reset connection succeeded")
+	}
+	waitReconnected(t, client)
+
+	// The subscription was added again on the new connection
+	for server.Notifications() != 1 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	next() // Initial sample of the new notification
+	if err := server.WriteSymbol("MAIN.counter", []byte{2, 0, 0, 0}); err != nil {
+		t.Fatalf("WriteSymbol() error = %v", err)
+	}
+	if data := next().Data; len(data) != 4 || data[0] != 2 {
+		t.Errorf("sample after reconnect = %v, want 2", data)
+	}
+}
+
+func TestSubscriptionRestoredAfterReset(t *testing.T) {
+	server, client := startSimulator(t, counterConfig, WithTimeout(300*time.Millisecond), WithAutoReconnect(true))
+	ctx := context.Background()
+
+	sub, err := client.Subscribe(ctx, NotificationOptions{
+		IndexGroup:       0x4020,
+		Length:           4,
+		TransmissionMode: ads.TransModeOnChange,
+	})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	notifications := sub.Notifications()
+	oldHandle := sub.Handle()
+	nextEvent := func() SubscriptionEvent {
+		t.Helper()
+		select {
+		case event := <-sub.Events():
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no subscription event received")
+			return SubscriptionEvent{}
+		}
+	}
+	<-notifications // Initial sample
+
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultReset, Count: 1})
+	if _, err := client.ReadState(ctx); err == nil {
+		t.Fatal("ReadState() on reset connection succeeded")
+	}
+
+	if event := nextEvent(); event.Type != SubscriptionSuspended || event.OldHandle != oldHandle || event.Err == nil {
+		t.Fatalf("event = %+v, want suspended for handle %d", event, oldHandle)
+	}
+	event := nextEvent()
+	if event.Type != SubscriptionRestored || event.OldHandle != oldHandle || event.Handle != sub.Handle() {
+		t.Fatalf("event = %+v, want restored from handle %d to %d", event, oldHandle, sub.Handle())
+	}
+	if !sub.Active() {
+		t.Error("Active() = false after restore")
+	}
+
+	// Samples of the new notification arrive on the original channel
+	if sub.Notifications() != notifications {
+		t.Fatal("notification channel replaced by restore")
+	}
+	if err := server.WriteSymbol("MAIN.counter", []byte{7, 0, 0, 0}); err != nil {
+		t.Fatalf("WriteSymbol() error = %v", err)
+	}
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case notif := <-notifications:
+			if len(notif.Data) == 4 && notif.Data[0] == 7 {
+				return
+			}
+		case <-deadline:
+			t.Fatal("no sample after restore")
+		}
+	}
+}
+
+func TestRetryDroppedResponse(t *testing.T) {
+	server, client := startSimulator(t, counterConfig, WithTimeout(300*time.Millisecond), WithAutoReconnect(true))
+
+	// A single lost response is covered by the retries
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultDrop, Command: ads.CmdReadState, Count: 1})
+	if _, err := client.ReadState(context.Background()); err != nil {
+		t.Fatalf("ReadState() error = %v", err)
+	}
+	if n := server.FaultsTriggered(); n != 1 {
+		t.Errorf("faults triggered = %d, want 1", n)
+	}
+}
+
+func TestReconnectAfterCorruptHeader(t *testing.T) {
+	for _, kind := range []adssim.FaultKind{adssim.FaultCorrupt, adssim.FaultTruncate} {
+		t.Run(kind.String(), func(t *testing.T) {
+			server, client := startSimulator(t, counterConfig, WithTimeout(300*time.Millisecond), WithAutoReconnect(true))
+
+			server.InjectFault(adssim.Fault{Kind: kind, Count: 1})
+			if _, err := client.ReadState(context.Background()); err == nil {
+				t.Fatal("ReadState() with broken response succeeded")
+			}
+			waitReconnected(t, client)
+		})
+	}
+}
+
+func TestADSErrorNotRetried(t *testing.T) {
+	server, client := startSimulator(t, counterConfig, WithTimeout(300*time.Millisecond), WithAutoReconnect(true))
+	ctx := context.Background()
+
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultError, IndexGroup: 0x4020, Error: ads.ErrDeviceServiceNotSupported})
+	if _, err := client.Read(ctx, 0x4020, 0, 4); !errors.Is(err, ads.ErrDeviceServiceNotSupported) {
+		t.Errorf("Read(0x4020) error = %v, want service not supported", err)
+	}
+	if n := server.FaultsTriggered(); n != 1 {
+		t.Errorf("faults triggered = %d, want 1 (no retries)", n)
+	}
+
+	// Other index groups are not affected
+	if _, err := client.ReadSymbolVersion(ctx); err != nil {
+		t.Errorf("ReadSymbolVersion() error = %v", err)
+	}
+	if client.ReconnectAttempts() != 0 {
+		t.Error("ADS error triggered a reconnection")
+	}
+}
+
+func TestDelayedResponseDeadline(t *testing.T) {
+	server, client := startSimulator(t, counterConfig, WithTimeout(300*time.Millisecond), WithAutoReconnect(true))
+
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultDelay, Delay: 200 * time.Millisecond, Count: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.ReadState(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ReadState() error = %v, want deadline exceeded", err)
+	}
+
+	// The late response is discarded and the connection stays usable
+	time.Sleep(250 * time.Millisecond)
+	if _, err := client.ReadState(context.Background()); err != nil {
+		t.Errorf("ReadState() after late response error = %v", err)
+	}
+}
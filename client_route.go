@@ -0,0 +1,102 @@
+package goadstc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/mrpasztoradam/goadstc/discovery"
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+// RouteOptions configures the route a client registers on the target, see WithRoute.
+type RouteOptions struct {
+	Username string // User of the target system
+	Password string
+
+	// Name of the route on the target (default: local hostname)
+	Name string
+	// Address the target uses to reach this system (default: the local IP
+	// address used towards the target)
+	Host string
+	// UDP address of the target router (default: target host, port 48899)
+	RouterAddress string
+}
+
+// WithRoute registers a route for this client on the target before the first
+// connection (optional), so no static route has to be added on the PLC by hand.
+// The route leads to the source NetID; if none is set, it is derived from the
+// local IP address as "a.b.c.d.1.1" and used for the connection as well.
+func WithRoute(opts RouteOptions) Option {
+	return func(c *clientConfig) error {
+		if opts.Username == "" {
+			return fmt.Errorf("goadstc: route username cannot be empty")
+		}
+		c.route = &opts
+		return nil
+	}
+}
+
+// registerRoute adds the route configured with WithRoute on the target.
+func registerRoute(cfg *clientConfig) error {
+	opts := *cfg.route
+
+	if opts.RouterAddress == "" {
+		host, _, err := net.SplitHostPort(cfg.address)
+		if err != nil {
+			return fmt.Errorf("invalid target address %q: %w", cfg.address, err)
+		}
+		opts.RouterAddress = net.JoinHostPort(host, strconv.Itoa(discovery.Port))
+	}
+
+	localIP, err := localAddressTo(opts.RouterAddress)
+	if err != nil {
+		return err
+	}
+	if opts.Host == "" {
+		opts.Host = localIP.String()
+	}
+	if cfg.sourceNetID == (ams.NetID{}) {
+		ip4 := localIP.To4()
+		if ip4 == nil {
+			return fmt.Errorf("cannot derive a source NetID from %s, set one with WithSourceNetID", localIP)
+		}
+		cfg.sourceNetID = ams.NetID{ip4[0], ip4[1], ip4[2], ip4[3], 1, 1}
+	}
+	if opts.Name == "" {
+		if opts.Name, err = os.Hostname(); err != nil || opts.Name == "" {
+			opts.Name = opts.Host
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+	defer cancel()
+
+	err = discovery.AddRoute(ctx, opts.RouterAddress, discovery.Route{
+		Name:     opts.Name,
+		NetID:    cfg.sourceNetID,
+		Host:     opts.Host,
+		Username: opts.Username,
+		Password: opts.Password,
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg.logger.Info("route registered on target",
+		"router", opts.RouterAddress, "name", opts.Name, "netID", cfg.sourceNetID, "host", opts.Host)
+	return nil
+}
+
+// localAddressTo returns the local IP address used to reach a UDP address.
+// No packet is sent.
+func localAddressTo(address string) (net.IP, error) {
+	conn, err := net.Dial("udp4", address)
+	if err != nil {
+		return nil, fmt.Errorf("determine local address: %w", err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
@@ -0,0 +1,58 @@
+package goadstc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/discovery"
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+func TestRegisterRoute(t *testing.T) {
+	server, err := discovery.StartServer("127.0.0.1:0", discovery.ServerConfig{
+		Device:   discovery.Device{NetID: ams.NetID{127, 0, 0, 1, 1, 1}},
+		Username: "Administrator",
+		Password: "1",
+	})
+	if err != nil {
+		t.Fatalf("StartServer() error = %v", err)
+	}
+	defer server.Close()
+
+	cfg := &clientConfig{
+		address: "127.0.0.1:48898",
+		timeout: time.Second,
+		logger:  &noopLogger{},
+		route: &RouteOptions{
+			Username:      "Administrator",
+			Password:      "1",
+			Name:          "test-client",
+			RouterAddress: server.Addr(),
+		},
+	}
+	if err := registerRoute(cfg); err != nil {
+		t.Fatalf("registerRoute() error = %v", err)
+	}
+
+	// Without a source NetID, one is derived from the local address and used for the route
+	want := discovery.Route{
+		Name:     "test-client",
+		NetID:    ams.NetID{127, 0, 0, 1, 1, 1},
+		Host:     "127.0.0.1",
+		Username: "Administrator",
+		Password: "1",
+	}
+	if cfg.sourceNetID != want.NetID {
+		t.Errorf("source NetID = %v, want %v", cfg.sourceNetID, want.NetID)
+	}
+	if routes := server.Routes(); len(routes) != 1 || routes[0] != want {
+		t.Errorf("routes = %+v, want %+v", routes, want)
+	}
+
+	cfg.route.Password = "wrong"
+	var routeErr *discovery.RouteError
+	if err := registerRoute(cfg); !errors.As(err, &routeErr) {
+		t.Errorf("registerRoute() with wrong password error = %v, want RouteError", err)
+	}
+}
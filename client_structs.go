@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math"
 	"strings"
+	"time"
 
 	"github.com/mrpasztoradam/goadstc/internal/ads"
 	"github.com/mrpasztoradam/goadstc/internal/symbols"
@@ -17,10 +18,12 @@ import (
 // For reading struct fields, use:
 //   - ReadSymbolValue(ctx, "MAIN.myStruct") - returns full struct as map[string]interface{}
 //   - ReadInt16(ctx, "MAIN.myStruct.field1") - direct field access with dot notation
+//   - ReadInto(ctx, "MAIN.myStruct", &goStruct) - decode into a tagged Go struct
 //
 // For writing struct fields, use:
 //   - WriteSymbolValue(ctx, "MAIN.myStruct.field1", value) - automatic type encoding
 //   - WriteInt16(ctx, "MAIN.myStruct.field1", value) - type-safe field writing
+//   - WriteFrom(ctx, "MAIN.myStruct", goStruct) - encode a tagged Go struct
 
 // Deprecated: Use ReadSymbolValue or type-safe Read methods with dot notation (e.g., ReadInt16(ctx, "MAIN.struct.field"))
 func (c *Client) ReadStructFieldInt16(ctx context.Context, fieldPath string) (int16, error) {
@@ -102,7 +105,7 @@ func (c *Client) UploadDataTypeTable(ctx context.Context) ([]byte, error) {
 
 	// Use Read command with ADSIGRP_SYM_DT_UPLOAD (0xF011)
 	readLength := dataTypeSize + 1024 // Add buffer
-	readData, err := c.Read(ctx, 0xF011, 0, readLength)
+	readData, err := c.Read(ctx, ads.IndexGroupDataTypeUpload, 0, readLength)
 	if err != nil {
 		return nil, fmt.Errorf("upload data type table: %w", err)
 	}
@@ -142,7 +145,16 @@ func (c *Client) getOrFetchTypeInfo(ctx context.Context, typeName string) (symbo
 	}
 	c.typeRegistryMu.RUnlock()
 
-	// Not in registry, fetch from PLC
+	// Load the whole data type table on first use
+	c.ensureTypesLoaded(ctx)
+	c.typeRegistryMu.RLock()
+	if typeInfo, exists := c.typeRegistry.Get(typeName); exists {
+		c.typeRegistryMu.RUnlock()
+		return typeInfo, nil
+	}
+	c.typeRegistryMu.RUnlock()
+
+	// Not in data type table, fetch from PLC
 	typeInfo, err := c.fetchTypeInfoFromPLC(ctx, typeName)
 	if err != nil {
 		return symbols.TypeInfo{}, err
@@ -150,125 +162,96 @@ func (c *Client) getOrFetchTypeInfo(ctx context.Context, typeName string) (symbo
 
 	// Cache it
 	c.typeRegistryMu.Lock()
-	c.typeRegistry.Register(typeName, typeInfo)
+	c.typeRegistry.Cache(typeName, typeInfo)
 	c.typeRegistryMu.Unlock()
 
 	return typeInfo, nil
 }
 
 // fetchTypeInfoFromPLC retrieves type information from the PLC using ADSIGRP_SYM_DT_UPLOAD (0xF011).
+// It is the fallback for types missing from the data type table loaded by LoadDataTypes.
 func (c *Client) fetchTypeInfoFromPLC(ctx context.Context, typeName string) (symbols.TypeInfo, error) {
 	// Use ReadWrite command with ADSIGRP_SYM_DT_UPLOAD (0xF011)
 	typeNameBytes := []byte(typeName)
 	typeNameBytes = append(typeNameBytes, 0) // Null terminator
 
-	readData, err := c.ReadWrite(ctx, 0xF011, 0, 0xFFFF, typeNameBytes)
+	readData, err := c.ReadWrite(ctx, ads.IndexGroupDataTypeUpload, 0, 0xFFFF, typeNameBytes)
 	if err != nil {
 		return symbols.TypeInfo{}, fmt.Errorf("read type info from PLC: %w", err)
 	}
 
-	if len(readData) < 42 {
-		return symbols.TypeInfo{}, fmt.Errorf("response too short for type info: %d bytes", len(readData))
+	entry, err := symbols.ParseDataTypeEntry(readData)
+	if err != nil {
+		return symbols.TypeInfo{}, fmt.Errorf("parse type info for %q: %w", typeName, err)
 	}
 
-	// Parse data type entry structure according to ADS specification:
-	// Offset 0: entryLength (4 bytes)
-	// Offset 16: size (4 bytes)
-	// Offset 20: offs (4 bytes)
-	// Offset 24: dataType (4 bytes)
-	// Offset 32: nameLength (2 bytes)
-	// Offset 34: typeLength (2 bytes)
-	// Offset 36: commentLength (2 bytes)
-	// Offset 40: subItems (2 bytes) <- number of fields
+	// Resolve nested struct fields through the registry or further requests
+	typeInfo := entry.TypeInfo(func(fieldTypeName string) (symbols.TypeInfo, bool) {
+		if isSimpleTypeName(fieldTypeName) {
+			return symbols.TypeInfo{}, false
+		}
+		fieldType, err := c.getOrFetchTypeInfo(ctx, fieldTypeName)
+		return fieldType, err == nil
+	})
+	typeInfo.Name = typeName
 
-	typeSize := binary.LittleEndian.Uint32(readData[16:20])
-	dataTypeID := binary.LittleEndian.Uint32(readData[24:28])
-	nameLength := binary.LittleEndian.Uint16(readData[32:34])
-	typeLength := binary.LittleEndian.Uint16(readData[34:36])
-	commentLength := binary.LittleEndian.Uint16(readData[36:38])
-	subItems := binary.LittleEndian.Uint16(readData[40:42])
+	return typeInfo, nil
+}
 
-	typeInfo := symbols.TypeInfo{
-		Name:     typeName,
-		BaseType: symbols.DataType(dataTypeID),
-		Size:     typeSize,
-		IsStruct: subItems > 0,
-		Fields:   make([]symbols.FieldInfo, 0, subItems),
+// LoadDataTypes uploads the complete data type table (0xF011) and caches every
+// type in the type registry, with nested struct fields resolved.
+// Struct and array parsing then needs no per-type requests.
+// It is called automatically the first time type information is needed.
+func (c *Client) LoadDataTypes(ctx context.Context) error {
+	data, err := c.UploadDataTypeTable(ctx)
+	if err != nil {
+		return fmt.Errorf("load data types: %w", err)
 	}
 
-	if subItems == 0 {
-		return typeInfo, nil // No fields (primitive type)
+	entries, err := symbols.ParseDataTypeTable(data)
+	if err != nil {
+		return fmt.Errorf("load data types: %w", err)
 	}
 
-	// Calculate offset to sub-items
-	offset := 42 + int(nameLength) + 1 + int(typeLength) + 1 + int(commentLength) + 1
-
-	// Parse each sub-item (field)
-	for i := 0; i < int(subItems) && offset+42 <= len(readData); i++ {
-		fieldSize := binary.LittleEndian.Uint32(readData[offset+16 : offset+20])
-		fieldOffset := binary.LittleEndian.Uint32(readData[offset+20 : offset+24])
-		fieldDataType := binary.LittleEndian.Uint32(readData[offset+24 : offset+28])
-		fieldNameLen := binary.LittleEndian.Uint16(readData[offset+32 : offset+34])
-		fieldTypeLen := binary.LittleEndian.Uint16(readData[offset+34 : offset+36])
-
-		// Extract field name
-		fieldNameStart := offset + 42
-		fieldNameEnd := fieldNameStart + int(fieldNameLen)
-		if fieldNameEnd > len(readData) {
-			break
-		}
-		fieldName := string(readData[fieldNameStart:fieldNameEnd])
-		// Remove null terminator
-		for idx := 0; idx < len(fieldName); idx++ {
-			if fieldName[idx] == 0 {
-				fieldName = fieldName[:idx]
-				break
-			}
-		}
+	types := symbols.ResolveDataTypes(entries)
 
-		// Extract field type name
-		fieldTypeStart := fieldNameEnd + 1
-		fieldTypeEnd := fieldTypeStart + int(fieldTypeLen)
-		if fieldTypeEnd > len(readData) {
-			break
-		}
-		fieldTypeName := string(readData[fieldTypeStart:fieldTypeEnd])
-		// Remove null terminator
-		for idx := 0; idx < len(fieldTypeName); idx++ {
-			if fieldTypeName[idx] == 0 {
-				fieldTypeName = fieldTypeName[:idx]
-				break
-			}
+	c.typeRegistryMu.Lock()
+	for name, typeInfo := range types {
+		// Types registered by the user take precedence
+		if c.typeRegistry.Has(name) && !c.typeRegistry.IsCached(name) {
+			continue
 		}
+		c.typeRegistry.Cache(name, typeInfo)
+	}
+	c.typesLoaded = true
+	c.typeRegistryMu.Unlock()
 
-		// Create field info
-		fieldInfo := symbols.FieldInfo{
-			Name:   fieldName,
-			Offset: fieldOffset,
-			Type: symbols.TypeInfo{
-				Name:     fieldTypeName,
-				BaseType: symbols.DataType(fieldDataType),
-				Size:     fieldSize,
-			},
-		}
+	c.logger.Debug("data types loaded", "count", len(types))
+	return nil
+}
 
-		// Check if field is a nested struct - recursively fetch its type info
-		if fieldDataType == 65 || !isSimpleDataType(symbols.DataType(fieldDataType)) {
-			fieldInfo.Type.IsStruct = true
-			// Recursively fetch nested struct type info
-			if nestedTypeInfo, err := c.fetchTypeInfoFromPLC(ctx, fieldTypeName); err == nil {
-				fieldInfo.Type = nestedTypeInfo
-			}
-		}
+// typesRetryInterval is the wait before the data type table upload is retried
+// after it failed.
+const typesRetryInterval = 30 * time.Second
 
-		typeInfo.Fields = append(typeInfo.Fields, fieldInfo)
+// ensureTypesLoaded loads the data type table once. A failed upload is only
+// logged; callers fall back to fetching single types and the upload is retried
+// after typesRetryInterval.
+func (c *Client) ensureTypesLoaded(ctx context.Context) {
+	c.typeRegistryMu.RLock()
+	skip := c.typesLoaded || time.Now().Before(c.typesRetryAt)
+	c.typeRegistryMu.RUnlock()
 
-		// Move to next sub-item
-		entryLength := binary.LittleEndian.Uint32(readData[offset : offset+4])
-		offset += int(entryLength)
+	if skip {
+		return
 	}
 
-	return typeInfo, nil
+	if err := c.LoadDataTypes(ctx); err != nil {
+		c.logger.Warn("data type table upload failed, fetching types individually", "error", err, "retryIn", typesRetryInterval)
+		c.typeRegistryMu.Lock()
+		c.typesRetryAt = time.Now().Add(typesRetryInterval)
+		c.typeRegistryMu.Unlock()
+	}
 }
 
 // isSimpleDataType checks if a data type is a simple (non-struct) type.
@@ -298,7 +281,7 @@ func (c *Client) ReadStructAsMap(ctx context.Context, symbolName string) (map[st
 	}
 
 	// Get symbol and validate type
-	symbol, structTypeName, err := c.getAndValidateStructSymbol(symbolName)
+	symbol, structTypeName, err := c.getAndValidateStructSymbol(ctx, symbolName)
 	if err != nil {
 		return nil, err
 	}
@@ -317,18 +300,13 @@ func (c *Client) ReadStructAsMap(ctx context.Context, symbolName string) (map[st
 }
 
 // getAndValidateStructSymbol gets the symbol and validates it's a struct type.
-func (c *Client) getAndValidateStructSymbol(symbolName string) (*symbols.Symbol, string, error) {
-	// Parse array notation if present
-	baseName, _, err := parseArrayAccess(symbolName)
-	if err != nil {
-		return nil, "", err
-	}
-
-	// Get the base symbol to check type
-	symbol, err := c.symbolTable.Get(baseName)
+func (c *Client) getAndValidateStructSymbol(ctx context.Context, symbolName string) (*symbols.Symbol, string, error) {
+	// Resolve the path, including array elements and nested fields
+	target, err := c.resolveSymbolPath(ctx, symbolName)
 	if err != nil {
-		return nil, "", fmt.Errorf("get symbol %q: %w", baseName, err)
+		return nil, "", fmt.Errorf("get symbol %q: %w", symbolName, err)
 	}
+	symbol := target.symbol
 
 	// Determine the struct type name (handle array of structs)
 	structTypeName := symbol.Type.Name
@@ -351,14 +329,22 @@ func (c *Client) resolveTypeInfo(ctx context.Context, structTypeName string) (sy
 	typeInfo, hasTypeInfo := c.typeRegistry.Get(structTypeName)
 	c.typeRegistryMu.RUnlock()
 
-	// If not registered or no fields, try to fetch from PLC
+	// If not registered or no fields, load the data type table and look again
+	if !hasTypeInfo || len(typeInfo.Fields) == 0 {
+		c.ensureTypesLoaded(ctx)
+		c.typeRegistryMu.RLock()
+		typeInfo, hasTypeInfo = c.typeRegistry.Get(structTypeName)
+		c.typeRegistryMu.RUnlock()
+	}
+
+	// Still nothing, try to fetch the single type from PLC
 	if !hasTypeInfo || len(typeInfo.Fields) == 0 {
 		if fetchedTypeInfo, err := c.fetchTypeInfoFromPLC(ctx, structTypeName); err == nil {
 			typeInfo = fetchedTypeInfo
 			hasTypeInfo = true
 			// Cache it for future use
 			c.typeRegistryMu.Lock()
-			c.typeRegistry.Register(structTypeName, typeInfo)
+			c.typeRegistry.Cache(structTypeName, typeInfo)
 			c.typeRegistryMu.Unlock()
 		}
 	}
@@ -394,6 +380,10 @@ func parseFieldsFromTypeInfo(result map[string]interface{}, structData []byte, f
 			continue // Skip fields beyond data bounds
 		}
 		fieldData := structData[field.Offset : field.Offset+field.Type.Size]
+		if field.BitSize > 0 {
+			result[field.Name] = bitFieldValue(extractBits(fieldData, field.BitOffset, field.BitSize), field.BitSize)
+			continue
+		}
 		result[field.Name] = parseFieldValue(fieldData, field.Type)
 	}
 }
@@ -412,6 +402,11 @@ func parseFieldValue(data []byte, typeInfo symbols.TypeInfo) interface{} {
 		return nil
 	}
 
+	// Handle enums
+	if len(typeInfo.EnumValues) > 0 {
+		return decodeEnumValue(data, typeInfo)
+	}
+
 	// Handle arrays
 	if typeInfo.IsArray {
 		return fmt.Sprintf("<array %d bytes>", len(data))
@@ -438,13 +433,7 @@ func parseNestedStruct(data []byte, typeInfo symbols.TypeInfo) interface{} {
 	}
 
 	nestedResult := make(map[string]interface{})
-	for _, field := range typeInfo.Fields {
-		if int(field.Offset)+int(field.Type.Size) > len(data) {
-			continue
-		}
-		fieldData := data[field.Offset : field.Offset+field.Type.Size]
-		nestedResult[field.Name] = parseFieldValue(fieldData, field.Type)
-	}
+	parseFieldsFromTypeInfo(nestedResult, data, typeInfo.Fields)
 	return nestedResult
 }
 
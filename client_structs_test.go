@@ -0,0 +1,48 @@
+package goadstc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/adssim"
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+func TestDataTypeUploadRetried(t *testing.T) {
+	server, client := startSimulator(t, adssim.Config{
+		DataTypes: []symbols.DataTypeEntry{flagsType},
+		Symbols:   []adssim.Symbol{{Name: "MAIN.flags", Type: "ST_Flags"}},
+		Faults: []adssim.Fault{{
+			Kind: adssim.FaultError, Error: ads.ErrDeviceServiceNotSupported,
+			Command: ads.CmdRead, IndexGroup: ads.IndexGroupDataTypeUpload, Count: 1,
+		}},
+	})
+	ctx := context.Background()
+
+	loaded := func() bool {
+		client.typeRegistryMu.RLock()
+		defer client.typeRegistryMu.RUnlock()
+		return client.typesLoaded
+	}
+
+	client.ensureTypesLoaded(ctx)
+	if loaded() || server.FaultsTriggered() != 1 {
+		t.Fatalf("types loaded = %v after failed upload, faults triggered = %d", loaded(), server.FaultsTriggered())
+	}
+
+	// Not retried right away, but once the retry interval passed
+	client.ensureTypesLoaded(ctx)
+	if loaded() {
+		t.Fatal("upload retried before the retry interval")
+	}
+	client.typeRegistryMu.Lock()
+	client.typesRetryAt = time.Now()
+	client.typeRegistryMu.Unlock()
+
+	client.ensureTypesLoaded(ctx)
+	if !loaded() {
+		t.Fatal("upload not retried after the retry interval")
+	}
+}
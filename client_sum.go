@@ -0,0 +1,219 @@
+package goadstc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+)
+
+// sumRead reads several memory areas using ADS sum command 0xF080.
+// Requests larger than ads.MaxSumCommands are split automatically.
+// The returned slice has one result per item; an item that failed on the PLC
+// carries a classified ADS error and no data.
+func (c *Client) sumRead(ctx context.Context, items []ads.SumReadItem) ([]SumResult, error) {
+	results := make([]SumResult, 0, len(items))
+
+	for start := 0; start < len(items); start += ads.MaxSumCommands {
+		end := min(start+ads.MaxSumCommands, len(items))
+		chunk, err := c.sumReadChunk(ctx, items[start:end])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, chunk...)
+	}
+
+	return results, nil
+}
+
+// sumReadChunk sends a single sum read request with at most ads.MaxSumCommands items.
+func (c *Client) sumReadChunk(ctx context.Context, items []ads.SumReadItem) ([]SumResult, error) {
+	start := time.Now()
+	c.metrics.OperationStarted("sum_read")
+	c.logger.Debug("sending sum read", "items", len(items))
+
+	req := ads.SumReadRequest{Items: items}
+	reqData, _ := req.MarshalBinary()
+
+	readData, err := c.ReadWrite(ctx, ads.IndexGroupSumCommandRead, uint32(len(items)), req.ReadLength(), reqData)
+	if err != nil {
+		c.logger.Error("sum read failed", "error", err, "items", len(items))
+		c.metrics.OperationCompleted("sum_read", time.Since(start), err)
+		ce := ClassifyError(err, "sum_read")
+		c.metrics.ErrorOccurred(ce.Category, "sum_read")
+		return nil, ce
+	}
+
+	lengths := make([]uint32, len(items))
+	for i, item := range items {
+		lengths[i] = item.Length
+	}
+
+	resp := ads.SumReadResponse{Lengths: lengths}
+	if err := resp.UnmarshalBinary(readData); err != nil {
+		c.logger.Error("sum read unmarshal failed", "error", err)
+		c.metrics.OperationCompleted("sum_read", time.Since(start), err)
+		c.metrics.ErrorOccurred(ErrorCategoryProtocol, "sum_read")
+		return nil, err
+	}
+
+	results := make([]SumResult, len(items))
+	for i := range items {
+		if resp.Results[i] != 0 {
+			results[i].Err = NewADSError("sum_read", ads.Error(resp.Results[i]))
+			continue
+		}
+		results[i].Data = resp.Data[i]
+	}
+
+	c.metrics.BytesReceived(int64(len(readData)))
+	c.metrics.OperationCompleted("sum_read", time.Since(start), nil)
+	c.logger.Debug("sum read completed", "items", len(items), "bytes", len(readData), "duration", time.Since(start))
+	return results, nil
+}
+
+// SumResult holds the outcome of a single sub-command of a sum request.
+type SumResult struct {
+	Data []byte // Data returned by the PLC (nil for writes or on error)
+	Err  error  // Per-item error; a *ClassifiedError carrying the ADS error code
+}
+
+// ADSErrorCode returns the ADS error code of the item, or 0 if it succeeded.
+func (r SumResult) ADSErrorCode() uint32 {
+	var adsErr ads.Error
+	if errors.As(r.Err, &adsErr) {
+		return uint32(adsErr)
+	}
+	return 0
+}
+
+// isSumCommandUnsupported reports whether the target rejected the sum command itself,
+// which happens on older runtimes (e.g. TwinCAT 2 BC controllers).
+func isSumCommandUnsupported(err error) bool {
+	var adsErr ads.Error
+	if !errors.As(err, &adsErr) {
+		return false
+	}
+	return adsErr == ads.ErrDeviceServiceNotSupported || adsErr == ads.ErrDeviceInvalidIndexGroup
+}
+
+// SumReadWriteItem describes a single sub-command of a sum read/write request.
+type SumReadWriteItem = ads.SumReadWriteItem
+
+// SumReadWrite executes several read/write operations in a single ADS sum
+// command request (0xF082). Requests larger than 500 items are split into
+// several requests. The returned slice contains one SumResult per item in the
+// same order; Data holds at most ReadLength bytes as returned by the PLC.
+func (c *Client) SumReadWrite(ctx context.Context, items []SumReadWriteItem) ([]SumResult, error) {
+	results := make([]SumResult, 0, len(items))
+
+	for start := 0; start < len(items); start += ads.MaxSumCommands {
+		end := min(start+ads.MaxSumCommands, len(items))
+		chunk, err := c.sumReadWriteChunk(ctx, items[start:end])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, chunk...)
+	}
+
+	return results, nil
+}
+
+// sumReadWriteChunk sends a single sum read/write request with at most ads.MaxSumCommands items.
+func (c *Client) sumReadWriteChunk(ctx context.Context, items []SumReadWriteItem) ([]SumResult, error) {
+	start := time.Now()
+	c.metrics.OperationStarted("sum_read_write")
+	c.logger.Debug("sending sum read/write", "items", len(items))
+
+	req := ads.SumReadWriteRequest{Items: items}
+	reqData, _ := req.MarshalBinary()
+
+	readData, err := c.ReadWrite(ctx, ads.IndexGroupSumCommandReadWrite, uint32(len(items)), req.ReadLength(), reqData)
+	if err != nil {
+		c.logger.Error("sum read/write failed", "error", err, "items", len(items))
+		c.metrics.OperationCompleted("sum_read_write", time.Since(start), err)
+		ce := ClassifyError(err, "sum_read_write")
+		c.metrics.ErrorOccurred(ce.Category, "sum_read_write")
+		return nil, ce
+	}
+
+	resp := ads.SumReadWriteResponse{Count: len(items)}
+	if err := resp.UnmarshalBinary(readData); err != nil {
+		c.logger.Error("sum read/write unmarshal failed", "error", err)
+		c.metrics.OperationCompleted("sum_read_write", time.Since(start), err)
+		c.metrics.ErrorOccurred(ErrorCategoryProtocol, "sum_read_write")
+		return nil, err
+	}
+
+	results := make([]SumResult, len(items))
+	for i := range items {
+		if resp.Results[i] != 0 {
+			results[i].Err = NewADSError("sum_read_write", ads.Error(resp.Results[i]))
+			continue
+		}
+		results[i].Data = resp.Data[i]
+	}
+
+	c.metrics.BytesSent(int64(len(reqData)))
+	c.metrics.BytesReceived(int64(len(readData)))
+	c.metrics.OperationCompleted("sum_read_write", time.Since(start), nil)
+	c.logger.Debug("sum read/write completed", "items", len(items), "duration", time.Since(start))
+	return results, nil
+}
+
+// sumWrite writes several memory areas using ADS sum command 0xF081.
+// Requests larger than ads.MaxSumCommands are split automatically.
+func (c *Client) sumWrite(ctx context.Context, items []ads.SumWriteItem) ([]SumResult, error) {
+	results := make([]SumResult, 0, len(items))
+
+	for start := 0; start < len(items); start += ads.MaxSumCommands {
+		end := min(start+ads.MaxSumCommands, len(items))
+		chunk, err := c.sumWriteChunk(ctx, items[start:end])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, chunk...)
+	}
+
+	return results, nil
+}
+
+// sumWriteChunk sends a single sum write request with at most ads.MaxSumCommands items.
+func (c *Client) sumWriteChunk(ctx context.Context, items []ads.SumWriteItem) ([]SumResult, error) {
+	start := time.Now()
+	c.metrics.OperationStarted("sum_write")
+	c.logger.Debug("sending sum write", "items", len(items))
+
+	req := ads.SumWriteRequest{Items: items}
+	reqData, _ := req.MarshalBinary()
+
+	readData, err := c.ReadWrite(ctx, ads.IndexGroupSumCommandWrite, uint32(len(items)), req.ReadLength(), reqData)
+	if err != nil {
+		c.logger.Error("sum write failed", "error", err, "items", len(items))
+		c.metrics.OperationCompleted("sum_write", time.Since(start), err)
+		ce := ClassifyError(err, "sum_write")
+		c.metrics.ErrorOccurred(ce.Category, "sum_write")
+		return nil, ce
+	}
+
+	resp := ads.SumWriteResponse{Count: len(items)}
+	if err := resp.UnmarshalBinary(readData); err != nil {
+		c.logger.Error("sum write unmarshal failed", "error", err)
+		c.metrics.OperationCompleted("sum_write", time.Since(start), err)
+		c.metrics.ErrorOccurred(ErrorCategoryProtocol, "sum_write")
+		return nil, err
+	}
+
+	results := make([]SumResult, len(items))
+	for i := range items {
+		if resp.Results[i] != 0 {
+			results[i].Err = NewADSError("sum_write", ads.Error(resp.Results[i]))
+		}
+	}
+
+	c.metrics.BytesSent(int64(len(reqData)))
+	c.metrics.OperationCompleted("sum_write", time.Since(start), nil)
+	c.logger.Debug("sum write completed", "items", len(items), "duration", time.Since(start))
+	return results, nil
+}
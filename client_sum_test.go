@@ -0,0 +1,70 @@
+package goadstc
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/mrpasztoradam/goadstc/adssim"
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+)
+
+func TestSumReadWrite(t *testing.T) {
+	const count = 2*ads.MaxSumCommands + 100
+	server, client := startSimulator(t, adssim.Config{Symbols: counterSymbols(count)})
+
+	// Read every value by name (0xF004), with a missing symbol in the second request
+	const missing = 700
+	items := make([]SumReadWriteItem, count)
+	for i := range items {
+		name := fmt.Sprintf("MAIN.v%d", i)
+		if i == missing {
+			name = "MAIN.missing"
+		}
+		items[i] = SumReadWriteItem{
+			IndexGroup: ads.IndexGroupSymbolValueByName,
+			ReadLength: 4,
+			WriteData:  append([]byte(name), 0),
+		}
+	}
+
+	// Count the sum requests without changing them
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultDelay, Command: ads.CmdReadWrite, IndexGroup: ads.IndexGroupSumCommandReadWrite})
+
+	results, err := client.SumReadWrite(context.Background(), items)
+	if err != nil {
+		t.Fatalf("SumReadWrite() error = %v", err)
+	}
+	if len(results) != count {
+		t.Fatalf("got %d results, want %d", len(results), count)
+	}
+	for i, result := range results {
+		if i == missing {
+			if result.ADSErrorCode() != uint32(ads.ErrDeviceSymbolNotFound) || result.Data != nil {
+				t.Errorf("result %d = %+v, want ADS error %#x", i, result, uint32(ads.ErrDeviceSymbolNotFound))
+			}
+			continue
+		}
+		if result.Err != nil || len(result.Data) != 4 || binary.LittleEndian.Uint32(result.Data) != uint32(i) {
+			t.Errorf("result %d = %+v, want the value %d", i, result, i)
+		}
+	}
+	if n := server.FaultsTriggered(); n != 3 {
+		t.Errorf("%d sum requests sent for %d items, want 3", n, count)
+	}
+}
+
+func TestSumReadWriteRejected(t *testing.T) {
+	server, client := startSimulator(t, adssim.Config{Symbols: counterSymbols(1)})
+	server.InjectFault(adssim.Fault{
+		Kind: adssim.FaultError, Error: ads.ErrDeviceServiceNotSupported,
+		Command: ads.CmdReadWrite, IndexGroup: ads.IndexGroupSumCommandReadWrite,
+	})
+
+	items := []SumReadWriteItem{{IndexGroup: ads.IndexGroupSymbolValueByName, ReadLength: 4, WriteData: []byte("MAIN.v0\x00")}}
+	results, err := client.SumReadWrite(context.Background(), items)
+	if err == nil || !isSumCommandUnsupported(err) {
+		t.Errorf("SumReadWrite() = %v, %v; want the sum command rejection", results, err)
+	}
+}
@@ -0,0 +1,106 @@
+package goadstc
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+)
+
+// startSymbolVersionWatch subscribes to the PLC symbol version (0xF008) unless a
+// watch is already active. A failed subscription is logged and retried on the
+// next symbol table load, it does not fail the caller.
+func (c *Client) startSymbolVersionWatch(ctx context.Context) {
+	c.symbolWatchMu.Lock()
+	defer c.symbolWatchMu.Unlock()
+
+	if c.symbolWatch != nil {
+		return
+	}
+
+	sub, err := c.Subscribe(ctx, NotificationOptions{
+		IndexGroup:       ads.IndexGroupSymbolVersion,
+		IndexOffset:      0,
+		Length:           1,
+		TransmissionMode: ads.TransModeOnChange,
+	})
+	if err != nil {
+		c.logger.Warn("failed to watch symbol version, online changes will not be detected", "error", err)
+		return
+	}
+
+	c.subscriptionsMu.Lock()
+	sub.internal = true
+	c.subscriptionsMu.Unlock()
+
+	c.symbolWatch = sub
+	c.logger.Debug("watching symbol version", "handle", sub.Handle())
+
+	go c.watchSymbolVersion(sub)
+}
+
+// restartSymbolVersionWatch re-subscribes to the symbol version after a reconnect.
+// The watch is only restarted if it was active before.
+func (c *Client) restartSymbolVersionWatch() {
+	c.symbolWatchMu.Lock()
+	active := c.symbolWatch != nil
+	c.symbolWatch = nil
+	c.symbolWatchMu.Unlock()
+
+	if !active {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.timeout)
+	defer cancel()
+	c.startSymbolVersionWatch(ctx)
+}
+
+// watchSymbolVersion consumes symbol version notifications until the subscription is closed.
+func (c *Client) watchSymbolVersion(sub *Subscription) {
+	for notif := range sub.Notifications() {
+		if len(notif.Data) < 1 {
+			continue
+		}
+		c.handleSymbolVersion(notif.Data[0])
+	}
+}
+
+// handleSymbolVersion records the symbol version and reloads symbols when it changed.
+// The first value seen only sets the baseline.
+func (c *Client) handleSymbolVersion(version uint8) {
+	c.symbolWatchMu.Lock()
+	oldVersion := c.symbolVersion
+	changed := c.symbolVersionKnown && oldVersion != version
+	c.symbolVersion = version
+	c.symbolVersionKnown = true
+	c.symbolWatchMu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	c.logger.Info("PLC symbol version changed, reloading symbols", "oldVersion", oldVersion, "newVersion", version)
+
+	// Handles and type layouts from before the online change are no longer valid
+	if c.handles != nil {
+		c.releaseDroppedHandles(context.Background(), c.handles.reset()...)
+	}
+	c.typeRegistryMu.Lock()
+	c.typeRegistry.ClearCached()
+	c.typesLoaded = false
+	c.typesRetryAt = time.Time{}
+	c.typeRegistryMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	err := c.RefreshSymbols(ctx)
+	cancel()
+
+	if err != nil {
+		c.logger.Error("failed to reload symbols after online change", "error", err)
+	}
+
+	if c.symbolChangeCallback != nil {
+		go c.symbolChangeCallback(oldVersion, version, err)
+	}
+}
@@ -0,0 +1,159 @@
+package goadstc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/adssim"
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+// symbolChange is a call of a SymbolChangeCallback.
+type symbolChange struct {
+	oldVersion, newVersion uint8
+	err                    error
+}
+
+// startVersionWatchServer starts a simulator and a client whose symbol
+// version watch is active and has seen the initial version.
+func startVersionWatchServer(t *testing.T, opts ...Option) (*adssim.Server, *Client, <-chan symbolChange) {
+	t.Helper()
+	changes := make(chan symbolChange, 4)
+	opts = append(opts, WithSymbolChangeCallback(func(oldVersion, newVersion uint8, err error) {
+		changes <- symbolChange{oldVersion, newVersion, err}
+	}))
+	server, client := startSimulator(t, adssim.Config{
+		DataTypes: []symbols.DataTypeEntry{flagsType},
+		Symbols: []adssim.Symbol{
+			{Name: "MAIN.counter", Type: "DINT", Value: []byte{1, 0, 0, 0}},
+			{Name: "MAIN.flags", Type: "ST_Flags", Value: []byte{0b01}},
+		},
+	}, opts...)
+
+	if err := client.RefreshSymbols(context.Background()); err != nil {
+		t.Fatalf("RefreshSymbols() error = %v", err)
+	}
+	waitSymbolVersionKnown(t, client)
+	return server, client, changes
+}
+
+// waitSymbolVersionKnown waits for the first sample of the symbol version watch.
+func waitSymbolVersionKnown(t *testing.T, client *Client) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		client.symbolWatchMu.Lock()
+		known := client.symbolVersionKnown
+		client.symbolWatchMu.Unlock()
+		if known {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("symbol version watch did not report the version")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// nextSymbolChange waits for a call of the symbol change callback.
+func nextSymbolChange(t *testing.T, changes <-chan symbolChange) symbolChange {
+	t.Helper()
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(2 * time.Second):
+		t.Fatal("symbol change callback not called")
+		return symbolChange{}
+	}
+}
+
+func TestSymbolChangeCallback(t *testing.T) {
+	server, client, changes := startVersionWatchServer(t, WithHandleAccess())
+	ctx := context.Background()
+
+	// Fill the handle cache and the type registry
+	if _, err := client.ReadSymbolValue(ctx, "MAIN.counter"); err != nil {
+		t.Fatalf("ReadSymbolValue() error = %v", err)
+	}
+	if _, err := client.ReadSymbolValue(ctx, "MAIN.flags"); err != nil {
+		t.Fatalf("ReadSymbolValue() error = %v", err)
+	}
+	if _, ok := client.handles.get("MAIN.counter"); !ok {
+		t.Fatal("handle of MAIN.counter not cached")
+	}
+	client.typeRegistryMu.RLock()
+	loaded := client.typesLoaded
+	client.typeRegistryMu.RUnlock()
+	if !loaded {
+		t.Fatal("data types not loaded")
+	}
+
+	version := server.SymbolVersion()
+	if err := server.AddSymbol(adssim.Symbol{Name: "MAIN.added", Type: "UDINT", Value: []byte{5, 0, 0, 0}}); err != nil {
+		t.Fatalf("AddSymbol() error = %v", err)
+	}
+
+	change := nextSymbolChange(t, changes)
+	if change.oldVersion != version || change.newVersion != version+1 || change.err != nil {
+		t.Errorf("callback(%d, %d, %v), want (%d, %d, nil)", change.oldVersion, change.newVersion, change.err, version, version+1)
+	}
+
+	// Handles and types from before the change are dropped
+	if _, ok := client.handles.get("MAIN.counter"); ok {
+		t.Error("handle of MAIN.counter still cached after the online change")
+	}
+	client.typeRegistryMu.RLock()
+	loaded = client.typesLoaded
+	client.typeRegistryMu.RUnlock()
+	if loaded {
+		t.Error("data types still marked loaded after the online change")
+	}
+
+	// The symbol table was reloaded before the callback
+	if value, err := client.ReadSymbolValue(ctx, "MAIN.added"); err != nil || value != uint32(5) {
+		t.Errorf("ReadSymbolValue(MAIN.added) = %v, %v; want 5", value, err)
+	}
+}
+
+func TestSymbolVersionWatchAfterReconnect(t *testing.T) {
+	server, client, changes := startVersionWatchServer(t, WithTimeout(300*time.Millisecond), WithAutoReconnect(true))
+	ctx := context.Background()
+
+	client.symbolWatchMu.Lock()
+	oldWatch := client.symbolWatch
+	client.symbolWatchMu.Unlock()
+
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultReset, Count: 1})
+	if _, err := client.ReadState(ctx); err == nil {
+		t.Fatal("ReadState() on reset connection succeeded")
+	}
+
+	// An online change while disconnected is noticed by the new watch
+	version := server.SymbolVersion()
+	if err := server.AddSymbol(adssim.Symbol{Name: "MAIN.added", Type: "UDINT"}); err != nil {
+		t.Fatalf("AddSymbol() error = %v", err)
+	}
+	waitReconnected(t, client)
+
+	change := nextSymbolChange(t, changes)
+	if change.oldVersion != version || change.newVersion != version+1 {
+		t.Errorf("callback(%d, %d, %v), want (%d, %d)", change.oldVersion, change.newVersion, change.err, version, version+1)
+	}
+
+	client.symbolWatchMu.Lock()
+	watch := client.symbolWatch
+	client.symbolWatchMu.Unlock()
+	if watch == nil || watch == oldWatch {
+		t.Fatal("symbol version watch not restarted after reconnect")
+	}
+
+	// And so are later ones
+	if err := server.RemoveSymbol("MAIN.added"); err != nil {
+		t.Fatalf("RemoveSymbol() error = %v", err)
+	}
+	change = nextSymbolChange(t, changes)
+	if change.oldVersion != version+1 || change.newVersion != version+2 {
+		t.Errorf("callback(%d, %d, %v), want (%d, %d)", change.oldVersion, change.newVersion, change.err, version+1, version+2)
+	}
+}
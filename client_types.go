@@ -208,12 +208,12 @@ func (c *Client) ReadWString(ctx context.Context, symbolName string) (string, er
 		return "", err
 	}
 
-	indexGroup, indexOffset, size, err := c.resolveArraySymbol(ctx, symbolName)
+	target, err := c.resolveSymbolPath(ctx, symbolName)
 	if err != nil {
 		return "", fmt.Errorf("read wstring %q: %w", symbolName, err)
 	}
 
-	data, err := c.Read(ctx, indexGroup, indexOffset, size)
+	data, err := c.readResolved(ctx, target)
 	if err != nil {
 		return "", err
 	}
@@ -328,10 +328,11 @@ func (c *Client) WriteString(ctx context.Context, symbolName string, value strin
 		return err
 	}
 
-	indexGroup, indexOffset, size, err := c.resolveArraySymbol(ctx, symbolName)
+	target, err := c.resolveSymbolPath(ctx, symbolName)
 	if err != nil {
 		return fmt.Errorf("write string %q: %w", symbolName, err)
 	}
+	size := target.size
 
 	// Create buffer with the string's allocated size
 	data := make([]byte, size)
@@ -344,7 +345,7 @@ func (c *Client) WriteString(ctx context.Context, symbolName string, value strin
 	copy(data, []byte(value))
 	// data is already zero-filled, so null terminator is implicit
 
-	return c.Write(ctx, indexGroup, indexOffset, data)
+	return c.writeResolved(ctx, target, data)
 }
 
 // WriteTime writes a time.Duration value to a TIME symbol.
@@ -383,10 +384,11 @@ func (c *Client) WriteWString(ctx context.Context, symbolName string, value stri
 		return err
 	}
 
-	indexGroup, indexOffset, size, err := c.resolveArraySymbol(ctx, symbolName)
+	target, err := c.resolveSymbolPath(ctx, symbolName)
 	if err != nil {
 		return fmt.Errorf("write wstring %q: %w", symbolName, err)
 	}
+	size := target.size
 
 	// Create buffer with the string's allocated size
 	data := make([]byte, size)
@@ -407,5 +409,5 @@ func (c *Client) WriteWString(ctx context.Context, symbolName string, value stri
 	}
 	// data is already zero-filled, so null terminator is implicit
 
-	return c.Write(ctx, indexGroup, indexOffset, data)
+	return c.writeResolved(ctx, target, data)
 }
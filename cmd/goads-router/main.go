@@ -0,0 +1,87 @@
+// Command goads-router is an AMS/TCP proxy for machines without a TwinCAT
+// router. It accepts standard AMS/TCP connections from ADS clients (pyads,
+// TwinCAT tools, goadstc) and forwards their requests to PLCs by the target
+// NetID of each request.
+//
+// Towards a PLC all clients share one connection and use the NetID of the
+// router, so only that NetID needs a route on the PLC. Source addresses and
+// invoke IDs are rewritten on the way in and restored on responses and
+// notifications. Notifications and symbol handles of a client that disconnects
+// are deleted and released on its behalf.
+//
+//	goads-router -netid 192.168.1.10.1.1 \
+//	    -target 192.168.1.100.1.1=192.168.1.100 \
+//	    -target 192.168.1.101.1.1=192.168.1.101:48898
+//
+// Clients then connect to port 48898 of the router host with the NetID of the
+// PLC as target.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+func main() {
+	listenAddr := flag.String("listen", ":48898", "Address to accept AMS/TCP connections on")
+	netID := flag.String("netid", "", "AMS NetID of the router, as routed on the PLCs")
+	timeout := flag.Duration("timeout", 5*time.Second, "Timeout for connecting to a PLC")
+	targets := targetFlag{}
+	flag.Var(targets, "target", "PLC as NETID=host[:port] (repeatable, default port 48898)")
+	flag.Parse()
+
+	if *netID == "" || len(targets) == 0 {
+		log.Fatalf("goads-router: -netid and at least one -target are required")
+	}
+	routerNetID, err := ams.ParseNetID(*netID)
+	if err != nil {
+		log.Fatalf("goads-router: %v", err)
+	}
+
+	p, err := listen(*listenAddr, routerNetID, targets, *timeout)
+	if err != nil {
+		log.Fatalf("goads-router: %v", err)
+	}
+	log.Printf("goads-router: listening on %s as %s for %d targets", p.Addr(), routerNetID, len(targets))
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	p.Close()
+}
+
+// targetFlag collects -target flags as PLC addresses by NetID.
+type targetFlag map[ams.NetID]string
+
+func (f targetFlag) String() string {
+	entries := make([]string, 0, len(f))
+	for netID, address := range f {
+		entries = append(entries, netID.String()+"="+address)
+	}
+	return strings.Join(entries, ",")
+}
+
+func (f targetFlag) Set(value string) error {
+	netIDText, address, ok := strings.Cut(value, "=")
+	if !ok || address == "" {
+		return fmt.Errorf("expected NETID=host[:port], got %q", value)
+	}
+	netID, err := ams.ParseNetID(netIDText)
+	if err != nil {
+		return err
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "48898")
+	}
+	f[netID] = address
+	return nil
+}
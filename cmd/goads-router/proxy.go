@@ -0,0 +1,566 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+// firstPort is the first virtual source port used towards a target.
+const firstPort ams.Port = 32905
+
+// proxy forwards the AMS/TCP requests of downstream ADS clients to PLC targets
+// by target NetID. Towards a target all clients share one connection and
+// appear with the NetID of the proxy, each source NetID/port of a client
+// behind its own virtual port, so a single route on the PLC covers them all.
+type proxy struct {
+	netID   ams.NetID            // Source NetID towards the targets
+	targets map[ams.NetID]string // Target addresses by NetID
+	timeout time.Duration        // Dial timeout
+
+	listener net.Listener
+
+	mu        sync.Mutex
+	upstreams map[ams.NetID]*upstream
+	clients   map[*downstream]struct{}
+	closed    bool
+
+	// dialMu serializes dialing, so a target is connected once
+	dialMu sync.Mutex
+
+	wg sync.WaitGroup
+}
+
+// endpoint is the source address of a downstream client.
+type endpoint struct {
+	client *downstream
+	netID  ams.NetID
+	port   ams.Port
+}
+
+// pendingRequest is a forwarded request waiting for its response.
+type pendingRequest struct {
+	endpoint
+	invokeID uint32      // Invoke ID of the client
+	port     ams.Port    // Virtual port of the client
+	request  *ams.Packet // Kept if the response may acquire resources
+}
+
+// resource is a notification or symbol handle on a port of a target.
+type resource struct {
+	port   ams.Port
+	handle uint32
+}
+
+// holder is the client a resource was acquired for.
+type holder struct {
+	endpoint
+	port ams.Port // Virtual port the resource belongs to on the target
+}
+
+// upstream is the connection to a target, shared by all clients.
+type upstream struct {
+	netID ams.NetID
+	conn  net.Conn
+
+	writeMu sync.Mutex
+
+	// Guarded by proxy.mu
+	invokeID uint32
+	pending  map[uint32]pendingRequest // by upstream invoke ID
+	ports    map[ams.Port]endpoint
+	routes   map[endpoint]ams.Port
+	nextPort ams.Port
+
+	// Notifications and symbol handles of the clients, deleted and released
+	// on their behalf when they disconnect
+	notifications map[resource]holder
+	handles       map[resource]holder
+}
+
+// downstream is a connection of an ADS client to the proxy.
+type downstream struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+}
+
+// write sends a packet to the client.
+func (d *downstream) write(packet *ams.Packet) error {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+	return ams.WritePacket(d.conn, packet)
+}
+
+// listen starts a proxy on address.
+func listen(address string, netID ams.NetID, targets map[ams.NetID]string, timeout time.Duration) (*proxy, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s: %w", address, err)
+	}
+
+	p := &proxy{
+		netID:     netID,
+		targets:   targets,
+		timeout:   timeout,
+		listener:  listener,
+		upstreams: make(map[ams.NetID]*upstream),
+		clients:   make(map[*downstream]struct{}),
+	}
+	p.wg.Add(1)
+	go p.serve()
+	return p, nil
+}
+
+// Addr returns the address the proxy listens on.
+func (p *proxy) Addr() string {
+	return p.listener.Addr().String()
+}
+
+// Close stops the proxy and closes all connections.
+func (p *proxy) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	var conns []net.Conn
+	for d := range p.clients {
+		conns = append(conns, d.conn)
+	}
+	for _, u := range p.upstreams {
+		conns = append(conns, u.conn)
+	}
+	p.mu.Unlock()
+
+	err := p.listener.Close()
+	for _, conn := range conns {
+		conn.Close()
+	}
+	p.wg.Wait()
+	return err
+}
+
+// serve accepts client connections until the listener is closed.
+func (p *proxy) serve() {
+	defer p.wg.Done()
+
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		d := &downstream{conn: conn}
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			conn.Close()
+			return
+		}
+		p.clients[d] = struct{}{}
+		p.mu.Unlock()
+
+		log.Printf("client %s connected", conn.RemoteAddr())
+		p.wg.Add(1)
+		go p.runDownstream(d)
+	}
+}
+
+// runDownstream forwards the requests of a client until it disconnects.
+func (p *proxy) runDownstream(d *downstream) {
+	defer p.wg.Done()
+	defer p.removeDownstream(d)
+	defer d.conn.Close()
+
+	for {
+		packet, err := ams.ReadPacket(d.conn)
+		if err != nil {
+			return
+		}
+		if !packet.Header.IsRequest() {
+			continue
+		}
+		if err := p.forward(d, packet); err != nil {
+			log.Printf("client %s: %v", d.conn.RemoteAddr(), err)
+			if err := d.write(errorResponse(packet, ads.ErrTargetMachineNotFound)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// removeDownstream forgets a disconnected client, its virtual ports and its
+// pending requests, and deletes its notifications and releases its symbol
+// handles on the targets. Requests that may still acquire a resource stay
+// pending, the resource is freed when the response arrives.
+func (p *proxy) removeDownstream(d *downstream) {
+	p.mu.Lock()
+	delete(p.clients, d)
+	releases := make(map[*upstream][]*ams.Packet)
+	for _, u := range p.upstreams {
+		for ep, port := range u.routes {
+			if ep.client == d {
+				delete(u.routes, ep)
+				delete(u.ports, port)
+			}
+		}
+		for invokeID, req := range u.pending {
+			if req.client == d && req.request == nil {
+				delete(u.pending, invokeID)
+			}
+		}
+		if packets := p.releaseRequests(u, d); len(packets) > 0 {
+			releases[u] = packets
+		}
+	}
+	p.mu.Unlock()
+
+	for u, packets := range releases {
+		p.send(u, packets)
+		log.Printf("client %s: released %d notifications and handles on %s", d.conn.RemoteAddr(), len(packets), u.netID)
+	}
+	log.Printf("client %s disconnected", d.conn.RemoteAddr())
+}
+
+// releaseRequests returns the requests deleting the notifications and releasing
+// the symbol handles a client holds on a target, and forgets them. proxy.mu
+// must be held.
+func (p *proxy) releaseRequests(u *upstream, d *downstream) []*ams.Packet {
+	var packets []*ams.Packet
+	request := func(h holder, r resource, command ads.CommandID, data []byte) {
+		u.invokeID++
+		packets = append(packets, ams.NewRequestPacket(u.netID, r.port, p.netID, h.port, uint16(command), u.invokeID, data))
+	}
+	for r, h := range u.notifications {
+		if h.client == d {
+			delete(u.notifications, r)
+			req := ads.DeleteDeviceNotificationRequest{NotificationHandle: r.handle}
+			data, _ := req.MarshalBinary()
+			request(h, r, ads.CmdDelDeviceNotification, data)
+		}
+	}
+	for r, h := range u.handles {
+		if h.client == d {
+			delete(u.handles, r)
+			release := ads.ReleaseSymbolHandleRequest{Handle: r.handle}
+			handleData, _ := release.MarshalBinary()
+			req := ads.WriteRequest{IndexGroup: ads.IndexGroupReleaseSymbolHandle, Length: 4, Data: handleData}
+			data, _ := req.MarshalBinary()
+			request(h, r, ads.CmdWrite, data)
+		}
+	}
+	return packets
+}
+
+// send writes requests of the proxy itself to a target. Their responses are
+// not routed to any client.
+func (p *proxy) send(u *upstream, packets []*ams.Packet) {
+	u.writeMu.Lock()
+	defer u.writeMu.Unlock()
+	for _, packet := range packets {
+		if err := ams.WritePacket(u.conn, packet); err != nil {
+			log.Printf("send to %s: %v", u.netID, err)
+			return
+		}
+	}
+}
+
+// forward sends a client request to its target with the source address of the
+// proxy and an invoke ID of the target connection.
+func (p *proxy) forward(d *downstream, packet *ams.Packet) error {
+	u, err := p.upstream(packet.Header.TargetNetID)
+	if err != nil {
+		return err
+	}
+
+	ep := endpoint{client: d, netID: packet.Header.SourceNetID, port: packet.Header.SourcePort}
+	p.mu.Lock()
+	port, ok := u.routes[ep]
+	if !ok {
+		port, err = u.allocatePort()
+		if err != nil {
+			p.mu.Unlock()
+			return fmt.Errorf("forward to %s: %w", u.netID, err)
+		}
+		u.routes[ep] = port
+		u.ports[port] = ep
+	}
+	u.invokeID++
+	invokeID := u.invokeID
+	req := pendingRequest{endpoint: ep, invokeID: packet.Header.InvokeID, port: port}
+	if acquires(packet) {
+		req.request = packet
+	}
+	u.pending[invokeID] = req
+	u.forget(packet)
+	p.mu.Unlock()
+
+	packet.Header.SourceNetID = p.netID
+	packet.Header.SourcePort = port
+	packet.Header.InvokeID = invokeID
+
+	u.writeMu.Lock()
+	err = ams.WritePacket(u.conn, packet)
+	u.writeMu.Unlock()
+	if err != nil {
+		// The read loop of the target connection drops it
+		u.conn.Close()
+		p.mu.Lock()
+		delete(u.pending, invokeID)
+		p.mu.Unlock()
+		return fmt.Errorf("forward to %s: %w", u.netID, err)
+	}
+	return nil
+}
+
+// allocatePort returns an unused virtual port. proxy.mu must be held.
+func (u *upstream) allocatePort() (ams.Port, error) {
+	for i := 0; i < 1<<16; i++ {
+		port := u.nextPort
+		u.nextPort++
+		if u.nextPort < firstPort {
+			u.nextPort = firstPort
+		}
+		if _, used := u.ports[port]; !used {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free virtual port")
+}
+
+// acquires reports whether the response to a request may add a notification
+// or return symbol handles.
+func acquires(req *ams.Packet) bool {
+	switch ads.CommandID(req.Header.CommandID) {
+	case ads.CmdAddDeviceNotification:
+		return true
+	case ads.CmdReadWrite:
+		if len(req.Data) < 4 {
+			return false
+		}
+		indexGroup := binary.LittleEndian.Uint32(req.Data[0:4])
+		return indexGroup == ads.IndexGroupSymbolHandleByName || indexGroup == ads.IndexGroupSumCommandReadWrite
+	}
+	return false
+}
+
+// track records the notification or symbol handles a response acquired for a
+// client. proxy.mu must be held.
+func (u *upstream) track(h holder, req, resp *ams.Packet) {
+	if resp.Header.ErrorCode != 0 {
+		return
+	}
+	port := req.Header.TargetPort
+
+	if ads.CommandID(req.Header.CommandID) == ads.CmdAddDeviceNotification {
+		var addResp ads.AddDeviceNotificationResponse
+		if addResp.UnmarshalBinary(resp.Data) == nil && addResp.Result == 0 {
+			u.notifications[resource{port, addResp.NotificationHandle}] = h
+		}
+		return
+	}
+
+	var rwReq ads.ReadWriteRequest
+	var rwResp ads.ReadWriteResponse
+	if rwReq.UnmarshalBinary(req.Data) != nil || rwResp.UnmarshalBinary(resp.Data) != nil || rwResp.Result != 0 {
+		return
+	}
+	if rwReq.IndexGroup == ads.IndexGroupSymbolHandleByName {
+		if len(rwResp.Data) >= 4 {
+			u.handles[resource{port, binary.LittleEndian.Uint32(rwResp.Data)}] = h
+		}
+		return
+	}
+
+	// Sum read/write: the handles of the successful 0xF003 sub-commands
+	count := int(rwReq.IndexOffset)
+	if count > len(rwReq.Data)/16 {
+		return
+	}
+	sumReq := ads.SumReadWriteRequest{Items: make([]ads.SumReadWriteItem, count)}
+	sumResp := ads.SumReadWriteResponse{Count: count}
+	if sumReq.UnmarshalBinary(rwReq.Data) != nil || sumResp.UnmarshalBinary(rwResp.Data) != nil {
+		return
+	}
+	for i, item := range sumReq.Items {
+		if item.IndexGroup == ads.IndexGroupSymbolHandleByName && sumResp.Results[i] == 0 && len(sumResp.Data[i]) >= 4 {
+			u.handles[resource{port, binary.LittleEndian.Uint32(sumResp.Data[i])}] = h
+		}
+	}
+}
+
+// forget drops the notifications and symbol handles a request deletes or
+// releases. proxy.mu must be held.
+func (u *upstream) forget(req *ams.Packet) {
+	port := req.Header.TargetPort
+
+	switch ads.CommandID(req.Header.CommandID) {
+	case ads.CmdDelDeviceNotification:
+		var delReq ads.DeleteDeviceNotificationRequest
+		if delReq.UnmarshalBinary(req.Data) == nil {
+			delete(u.notifications, resource{port, delReq.NotificationHandle})
+		}
+
+	case ads.CmdWrite:
+		var writeReq ads.WriteRequest
+		if writeReq.UnmarshalBinary(req.Data) == nil && writeReq.IndexGroup == ads.IndexGroupReleaseSymbolHandle && len(writeReq.Data) >= 4 {
+			delete(u.handles, resource{port, binary.LittleEndian.Uint32(writeReq.Data)})
+		}
+
+	case ads.CmdReadWrite:
+		// Sum write of 0xF006 sub-commands
+		var rwReq ads.ReadWriteRequest
+		if rwReq.UnmarshalBinary(req.Data) != nil || rwReq.IndexGroup != ads.IndexGroupSumCommandWrite {
+			return
+		}
+		count := int(rwReq.IndexOffset)
+		if count > len(rwReq.Data)/12 {
+			return
+		}
+		sumReq := ads.SumWriteRequest{Items: make([]ads.SumWriteItem, count)}
+		if sumReq.UnmarshalBinary(rwReq.Data) != nil {
+			return
+		}
+		for _, item := range sumReq.Items {
+			if item.IndexGroup == ads.IndexGroupReleaseSymbolHandle && len(item.Data) >= 4 {
+				delete(u.handles, resource{port, binary.LittleEndian.Uint32(item.Data)})
+			}
+		}
+	}
+}
+
+// upstream returns the connection to a target, dialing it if needed.
+func (p *proxy) upstream(netID ams.NetID) (*upstream, error) {
+	address, ok := p.targets[netID]
+	if !ok {
+		return nil, fmt.Errorf("no route to %s", netID)
+	}
+
+	p.dialMu.Lock()
+	defer p.dialMu.Unlock()
+
+	p.mu.Lock()
+	u, ok := p.upstreams[netID]
+	p.mu.Unlock()
+	if ok {
+		return u, nil
+	}
+
+	dialer := &net.Dialer{Timeout: p.timeout, KeepAlive: 30 * time.Second}
+	conn, err := dialer.Dial("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("connect %s at %s: %w", netID, address, err)
+	}
+
+	u = &upstream{
+		netID:         netID,
+		conn:          conn,
+		pending:       make(map[uint32]pendingRequest),
+		ports:         make(map[ams.Port]endpoint),
+		routes:        make(map[endpoint]ams.Port),
+		nextPort:      firstPort,
+		notifications: make(map[resource]holder),
+		handles:       make(map[resource]holder),
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		conn.Close()
+		return nil, fmt.Errorf("proxy closed")
+	}
+	p.upstreams[netID] = u
+	p.mu.Unlock()
+
+	log.Printf("connected to %s at %s", netID, address)
+	p.wg.Add(1)
+	go p.runUpstream(u)
+	return u, nil
+}
+
+// runUpstream passes responses and notifications of a target back to the
+// clients until the connection is lost.
+func (p *proxy) runUpstream(u *upstream) {
+	defer p.wg.Done()
+
+	for {
+		packet, err := ams.ReadPacket(u.conn)
+		if err != nil {
+			log.Printf("connection to %s lost: %v", u.netID, err)
+			break
+		}
+
+		p.mu.Lock()
+		var ep endpoint
+		var ok bool
+		var releases []*ams.Packet
+		if packet.Header.IsRequest() {
+			// Notifications and other requests of the target address a
+			// virtual port
+			ep, ok = u.ports[packet.Header.TargetPort]
+		} else {
+			var req pendingRequest
+			req, ok = u.pending[packet.Header.InvokeID]
+			if ok {
+				delete(u.pending, packet.Header.InvokeID)
+				ep = req.endpoint
+				packet.Header.InvokeID = req.invokeID
+				if req.request != nil {
+					u.track(holder{endpoint: ep, port: req.port}, req.request, packet)
+				}
+				if _, connected := p.clients[ep.client]; !connected {
+					// The client left before the response arrived
+					releases = p.releaseRequests(u, ep.client)
+					ok = false
+				}
+			}
+		}
+		p.mu.Unlock()
+		if len(releases) > 0 {
+			p.send(u, releases)
+		}
+		if !ok {
+			continue
+		}
+
+		packet.Header.TargetNetID = ep.netID
+		packet.Header.TargetPort = ep.port
+		ep.client.write(packet)
+	}
+
+	// Clients reconnect the target with their next request
+	u.conn.Close()
+	p.mu.Lock()
+	if p.upstreams[u.netID] == u {
+		delete(p.upstreams, u.netID)
+	}
+	p.mu.Unlock()
+}
+
+// errorResponse returns the response to a request that could not be
+// forwarded.
+func errorResponse(req *ams.Packet, code ads.Error) *ams.Packet {
+	return &ams.Packet{
+		TCPHeader: ams.TCPHeader{Length: 32},
+		Header: ams.Header{
+			TargetNetID: req.Header.SourceNetID,
+			TargetPort:  req.Header.SourcePort,
+			SourceNetID: req.Header.TargetNetID,
+			SourcePort:  req.Header.TargetPort,
+			CommandID:   req.Header.CommandID,
+			StateFlags:  ams.StateFlagsTCPResponse,
+			ErrorCode:   uint32(code),
+			InvokeID:    req.Header.InvokeID,
+		},
+	}
+}
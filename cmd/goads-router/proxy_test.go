@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc"
+	"github.com/mrpasztoradam/goadstc/adssim"
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func startPLC(t *testing.T, name string, netID ams.NetID) *adssim.Server {
+	t.Helper()
+	server, err := adssim.StartServer("127.0.0.1:0", adssim.Config{
+		NetID:      netID,
+		DeviceName: name,
+		Symbols:    []adssim.Symbol{{Name: "MAIN.counter", Type: "DINT"}},
+	})
+	if err != nil {
+		t.Fatalf("StartServer() error = %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func dialProxy(t *testing.T, p *proxy, target ams.NetID) *goadstc.Client {
+	t.Helper()
+	client, err := goadstc.New(
+		goadstc.WithTarget(p.Addr()),
+		goadstc.WithAMSNetID(target),
+		goadstc.WithTimeout(2*time.Second),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestProxyForwardsByNetID(t *testing.T) {
+	plc1 := startPLC(t, "PLC1", ams.NetID{10, 0, 0, 1, 1, 1})
+	plc2 := startPLC(t, "PLC2", ams.NetID{10, 0, 0, 2, 1, 1})
+
+	p, err := listen("127.0.0.1:0", ams.NetID{10, 0, 0, 100, 1, 1}, map[ams.NetID]string{
+		plc1.NetID(): plc1.Addr(),
+		plc2.NetID(): plc2.Addr(),
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+	defer p.Close()
+	ctx := context.Background()
+
+	for name, plc := range map[string]*adssim.Server{"PLC1": plc1, "PLC2": plc2} {
+		info, err := dialProxy(t, p, plc.NetID()).ReadDeviceInfo(ctx)
+		if err != nil {
+			t.Fatalf("ReadDeviceInfo() via proxy error = %v", err)
+		}
+		if info.Name != name {
+			t.Errorf("request for %s answered by %s", name, info.Name)
+		}
+	}
+
+	// Requests for unknown NetIDs are rejected by the proxy
+	_, err = goadstc.New(
+		goadstc.WithTarget(p.Addr()),
+		goadstc.WithAMSNetID(ams.NetID{10, 0, 0, 3, 1, 1}),
+		goadstc.WithTimeout(time.Second),
+	)
+	if !errors.Is(err, ads.ErrTargetMachineNotFound) {
+		t.Errorf("New() for unknown target error = %v, want target machine not found", err)
+	}
+}
+
+func TestProxySharesTargetConnection(t *testing.T) {
+	plc := startPLC(t, "PLC", ams.NetID{10, 0, 0, 1, 1, 1})
+	p, err := listen("127.0.0.1:0", ams.NetID{10, 0, 0, 100, 1, 1}, map[ams.NetID]string{
+		plc.NetID(): plc.Addr(),
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+	defer p.Close()
+	ctx := context.Background()
+
+	// Both clients use the same default source address; the proxy keeps them
+	// apart with virtual ports
+	clients := []*goadstc.Client{dialProxy(t, p, plc.NetID()), dialProxy(t, p, plc.NetID())}
+	if n := plc.Connections(); n != 1 {
+		t.Errorf("PLC has %d connections, want 1", n)
+	}
+
+	subs := make([]*goadstc.Subscription, len(clients))
+	for i, client := range clients {
+		subs[i], err = client.Subscribe(ctx, goadstc.NotificationOptions{
+			IndexGroup:       0x4020,
+			Length:           4,
+			TransmissionMode: ads.TransModeOnChange,
+		})
+		if err != nil {
+			t.Fatalf("Subscribe() error = %v", err)
+		}
+	}
+	next := func(sub *goadstc.Subscription) []byte {
+		t.Helper()
+		select {
+		case notif := <-sub.Notifications():
+			return notif.Data
+		case <-time.After(2 * time.Second):
+			t.Fatal("no notification received")
+			return nil
+		}
+	}
+	for _, sub := range subs {
+		next(sub) // Initial sample
+	}
+	if err := plc.WriteSymbol("MAIN.counter", []byte{7, 0, 0, 0}); err != nil {
+		t.Fatalf("WriteSymbol() error = %v", err)
+	}
+	for i, sub := range subs {
+		if data := next(sub); data[0] != 7 {
+			t.Errorf("client %d sample = %v, want 7", i, data)
+		}
+	}
+
+	// Concurrent requests get their own responses back
+	done := make(chan error, len(clients)*20)
+	for _, client := range clients {
+		for i := 0; i < 20; i++ {
+			go func() {
+				_, err := client.Read(ctx, 0x4020, 0, 4)
+				done <- err
+			}()
+		}
+	}
+	for i := 0; i < cap(done); i++ {
+		if err := <-done; err != nil {
+			t.Errorf("Read() via proxy error = %v", err)
+		}
+	}
+}
+
+func TestProxyReleasesResourcesOfLostClient(t *testing.T) {
+	plc := startPLC(t, "PLC", ams.NetID{10, 0, 0, 1, 1, 1})
+	p, err := listen("127.0.0.1:0", ams.NetID{10, 0, 0, 100, 1, 1}, map[ams.NetID]string{
+		plc.NetID(): plc.Addr(),
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+	defer p.Close()
+	ctx := context.Background()
+
+	client := dialProxy(t, p, plc.NetID())
+	if _, err := client.Subscribe(ctx, goadstc.NotificationOptions{
+		IndexGroup:       0x4020,
+		Length:           4,
+		TransmissionMode: ads.TransModeOnChange,
+	}); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if _, err := client.GetSymbolHandle(ctx, "MAIN.counter"); err != nil {
+		t.Fatalf("GetSymbolHandle() error = %v", err)
+	}
+	handles, err := client.GetSymbolHandles(ctx, "MAIN.counter", "MAIN.counter", "MAIN.missing")
+	if err != nil {
+		t.Fatalf("GetSymbolHandles() error = %v", err)
+	}
+	// A released handle is not released again
+	if err := client.ReleaseSymbolHandles(ctx, handles[0].Handle); err != nil {
+		t.Fatalf("ReleaseSymbolHandles() error = %v", err)
+	}
+	if n, m := plc.Notifications(), plc.Handles(); n != 1 || m != 2 {
+		t.Fatalf("PLC has %d notifications and %d handles, want 1 and 2", n, m)
+	}
+
+	// The client goes away without cleaning up
+	p.mu.Lock()
+	for d := range p.clients {
+		d.conn.Close()
+	}
+	p.mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for plc.Notifications() != 0 || plc.Handles() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("PLC still has %d notifications and %d handles of the lost client", plc.Notifications(), plc.Handles())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Other clients are not affected
+	if _, err := dialProxy(t, p, plc.NetID()).ReadState(ctx); err != nil {
+		t.Errorf("ReadState() via proxy error = %v", err)
+	}
+}
+
+func TestUpstreamAllocatePort(t *testing.T) {
+	u := &upstream{ports: make(map[ams.Port]endpoint), nextPort: firstPort}
+	for port := firstPort; port != 0; port++ {
+		if _, err := u.allocatePort(); err != nil {
+			t.Fatalf("allocatePort() for port %d error = %v", port, err)
+		}
+		u.ports[port] = endpoint{}
+	}
+	if _, err := u.allocatePort(); err == nil {
+		t.Error("allocatePort() with all ports in use succeeded")
+	}
+}
+
+func TestTargetFlag(t *testing.T) {
+	f := targetFlag{}
+	if err := f.Set("10.0.0.1.1.1=plc1"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := f.Set("10.0.0.2.1.1=plc2:851"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if f[ams.NetID{10, 0, 0, 1, 1, 1}] != "plc1:48898" || f[ams.NetID{10, 0, 0, 2, 1, 1}] != "plc2:851" {
+		t.Errorf("targets = %v", f)
+	}
+	for _, value := range []string{"plc1", "10.0.0.1=plc1", "10.0.0.1.1.1="} {
+		if err := f.Set(value); err == nil {
+			t.Errorf("Set(%q) accepted", value)
+		}
+	}
+}
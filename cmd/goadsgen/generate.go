@@ -0,0 +1,507 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+// schema is the symbol and type information bindings are generated from.
+type schema struct {
+	symbols []*symbols.Symbol
+	types   map[string]symbols.TypeInfo
+}
+
+// generator emits Go source for a schema.
+type generator struct {
+	pkg    string
+	schema schema
+
+	typesByName map[string]symbols.TypeInfo // upper-case PLC type name -> type
+	goTypes     map[string]string           // upper-case PLC type name -> Go type name
+	names       map[string]bool             // identifiers in use at package level
+	decls       map[string]string           // Go type name -> declaration
+	pending     []pendingType
+	imports     map[string]bool
+}
+
+// pendingType is a PLC type that has a Go name but no declaration yet.
+type pendingType struct {
+	goName   string
+	typeInfo symbols.TypeInfo
+}
+
+// boundSymbol is a symbol with the identifiers of its accessors.
+type boundSymbol struct {
+	symbol   *symbols.Symbol
+	ident    string
+	goType   string
+	readOnly bool
+}
+
+func newGenerator(pkg string, s schema) *generator {
+	g := &generator{
+		pkg:         pkg,
+		schema:      s,
+		typesByName: make(map[string]symbols.TypeInfo, len(s.types)),
+		goTypes:     make(map[string]string),
+		names:       make(map[string]bool),
+		decls:       make(map[string]string),
+		imports:     make(map[string]bool),
+	}
+	for name, typeInfo := range s.types {
+		g.typesByName[strings.ToUpper(name)] = typeInfo
+	}
+	return g
+}
+
+// generate returns the formatted Go source for all symbols of the schema.
+func (g *generator) generate() ([]byte, error) {
+	syms := append([]*symbols.Symbol(nil), g.schema.symbols...)
+	sort.Slice(syms, func(i, j int) bool { return syms[i].Name < syms[j].Name })
+
+	bound := make([]boundSymbol, 0, len(syms))
+	for _, sym := range syms {
+		ident := g.reserve(exportedName(sym.Name))
+		g.names["Read"+ident] = true
+		g.names["Write"+ident] = true
+		g.names["Symbol"+ident] = true
+		bound = append(bound, boundSymbol{
+			symbol:   sym,
+			ident:    ident,
+			goType:   g.symbolType(sym),
+			readOnly: sym.Flags&symbols.SymbolFlagReadOnly != 0,
+		})
+	}
+
+	if len(bound) > 0 {
+		g.imports["context"] = true
+		g.imports["github.com/mrpasztoradam/goadstc"] = true
+	}
+
+	// Declaring a type can discover further types through its members
+	for len(g.pending) > 0 {
+		next := g.pending[0]
+		g.pending = g.pending[1:]
+		g.decls[next.goName] = g.declare(next)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by goadsgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", g.pkg)
+
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	if len(imports) > 0 {
+		buf.WriteString("import (\n")
+		for _, path := range imports {
+			if !strings.Contains(path, ".") {
+				fmt.Fprintf(&buf, "\t%q\n", path)
+			}
+		}
+		buf.WriteString("\n")
+		for _, path := range imports {
+			if strings.Contains(path, ".") {
+				fmt.Fprintf(&buf, "\t%q\n", path)
+			}
+		}
+		buf.WriteString(")\n\n")
+	}
+
+	if len(bound) > 0 {
+		buf.WriteString("// PLC symbol names.\nconst (\n")
+		for _, b := range bound {
+			fmt.Fprintf(&buf, "\tSymbol%s = %q\n", b.ident, b.symbol.Name)
+		}
+		buf.WriteString(")\n\n")
+	}
+
+	typeNames := make([]string, 0, len(g.decls))
+	for name := range g.decls {
+		typeNames = append(typeNames, name)
+	}
+	sort.Strings(typeNames)
+	for _, name := range typeNames {
+		buf.WriteString(g.decls[name])
+		buf.WriteString("\n")
+	}
+
+	for _, b := range bound {
+		writeAccessors(&buf, b)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return src, nil
+}
+
+// writeAccessors emits the typed read and write functions of a symbol.
+func writeAccessors(buf *bytes.Buffer, b boundSymbol) {
+	plcType := b.symbol.Type.Name
+	fmt.Fprintf(buf, "// Read%s reads %s (%s).\n", b.ident, b.symbol.Name, plcType)
+	fmt.Fprintf(buf, "func Read%s(ctx context.Context, c *goadstc.Client) (%s, error) {\n", b.ident, b.goType)
+	fmt.Fprintf(buf, "\tvar v %s\n", b.goType)
+	fmt.Fprintf(buf, "\terr := c.ReadInto(ctx, Symbol%s, &v)\n", b.ident)
+	buf.WriteString("\treturn v, err\n}\n\n")
+
+	if b.readOnly {
+		return
+	}
+	fmt.Fprintf(buf, "// Write%s writes %s (%s).\n", b.ident, b.symbol.Name, plcType)
+	fmt.Fprintf(buf, "func Write%s(ctx context.Context, c *goadstc.Client, v %s) error {\n", b.ident, b.goType)
+	fmt.Fprintf(buf, "\treturn c.WriteFrom(ctx, Symbol%s, v)\n}\n\n", b.ident)
+}
+
+const referencePrefix = "REFERENCE TO "
+
+// symbolType returns the Go type of a symbol. References are followed by the
+// client, so a REFERENCE TO symbol maps to its pointee.
+func (g *generator) symbolType(sym *symbols.Symbol) string {
+	if sym.BitSize > 0 {
+		return bitFieldType(uint8(sym.BitSize))
+	}
+	typeInfo := sym.Type
+	if strings.HasPrefix(strings.ToUpper(typeInfo.Name), referencePrefix) {
+		typeInfo = g.resolve(strings.TrimSpace(typeInfo.Name[len(referencePrefix):]), 0)
+	}
+	return g.goType(typeInfo)
+}
+
+// resolve returns the full type information for a type name. Unknown types
+// keep the name and size only.
+func (g *generator) resolve(typeName string, size uint32) symbols.TypeInfo {
+	if typeInfo, ok := g.typesByName[strings.ToUpper(typeName)]; ok {
+		return typeInfo
+	}
+	if strings.Contains(strings.ToUpper(typeName), "ARRAY") {
+		return symbols.ParseTypeInfo(typeName, symbols.DataTypeBigType, size)
+	}
+	return symbols.TypeInfo{Name: typeName, Size: size}
+}
+
+// goType maps PLC type information to a Go type expression, queuing struct
+// and enum types for declaration.
+func (g *generator) goType(typeInfo symbols.TypeInfo) string {
+	name := strings.TrimSpace(typeInfo.Name)
+	upper := strings.ToUpper(name)
+
+	if !typeInfo.IsArray && len(typeInfo.Fields) == 0 && len(typeInfo.EnumValues) == 0 {
+		if full, ok := g.typesByName[upper]; ok {
+			typeInfo = full
+		}
+	}
+
+	switch {
+	case typeInfo.IsArray:
+		return g.arrayType(typeInfo)
+	case strings.HasPrefix(upper, "POINTER TO "), strings.HasPrefix(upper, referencePrefix):
+		// Pointer values are addresses in the PLC and only meaningful as raw data
+		return rawType(typeInfo.Size)
+	case strings.HasPrefix(upper, "STRING"), strings.HasPrefix(upper, "WSTRING"):
+		return "string"
+	}
+
+	switch upper {
+	case "TIME", "LTIME", "TOD", "TIME_OF_DAY":
+		g.imports["time"] = true
+		return "time.Duration"
+	case "DATE", "DT", "DATE_AND_TIME":
+		g.imports["time"] = true
+		return "time.Time"
+	}
+	if goName, ok := simpleTypes[upper]; ok {
+		return goName
+	}
+
+	switch {
+	case len(typeInfo.EnumValues) > 0, len(typeInfo.Fields) > 0:
+		return g.namedType(typeInfo)
+	}
+	if goName := baseGoType(typeInfo.BaseType); goName != "" {
+		return goName
+	}
+	return rawType(typeInfo.Size)
+}
+
+// arrayType maps a PLC array to nested Go arrays in row-major order.
+func (g *generator) arrayType(typeInfo symbols.TypeInfo) string {
+	dims := typeInfo.ArrayInfo
+	if len(dims) == 0 {
+		for _, elements := range typeInfo.ArrayDims {
+			dims = append(dims, symbols.ArrayDimension{Elements: elements})
+		}
+	}
+
+	total := uint32(1)
+	for _, dim := range dims {
+		total *= dim.Elements
+	}
+	if len(dims) == 0 || total == 0 || typeInfo.Size%total != 0 {
+		return rawType(typeInfo.Size)
+	}
+
+	elementName := typeInfo.ElementType
+	if elementName == "" {
+		elementName = symbols.ParseTypeInfo(typeInfo.Name, typeInfo.BaseType, typeInfo.Size).ElementType
+	}
+	element := g.resolve(elementName, typeInfo.Size/total)
+	if element.Size == 0 {
+		element.Size = typeInfo.Size / total
+	}
+	if element.BaseType == 0 && !isSequenceName(elementName) {
+		element.BaseType = typeInfo.BaseType
+	}
+
+	var b strings.Builder
+	for _, dim := range dims {
+		fmt.Fprintf(&b, "[%d]", dim.Elements)
+	}
+	b.WriteString(g.goType(element))
+	return b.String()
+}
+
+// namedType returns the Go name of a struct or enum type, queuing its declaration.
+func (g *generator) namedType(typeInfo symbols.TypeInfo) string {
+	key := strings.ToUpper(typeInfo.Name)
+	if goName, ok := g.goTypes[key]; ok {
+		return goName
+	}
+	goName := g.reserve(exportedName(typeInfo.Name))
+	g.goTypes[key] = goName
+	g.pending = append(g.pending, pendingType{goName: goName, typeInfo: typeInfo})
+	return goName
+}
+
+// declare returns the declaration of a queued type.
+func (g *generator) declare(p pendingType) string {
+	if len(p.typeInfo.EnumValues) > 0 {
+		return g.declareEnum(p)
+	}
+	return g.declareStruct(p)
+}
+
+func (g *generator) declareStruct(p pendingType) string {
+	var buf bytes.Buffer
+	writeTypeDoc(&buf, p.goName, p.typeInfo)
+	fmt.Fprintf(&buf, "type %s struct {\n", p.goName)
+
+	fieldNames := make(map[string]bool, len(p.typeInfo.Fields))
+	for _, field := range p.typeInfo.Fields {
+		if field.Name == "" {
+			continue
+		}
+		goName := uniqueName(exportedName(field.Name), fieldNames)
+
+		var goType string
+		if field.BitSize > 0 {
+			goType = bitFieldType(field.BitSize)
+		} else {
+			goType = g.goType(field.Type)
+		}
+		fmt.Fprintf(&buf, "\t%s %s `ads:%q` // %s\n", goName, goType, field.Name, fieldComment(field))
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+func (g *generator) declareEnum(p pendingType) string {
+	underlying := baseGoType(p.typeInfo.BaseType)
+	if underlying == "" || underlying == "bool" || strings.HasPrefix(underlying, "float") {
+		underlying = signedType(p.typeInfo.Size)
+	}
+	g.imports["fmt"] = true
+
+	var buf bytes.Buffer
+	writeTypeDoc(&buf, p.goName, p.typeInfo)
+	fmt.Fprintf(&buf, "type %s %s\n\n", p.goName, underlying)
+
+	constNames := make([]string, len(p.typeInfo.EnumValues))
+	fmt.Fprintf(&buf, "// %s values.\nconst (\n", p.goName)
+	for i, value := range p.typeInfo.EnumValues {
+		constNames[i] = g.reserve(p.goName + exportedName(value.Name))
+		fmt.Fprintf(&buf, "\t%s %s = %d\n", constNames[i], p.goName, value.Value)
+	}
+	buf.WriteString(")\n\n")
+
+	fmt.Fprintf(&buf, "// String returns the PLC name of the value.\n")
+	fmt.Fprintf(&buf, "func (v %s) String() string {\n\tswitch v {\n", p.goName)
+	seen := make(map[int64]bool, len(p.typeInfo.EnumValues))
+	for i, value := range p.typeInfo.EnumValues {
+		if seen[value.Value] {
+			continue
+		}
+		seen[value.Value] = true
+		fmt.Fprintf(&buf, "\tcase %s:\n\t\treturn %q\n", constNames[i], value.Name)
+	}
+	fmt.Fprintf(&buf, "\t}\n\treturn fmt.Sprintf(\"%s(%%d)\", int64(v))\n}\n", p.goName)
+	return buf.String()
+}
+
+// writeTypeDoc writes the doc comment of a generated type.
+func writeTypeDoc(buf *bytes.Buffer, goName string, typeInfo symbols.TypeInfo) {
+	fmt.Fprintf(buf, "// %s mirrors the PLC type %s (%d bytes).\n", goName, typeInfo.Name, typeInfo.Size)
+	if comment := strings.TrimSpace(typeInfo.Comment); comment != "" {
+		buf.WriteString("//\n")
+		for _, line := range strings.Split(comment, "\n") {
+			fmt.Fprintf(buf, "// %s\n", strings.TrimSpace(line))
+		}
+	}
+}
+
+// fieldComment describes a struct member for the trailing field comment.
+func fieldComment(field symbols.FieldInfo) string {
+	comment := field.Type.Name
+	if comment == "" {
+		comment = fmt.Sprintf("%d bytes", field.Type.Size)
+	}
+	if field.BitSize > 0 {
+		comment = fmt.Sprintf("%s, bit %d of byte %d", comment, field.BitOffset, field.Offset)
+	}
+	if c := strings.Join(strings.Fields(field.Comment), " "); c != "" {
+		comment += ": " + c
+	}
+	return comment
+}
+
+// reserve returns name, or name with a numeric suffix if it is already taken.
+func (g *generator) reserve(name string) string {
+	return uniqueName(name, g.names)
+}
+
+func uniqueName(name string, taken map[string]bool) string {
+	unique := name
+	for i := 2; taken[unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	taken[unique] = true
+	return unique
+}
+
+// exportedName turns a PLC identifier or symbol path into an exported Go
+// identifier: separators become underscores and the first letter is upper case.
+func exportedName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	ident := b.String()
+
+	first, size := utf8.DecodeRuneInString(ident)
+	switch {
+	case ident == "":
+		return "X"
+	case unicode.IsUpper(first):
+		return ident
+	case unicode.IsLower(first):
+		return string(unicode.ToUpper(first)) + ident[size:]
+	default:
+		return "X" + ident
+	}
+}
+
+// simpleTypes maps elementary PLC types to Go types.
+var simpleTypes = map[string]string{
+	"BOOL":  "bool",
+	"BIT":   "bool",
+	"SINT":  "int8",
+	"USINT": "uint8",
+	"BYTE":  "uint8",
+	"INT":   "int16",
+	"UINT":  "uint16",
+	"WORD":  "uint16",
+	"DINT":  "int32",
+	"UDINT": "uint32",
+	"DWORD": "uint32",
+	"LINT":  "int64",
+	"ULINT": "uint64",
+	"LWORD": "uint64",
+	"REAL":  "float32",
+	"LREAL": "float64",
+}
+
+// baseGoType maps an ADS data type ID to a Go type, or "" if there is none.
+func baseGoType(dataType symbols.DataType) string {
+	switch dataType {
+	case symbols.DataTypeBool, symbols.DataTypeBit:
+		return "bool"
+	case symbols.DataTypeInt8:
+		return "int8"
+	case symbols.DataTypeUInt8:
+		return "uint8"
+	case symbols.DataTypeInt16:
+		return "int16"
+	case symbols.DataTypeUInt16:
+		return "uint16"
+	case symbols.DataTypeInt32:
+		return "int32"
+	case symbols.DataTypeUInt32:
+		return "uint32"
+	case symbols.DataTypeInt64:
+		return "int64"
+	case symbols.DataTypeUInt64:
+		return "uint64"
+	case symbols.DataTypeReal32:
+		return "float32"
+	case symbols.DataTypeReal64:
+		return "float64"
+	case symbols.DataTypeString, symbols.DataTypeWString:
+		return "string"
+	}
+	return ""
+}
+
+// bitFieldType returns the Go type of a BIT member or bit-valued symbol.
+func bitFieldType(bitSize uint8) string {
+	switch {
+	case bitSize <= 1:
+		return "bool"
+	case bitSize <= 8:
+		return "uint8"
+	case bitSize <= 16:
+		return "uint16"
+	case bitSize <= 32:
+		return "uint32"
+	default:
+		return "uint64"
+	}
+}
+
+// signedType returns the signed Go integer type of the given size.
+func signedType(size uint32) string {
+	switch size {
+	case 1:
+		return "int8"
+	case 2:
+		return "int16"
+	case 8:
+		return "int64"
+	default:
+		return "int32"
+	}
+}
+
+func rawType(size uint32) string {
+	return fmt.Sprintf("[%d]byte", size)
+}
+
+// isSequenceName reports whether a type name is an array or string type whose
+// data type ID does not describe its elements.
+func isSequenceName(typeName string) bool {
+	upper := strings.ToUpper(typeName)
+	return strings.Contains(upper, "ARRAY") || strings.HasPrefix(upper, "STRING") || strings.HasPrefix(upper, "WSTRING")
+}
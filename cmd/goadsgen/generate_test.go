@@ -0,0 +1,120 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+func TestGenerate(t *testing.T) {
+	state := symbols.TypeInfo{
+		Name: "E_State", BaseType: symbols.DataTypeInt16, Size: 2,
+		EnumValues: []symbols.EnumValue{{Name: "Idle", Value: 0}, {Name: "Error", Value: -1}},
+	}
+	axis := symbols.TypeInfo{
+		Name: "ST_Axis", BaseType: symbols.DataTypeBigType, Size: 32, IsStruct: true,
+		Fields: []symbols.FieldInfo{
+			{Name: "bEnabled", Offset: 0, Type: symbols.TypeInfo{Name: "BIT", BaseType: symbols.DataTypeBit, Size: 1}, BitSize: 1},
+			{Name: "eState", Offset: 2, Type: state},
+			{Name: "fPosition", Offset: 8, Type: symbols.TypeInfo{Name: "LREAL", BaseType: symbols.DataTypeReal64, Size: 8}},
+			{Name: "tCycle", Offset: 16, Type: symbols.TypeInfo{Name: "TIME", BaseType: symbols.DataTypeUInt32, Size: 4}},
+			{Name: "aLimits", Offset: 20, Type: symbols.ParseTypeInfo("ARRAY [1..2, 0..2] OF WORD", symbols.DataTypeUInt16, 12)},
+		},
+	}
+
+	s := schema{
+		symbols: []*symbols.Symbol{
+			{Name: "MAIN.stAxis", Type: symbols.TypeInfo{Name: "ST_Axis", BaseType: symbols.DataTypeBigType, Size: 32}},
+			{Name: "MAIN.aAxes", Type: symbols.ParseTypeInfo("ARRAY [0..3] OF ST_Axis", symbols.DataTypeBigType, 128)},
+			{Name: "GVL.sName", Type: symbols.TypeInfo{Name: "STRING(80)", BaseType: symbols.DataTypeString, Size: 81},
+				Flags: symbols.SymbolFlagReadOnly},
+		},
+		types: map[string]symbols.TypeInfo{"ST_Axis": axis, "E_State": state},
+	}
+
+	src, err := newGenerator("plc", s).generate()
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	code := string(src)
+	typeCheck(t, src)
+
+	for _, want := range []string{
+		`SymbolMAIN_stAxis = "MAIN.stAxis"`,
+		"type ST_Axis struct {",
+		"BEnabled  bool ",
+		"EState    E_State ",
+		"ALimits   [2][3]uint16 ",
+		"TCycle    time.Duration ",
+		"type E_State int16",
+		"E_StateError E_State = -1",
+		"func ReadMAIN_stAxis(ctx context.Context, c *goadstc.Client) (ST_Axis, error)",
+		"func WriteMAIN_aAxes(ctx context.Context, c *goadstc.Client, v [4]ST_Axis) error",
+		"func ReadGVL_sName(ctx context.Context, c *goadstc.Client) (string, error)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code is missing %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "func WriteGVL_sName") {
+		t.Error("generated a write accessor for a read-only symbol")
+	}
+	if strings.Count(code, "type ST_Axis struct") != 1 {
+		t.Error("ST_Axis declared more than once")
+	}
+}
+
+func TestGenerateCompilesUnusualNames(t *testing.T) {
+	mode := symbols.TypeInfo{
+		Name: "E_Mode", BaseType: symbols.DataTypeUInt8, Size: 1,
+		EnumValues: []symbols.EnumValue{{Name: "auto", Value: 1}, {Name: "Auto", Value: 2}, {Name: "1st", Value: 3}},
+	}
+	vendor := symbols.TypeInfo{
+		Name: "3rd_Party", BaseType: symbols.DataTypeBigType, Size: 8, IsStruct: true,
+		Fields: []symbols.FieldInfo{
+			{Name: "mode", Offset: 0, Type: mode},
+			{Name: "Mode", Offset: 1, Type: mode},
+			{Name: "nBits", Offset: 2, Type: symbols.TypeInfo{Name: "BIT", BaseType: symbols.DataTypeBit, Size: 1}, BitSize: 3},
+			{Name: "pNext", Offset: 4, Type: symbols.TypeInfo{Name: "POINTER TO 3rd_Party", Size: 4}},
+		},
+	}
+
+	s := schema{
+		symbols: []*symbols.Symbol{
+			{Name: "MAIN.a_b", Type: symbols.TypeInfo{Name: "DINT", BaseType: symbols.DataTypeInt32, Size: 4}},
+			{Name: "MAIN.a.b", Type: symbols.TypeInfo{Name: "DINT", BaseType: symbols.DataTypeInt32, Size: 4}},
+			{Name: "MAIN.vendor", Type: symbols.TypeInfo{Name: "3rd_Party", BaseType: symbols.DataTypeBigType, Size: 8}},
+			{Name: "MAIN.refVendor", Type: symbols.TypeInfo{Name: "REFERENCE TO 3rd_Party", Size: 8}},
+			{Name: "MAIN.unknown", Type: symbols.TypeInfo{Name: "FB_Unknown", BaseType: symbols.DataTypeBigType, Size: 24}},
+			{Name: "MAIN.modes", Type: symbols.ParseTypeInfo("ARRAY [-1..1] OF E_Mode", symbols.DataTypeUInt8, 3)},
+			{Name: "MAIN.when", Type: symbols.TypeInfo{Name: "DT", BaseType: symbols.DataTypeUInt32, Size: 4}},
+		},
+		types: map[string]symbols.TypeInfo{"3rd_Party": vendor, "E_Mode": mode},
+	}
+
+	src, err := newGenerator("plc", s).generate()
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	typeCheck(t, src)
+}
+
+// typeCheck parses and type-checks generated code against the goadstc package.
+func typeCheck(t *testing.T, src []byte) {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "plc.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, src)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("plc", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("generated code does not type-check: %v\n%s", err, src)
+	}
+}
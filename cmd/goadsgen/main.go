@@ -0,0 +1,226 @@
+// Command goadsgen generates typed Go bindings from the symbol and data type
+// tables of a TwinCAT PLC.
+//
+// For every symbol it emits a name constant and Read/Write functions; struct
+// types become Go structs with `ads` tags for Client.ReadInto and
+// Client.WriteFrom, and enums become named integer types with constants.
+// Regenerating after a PLC change turns renamed or removed symbols and members
+// into compile errors.
+//
+// Generate from a running PLC:
+//
+//	goadsgen -target 192.168.1.100:48898 -netid 192.168.1.100.1.1 -source-netid 192.168.1.10.1.1 \
+//	    -filter MAIN.,GVL. -pkg plc -o plc/plc_gen.go
+//
+// The raw uploads can be saved with -save-symbols and -save-types and used
+// later without a connection, e.g. from go:generate:
+//
+//	//go:generate go run github.com/mrpasztoradam/goadstc/cmd/goadsgen -symbols plc.sym -types plc.types -pkg plc -o plc_gen.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc"
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+func main() {
+	target := flag.String("target", "", "PLC address (host:port)")
+	netID := flag.String("netid", "", "PLC AMS NetID")
+	port := flag.Uint("port", 851, "PLC AMS port")
+	sourceNetID := flag.String("source-netid", "", "Local AMS NetID")
+	timeout := flag.Duration("timeout", 10*time.Second, "Connection and request timeout")
+	symbolFile := flag.String("symbols", "", "Read the symbol table from an upload file instead of the PLC")
+	typeFile := flag.String("types", "", "Read the data type table from an upload file instead of the PLC")
+	saveSymbols := flag.String("save-symbols", "", "Save the raw symbol table upload to a file")
+	saveTypes := flag.String("save-types", "", "Save the raw data type table upload to a file")
+	filter := flag.String("filter", "", "Comma-separated symbol name substrings to include (case-insensitive)")
+	pkg := flag.String("pkg", "plc", "Package name of the generated code")
+	output := flag.String("o", "", "Output file (default stdout)")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	var s schema
+	var err error
+	if *symbolFile != "" {
+		s, err = loadFiles(*symbolFile, *typeFile)
+	} else {
+		s, err = loadFromPLC(ctx, plcConfig{
+			target:      *target,
+			netID:       *netID,
+			port:        ams.Port(*port),
+			sourceNetID: *sourceNetID,
+			timeout:     *timeout,
+			saveSymbols: *saveSymbols,
+			saveTypes:   *saveTypes,
+		})
+	}
+	if err != nil {
+		log.Fatalf("goadsgen: %v", err)
+	}
+
+	s.symbols = filterSymbols(s.symbols, *filter)
+
+	src, err := newGenerator(*pkg, s).generate()
+	if err != nil {
+		log.Fatalf("goadsgen: %v", err)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		log.Fatalf("goadsgen: %v", err)
+	}
+	log.Printf("goadsgen: wrote %d symbols to %s", len(s.symbols), *output)
+}
+
+// plcConfig holds the connection settings for loading from a PLC.
+type plcConfig struct {
+	target      string
+	netID       string
+	port        ams.Port
+	sourceNetID string
+	timeout     time.Duration
+	saveSymbols string
+	saveTypes   string
+}
+
+// loadFromPLC reads the symbol list and the type registry from a running PLC.
+func loadFromPLC(ctx context.Context, cfg plcConfig) (schema, error) {
+	if cfg.target == "" || cfg.netID == "" {
+		return schema{}, fmt.Errorf("either -symbols or -target and -netid are required")
+	}
+
+	opts := []goadstc.Option{
+		goadstc.WithTarget(cfg.target),
+		goadstc.WithAMSPort(cfg.port),
+		goadstc.WithTimeout(cfg.timeout),
+	}
+	plcNetID, err := ams.ParseNetID(cfg.netID)
+	if err != nil {
+		return schema{}, err
+	}
+	opts = append(opts, goadstc.WithAMSNetID(plcNetID))
+	if cfg.sourceNetID != "" {
+		sourceNetID, err := ams.ParseNetID(cfg.sourceNetID)
+		if err != nil {
+			return schema{}, err
+		}
+		opts = append(opts, goadstc.WithSourceNetID(sourceNetID))
+	}
+
+	client, err := goadstc.New(opts...)
+	if err != nil {
+		return schema{}, fmt.Errorf("connect: %w", err)
+	}
+	defer client.Close()
+
+	syms, err := client.ListSymbols(ctx)
+	if err != nil {
+		return schema{}, fmt.Errorf("list symbols: %w", err)
+	}
+	if err := client.LoadDataTypes(ctx); err != nil {
+		return schema{}, err
+	}
+
+	types := make(map[string]symbols.TypeInfo)
+	for _, name := range client.ListRegisteredTypes() {
+		if typeInfo, ok := client.GetRegisteredType(name); ok {
+			types[name] = typeInfo
+		}
+	}
+
+	if cfg.saveSymbols != "" {
+		if err := saveUpload(cfg.saveSymbols, func() ([]byte, error) { return client.UploadSymbolTable(ctx) }); err != nil {
+			return schema{}, err
+		}
+	}
+	if cfg.saveTypes != "" {
+		if err := saveUpload(cfg.saveTypes, func() ([]byte, error) { return client.UploadDataTypeTable(ctx) }); err != nil {
+			return schema{}, err
+		}
+	}
+
+	return schema{symbols: syms, types: types}, nil
+}
+
+// saveUpload writes a raw table upload to a file.
+func saveUpload(path string, upload func() ([]byte, error)) error {
+	data, err := upload()
+	if err != nil {
+		return fmt.Errorf("upload for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("save upload: %w", err)
+	}
+	return nil
+}
+
+// loadFiles parses raw symbol and data type table uploads. Without a type
+// file, structs and enums are generated as raw byte arrays.
+func loadFiles(symbolFile, typeFile string) (schema, error) {
+	data, err := os.ReadFile(symbolFile)
+	if err != nil {
+		return schema{}, fmt.Errorf("read symbol file: %w", err)
+	}
+	parsed, err := symbols.ParseSymbolTable(data)
+	if err != nil {
+		return schema{}, fmt.Errorf("parse symbol file %s: %w", symbolFile, err)
+	}
+	syms := make([]*symbols.Symbol, len(parsed))
+	for i := range parsed {
+		syms[i] = &parsed[i]
+	}
+
+	types := make(map[string]symbols.TypeInfo)
+	if typeFile != "" {
+		data, err := os.ReadFile(typeFile)
+		if err != nil {
+			return schema{}, fmt.Errorf("read type file: %w", err)
+		}
+		entries, err := symbols.ParseDataTypeTable(data)
+		if err != nil {
+			return schema{}, fmt.Errorf("parse type file %s: %w", typeFile, err)
+		}
+		types = symbols.ResolveDataTypes(entries)
+	}
+
+	return schema{symbols: syms, types: types}, nil
+}
+
+// filterSymbols keeps the symbols whose name contains one of the
+// comma-separated patterns, ignoring case. An empty filter keeps all symbols.
+func filterSymbols(syms []*symbols.Symbol, filter string) []*symbols.Symbol {
+	var patterns []string
+	for _, pattern := range strings.Split(filter, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, strings.ToLower(pattern))
+		}
+	}
+	if len(patterns) == 0 {
+		return syms
+	}
+
+	var matches []*symbols.Symbol
+	for _, sym := range syms {
+		name := strings.ToLower(sym.Name)
+		for _, pattern := range patterns {
+			if strings.Contains(name, pattern) {
+				matches = append(matches, sym)
+				break
+			}
+		}
+	}
+	return matches
+}
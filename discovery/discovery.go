@@ -0,0 +1,334 @@
+// Package discovery finds TwinCAT devices on the network and registers AMS
+// routes on them, using the router's UDP service on port 48899.
+//
+// List the devices answering a broadcast:
+//
+//	devices, err := discovery.Discover(ctx, discovery.Config{})
+//	for _, d := range devices {
+//	    fmt.Println(d.Address, d.NetID, d.Hostname, d.TwinCATVersion, d.OS)
+//	}
+//
+// Add a route for the local system on a PLC, as the TwinCAT "Add Route"
+// dialog does:
+//
+//	err := discovery.AddRoute(ctx, "192.168.1.100", discovery.Route{
+//	    Name:     "edge-gateway",
+//	    NetID:    ams.NetID{192, 168, 1, 10, 1, 1},
+//	    Host:     "192.168.1.10",
+//	    Username: "Administrator",
+//	    Password: "1",
+//	})
+package discovery
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+const (
+	defaultTimeout    = 2 * time.Second
+	retransmitPeriod  = 250 * time.Millisecond
+	maxDatagramSize   = 64 * 1024
+	broadcastAddress  = "255.255.255.255"
+	defaultSenderHost = "0.0.0.0:0"
+)
+
+// invokeIDs numbers the requests of this process.
+var invokeIDs atomic.Uint32
+
+// Device is a TwinCAT system that answered a discovery request.
+type Device struct {
+	Address        string // IP address the response came from
+	NetID          ams.NetID
+	Hostname       string
+	TwinCATVersion Version
+	OS             OSVersion
+	Fingerprint    string // Certificate fingerprint, TwinCAT 4024 and later
+}
+
+// Version is a TwinCAT version such as 3.1.4024.
+type Version struct {
+	Major uint8
+	Minor uint8
+	Build uint16
+}
+
+// String returns the version in dotted form.
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Build)
+}
+
+// OSVersion is the operating system reported by a device.
+type OSVersion struct {
+	Major    uint32
+	Minor    uint32
+	Build    uint32
+	Platform uint32 // 2 for Windows NT based systems
+	Name     string // Service pack or distribution string, may be empty
+}
+
+// String returns a readable description of the operating system.
+func (v OSVersion) String() string {
+	platform := fmt.Sprintf("Platform %d", v.Platform)
+	if v.Platform == 2 {
+		platform = "Windows NT"
+	}
+	s := fmt.Sprintf("%s %d.%d (Build %d)", platform, v.Major, v.Minor, v.Build)
+	if v.Name != "" {
+		s += " " + v.Name
+	}
+	return s
+}
+
+// Config configures a discovery.
+type Config struct {
+	// Addresses to send the request to (host or host:port). Defaults to the
+	// limited broadcast address 255.255.255.255.
+	Addresses []string
+
+	// NetID of the requester, sent in the request. Devices answer regardless.
+	NetID ams.NetID
+
+	// Timeout is how long responses are collected (default 2s). The context
+	// can end the discovery earlier.
+	Timeout time.Duration
+}
+
+// Discover sends a discovery request and collects the devices that answer
+// until the timeout expires. Each device is listed once.
+func Discover(ctx context.Context, cfg Config) ([]Device, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addresses := cfg.Addresses
+	if len(addresses) == 0 {
+		addresses = []string{broadcastAddress}
+	}
+	targets := make([]*net.UDPAddr, 0, len(addresses))
+	for _, address := range addresses {
+		addr, err := resolve(address)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, addr)
+	}
+
+	conn, err := net.ListenPacket("udp4", defaultSenderHost)
+	if err != nil {
+		return nil, fmt.Errorf("discovery: listen: %w", err)
+	}
+	defer conn.Close()
+	stopOnDone(ctx, conn)
+
+	req := message{invokeID: invokeIDs.Add(1), service: serviceDiscovery, netID: cfg.NetID, port: senderPort}
+	data, err := req.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	for _, target := range targets {
+		if _, err := conn.WriteTo(data, target); err != nil {
+			return nil, fmt.Errorf("discovery: send to %s: %w", target, err)
+		}
+	}
+
+	var devices []Device
+	seen := make(map[ams.NetID]bool)
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if timedOut(ctx, err) {
+				return devices, nil
+			}
+			return devices, fmt.Errorf("discovery: receive: %w", err)
+		}
+
+		var resp message
+		if err := resp.UnmarshalBinary(buf[:n]); err != nil || resp.service != serviceDiscovery|serviceResponse {
+			continue
+		}
+		if seen[resp.netID] {
+			continue
+		}
+		seen[resp.netID] = true
+
+		device := parseDevice(&resp)
+		if udpAddr, ok := from.(*net.UDPAddr); ok {
+			device.Address = udpAddr.IP.String()
+		}
+		devices = append(devices, device)
+	}
+}
+
+// parseDevice reads the device information from a discovery response.
+func parseDevice(m *message) Device {
+	device := Device{NetID: m.netID}
+	if data, ok := m.tag(tagHost); ok {
+		device.Hostname = cString(data)
+	}
+	if data, ok := m.tag(tagVersion); ok && len(data) >= 4 {
+		device.TwinCATVersion = Version{Major: data[0], Minor: data[1], Build: binary.LittleEndian.Uint16(data[2:4])}
+	}
+	if data, ok := m.tag(tagOSVersion); ok && len(data) >= 20 {
+		// OSVERSIONINFO: size, major, minor, build, platform, CSD version
+		device.OS = OSVersion{
+			Major:    binary.LittleEndian.Uint32(data[4:8]),
+			Minor:    binary.LittleEndian.Uint32(data[8:12]),
+			Build:    binary.LittleEndian.Uint32(data[12:16]),
+			Platform: binary.LittleEndian.Uint32(data[16:20]),
+			Name:     wideString(data[20:]),
+		}
+	}
+	if data, ok := m.tag(tagFingerprint); ok {
+		device.Fingerprint = cString(data)
+	}
+	return device
+}
+
+// Route is an AMS route to be added on a target system.
+type Route struct {
+	Name     string    // Route name shown on the target, e.g. the local hostname
+	NetID    ams.NetID // AMS NetID the route leads to, usually the local NetID
+	Host     string    // IP address or hostname the target uses to reach NetID
+	Username string    // User of the target system
+	Password string
+}
+
+// RouteError reports that the target rejected an add route request, e.g.
+// because of wrong credentials.
+type RouteError struct {
+	Status ads.Error
+}
+
+func (e *RouteError) Error() string {
+	return fmt.Sprintf("discovery: add route rejected: %v", e.Status)
+}
+
+// ErrNoResponse is returned when the target did not answer in time.
+var ErrNoResponse = errors.New("discovery: no response")
+
+// AddRoute adds a route on the system at address (host or host:port, port
+// 48899 by default). The request is repeated until the target answers or ctx
+// is done; without a deadline on ctx it gives up after 2s.
+func AddRoute(ctx context.Context, address string, route Route) error {
+	target, err := resolve(address)
+	if err != nil {
+		return err
+	}
+	if route.Name == "" || route.Host == "" {
+		return fmt.Errorf("discovery: route name and host are required")
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+		defer cancel()
+	}
+
+	conn, err := net.ListenPacket("udp4", defaultSenderHost)
+	if err != nil {
+		return fmt.Errorf("discovery: listen: %w", err)
+	}
+	defer conn.Close()
+	stopOnDone(ctx, conn)
+
+	req := message{
+		invokeID: invokeIDs.Add(1),
+		service:  serviceAddRoute,
+		netID:    route.NetID,
+		port:     senderPort,
+		tags: []tag{
+			stringTag(tagRouteName, route.Name),
+			{id: tagNetID, data: route.NetID[:]},
+			stringTag(tagUsername, route.Username),
+			stringTag(tagPassword, route.Password),
+			stringTag(tagHost, route.Host),
+		},
+	}
+	data, err := req.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	// Datagrams may be lost, so the request is repeated until answered
+	go func() {
+		ticker := time.NewTicker(retransmitPeriod)
+		defer ticker.Stop()
+		for {
+			if _, err := conn.WriteTo(data, target); err != nil {
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if timedOut(ctx, err) {
+				return fmt.Errorf("%w from %s", ErrNoResponse, target)
+			}
+			return fmt.Errorf("discovery: receive: %w", err)
+		}
+
+		var resp message
+		if err := resp.UnmarshalBinary(buf[:n]); err != nil ||
+			resp.service != serviceAddRoute|serviceResponse || resp.invokeID != req.invokeID {
+			continue
+		}
+
+		status, ok := resp.tag(tagStatus)
+		if !ok || len(status) < 4 {
+			return fmt.Errorf("discovery: add route response without status")
+		}
+		if code := ads.Error(binary.LittleEndian.Uint32(status)); code != 0 {
+			return &RouteError{Status: code}
+		}
+		return nil
+	}
+}
+
+// resolve parses host or host:port, defaulting to the discovery port.
+func resolve(address string) (*net.UDPAddr, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, fmt.Sprint(Port))
+	}
+	addr, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, fmt.Errorf("discovery: resolve %s: %w", address, err)
+	}
+	return addr, nil
+}
+
+// timedOut reports whether a read failed because ctx is done.
+func timedOut(ctx context.Context, err error) bool {
+	var netErr net.Error
+	return ctx.Err() != nil || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// stopOnDone unblocks reads on conn when ctx is done.
+func stopOnDone(ctx context.Context, conn net.PacketConn) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+	}
+	go func() {
+		<-ctx.Done()
+		conn.SetReadDeadline(time.Now())
+	}()
+}
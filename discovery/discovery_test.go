@@ -0,0 +1,127 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+func startTestServer(t *testing.T) *Server {
+	t.Helper()
+	server, err := StartServer("127.0.0.1:0", ServerConfig{
+		Device: Device{
+			NetID:          ams.NetID{192, 168, 1, 100, 1, 1},
+			Hostname:       "CX-1234",
+			TwinCATVersion: Version{Major: 3, Minor: 1, Build: 4024},
+			OS:             OSVersion{Major: 10, Minor: 0, Build: 19045, Platform: 2, Name: "LTSC"},
+			Fingerprint:    "ab12cd34",
+		},
+		Username: "Administrator",
+		Password: "1",
+	})
+	if err != nil {
+		t.Fatalf("StartServer() error = %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func TestDiscover(t *testing.T) {
+	server := startTestServer(t)
+
+	devices, err := Discover(context.Background(), Config{
+		// Listing the server twice must not list the device twice
+		Addresses: []string{server.Addr(), server.Addr()},
+		Timeout:   200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("found %d devices, want 1: %+v", len(devices), devices)
+	}
+
+	d := devices[0]
+	if d.Address != "127.0.0.1" || d.NetID != (ams.NetID{192, 168, 1, 100, 1, 1}) || d.Hostname != "CX-1234" {
+		t.Errorf("device = %+v", d)
+	}
+	if got := d.TwinCATVersion.String(); got != "3.1.4024" {
+		t.Errorf("TwinCAT version = %s", got)
+	}
+	if got := d.OS.String(); got != "Windows NT 10.0 (Build 19045) LTSC" {
+		t.Errorf("OS = %s", got)
+	}
+	if d.Fingerprint != "ab12cd34" {
+		t.Errorf("fingerprint = %q", d.Fingerprint)
+	}
+}
+
+func TestAddRoute(t *testing.T) {
+	server := startTestServer(t)
+	route := Route{
+		Name:     "edge-gateway",
+		NetID:    ams.NetID{192, 168, 1, 10, 1, 1},
+		Host:     "192.168.1.10",
+		Username: "Administrator",
+		Password: "1",
+	}
+
+	if err := AddRoute(context.Background(), server.Addr(), route); err != nil {
+		t.Fatalf("AddRoute() error = %v", err)
+	}
+	if routes := server.Routes(); len(routes) != 1 || routes[0] != route {
+		t.Errorf("routes = %+v, want %+v", routes, route)
+	}
+
+	route.Password = "wrong"
+	err := AddRoute(context.Background(), server.Addr(), route)
+	var routeErr *RouteError
+	if !errors.As(err, &routeErr) || routeErr.Status != errAccessDenied {
+		t.Errorf("AddRoute() with wrong password error = %v, want RouteError", err)
+	}
+}
+
+func TestAddRouteNoResponse(t *testing.T) {
+	server := startTestServer(t)
+	addr := server.Addr()
+	server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	err := AddRoute(ctx, addr, Route{Name: "x", Host: "127.0.0.1"})
+	if !errors.Is(err, ErrNoResponse) {
+		t.Errorf("AddRoute() error = %v, want ErrNoResponse", err)
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	m := message{
+		invokeID: 7,
+		service:  serviceAddRoute,
+		netID:    ams.NetID{1, 2, 3, 4, 1, 1},
+		port:     senderPort,
+		tags:     []tag{stringTag(tagRouteName, "r"), {id: tagNetID, data: []byte{1, 2, 3, 4, 1, 1}}},
+	}
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+
+	var got message
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if got.invokeID != 7 || got.service != serviceAddRoute || got.netID != m.netID || got.port != senderPort || len(got.tags) != 2 {
+		t.Errorf("decoded = %+v", got)
+	}
+	if name, _ := got.tag(tagRouteName); cString(name) != "r" {
+		t.Errorf("route name = %q", name)
+	}
+
+	if err := got.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("truncated message decoded without error")
+	}
+}
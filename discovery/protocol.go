@@ -0,0 +1,155 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+// Port is the UDP port of the TwinCAT router for discovery and route management.
+const Port = 48899
+
+// magic starts every discovery datagram.
+const magic uint32 = 0x71146603
+
+// Service IDs. Responses carry the request ID with the response bit set.
+const (
+	serviceDiscovery uint32 = 0x00000001
+	serviceAddRoute  uint32 = 0x00000006
+	serviceResponse  uint32 = 0x80000000
+)
+
+// Tag IDs of the parameters following the header.
+const (
+	tagStatus      uint16 = 0x0001
+	tagPassword    uint16 = 0x0002
+	tagVersion     uint16 = 0x0003
+	tagOSVersion   uint16 = 0x0004
+	tagHost        uint16 = 0x0005
+	tagNetID       uint16 = 0x0007
+	tagRouteName   uint16 = 0x000C
+	tagUsername    uint16 = 0x000D
+	tagFingerprint uint16 = 0x0012
+)
+
+// senderPort is the AMS port of the system service sending and answering
+// discovery requests.
+const senderPort ams.Port = 10000
+
+// headerSize is the size of magic, invoke ID, service, NetID, port and tag count.
+const headerSize = 24
+
+// tag is a parameter of a discovery message.
+type tag struct {
+	id   uint16
+	data []byte
+}
+
+// message is a discovery or route management datagram.
+type message struct {
+	invokeID uint32
+	service  uint32
+	netID    ams.NetID
+	port     ams.Port
+	tags     []tag
+}
+
+// MarshalBinary encodes the message.
+func (m *message) MarshalBinary() ([]byte, error) {
+	size := headerSize
+	for _, t := range m.tags {
+		size += 4 + len(t.data)
+	}
+
+	buf := make([]byte, size)
+	binary.LittleEndian.PutUint32(buf[0:4], magic)
+	binary.LittleEndian.PutUint32(buf[4:8], m.invokeID)
+	binary.LittleEndian.PutUint32(buf[8:12], m.service)
+	copy(buf[12:18], m.netID[:])
+	binary.LittleEndian.PutUint16(buf[18:20], uint16(m.port))
+	binary.LittleEndian.PutUint32(buf[20:24], uint32(len(m.tags)))
+
+	offset := headerSize
+	for _, t := range m.tags {
+		if len(t.data) > 0xFFFF {
+			return nil, fmt.Errorf("discovery: tag 0x%04X too long (%d bytes)", t.id, len(t.data))
+		}
+		binary.LittleEndian.PutUint16(buf[offset:offset+2], t.id)
+		binary.LittleEndian.PutUint16(buf[offset+2:offset+4], uint16(len(t.data)))
+		offset += 4
+		offset += copy(buf[offset:], t.data)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a message.
+func (m *message) UnmarshalBinary(data []byte) error {
+	if len(data) < headerSize {
+		return fmt.Errorf("discovery: message requires at least %d bytes, got %d", headerSize, len(data))
+	}
+	if got := binary.LittleEndian.Uint32(data[0:4]); got != magic {
+		return fmt.Errorf("discovery: invalid magic 0x%08X", got)
+	}
+
+	m.invokeID = binary.LittleEndian.Uint32(data[4:8])
+	m.service = binary.LittleEndian.Uint32(data[8:12])
+	copy(m.netID[:], data[12:18])
+	m.port = ams.Port(binary.LittleEndian.Uint16(data[18:20]))
+	count := binary.LittleEndian.Uint32(data[20:24])
+
+	m.tags = nil
+	offset := headerSize
+	for i := uint32(0); i < count; i++ {
+		if offset+4 > len(data) {
+			return fmt.Errorf("discovery: insufficient data for tag %d", i)
+		}
+		id := binary.LittleEndian.Uint16(data[offset : offset+2])
+		length := int(binary.LittleEndian.Uint16(data[offset+2 : offset+4]))
+		offset += 4
+		if offset+length > len(data) {
+			return fmt.Errorf("discovery: insufficient data for tag 0x%04X", id)
+		}
+		m.tags = append(m.tags, tag{id: id, data: bytes.Clone(data[offset : offset+length])})
+		offset += length
+	}
+	return nil
+}
+
+// tag returns the data of the first tag with the given ID.
+func (m *message) tag(id uint16) ([]byte, bool) {
+	for _, t := range m.tags {
+		if t.id == id {
+			return t.data, true
+		}
+	}
+	return nil, false
+}
+
+// stringTag encodes a null-terminated string parameter.
+func stringTag(id uint16, s string) tag {
+	return tag{id: id, data: append([]byte(s), 0)}
+}
+
+// cString decodes a null-terminated string.
+func cString(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	return string(data)
+}
+
+// wideString decodes a null-terminated UTF-16LE string.
+func wideString(data []byte) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		u := binary.LittleEndian.Uint16(data[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units))
+}
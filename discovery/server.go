@@ -0,0 +1,189 @@
+package discovery
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"unicode/utf16"
+
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+)
+
+// ServerConfig describes the system simulated by a Server.
+type ServerConfig struct {
+	Device Device // Reported in discovery responses; Address is ignored
+
+	// Credentials accepted for add route requests. Requests with other
+	// credentials are rejected with ADS error 0x0704 (invalid access).
+	Username string
+	Password string
+}
+
+// Server answers discovery and add route requests like the UDP service of a
+// TwinCAT router. It stands in for a real device in tests.
+type Server struct {
+	cfg  ServerConfig
+	conn net.PacketConn
+
+	mu     sync.Mutex
+	routes []Route
+	done   chan struct{}
+}
+
+// errAccessDenied is the status returned for wrong credentials.
+const errAccessDenied ads.Error = 0x0704
+
+// StartServer listens on address (e.g. "127.0.0.1:0") and serves requests
+// until Close is called.
+func StartServer(address string, cfg ServerConfig) (*Server, error) {
+	conn, err := net.ListenPacket("udp4", address)
+	if err != nil {
+		return nil, fmt.Errorf("discovery: listen %s: %w", address, err)
+	}
+
+	s := &Server{cfg: cfg, conn: conn, done: make(chan struct{})}
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.conn.LocalAddr().String()
+}
+
+// Routes returns the routes added so far.
+func (s *Server) Routes() []Route {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Route(nil), s.routes...)
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	err := s.conn.Close()
+	<-s.done
+	return err
+}
+
+// serve answers requests until the socket is closed.
+func (s *Server) serve() {
+	defer close(s.done)
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, from, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		var req message
+		if err := req.UnmarshalBinary(buf[:n]); err != nil {
+			continue
+		}
+
+		var resp *message
+		switch req.service {
+		case serviceDiscovery:
+			resp = s.discoveryResponse(&req)
+		case serviceAddRoute:
+			resp = s.addRoute(&req)
+		default:
+			continue
+		}
+
+		data, err := resp.MarshalBinary()
+		if err != nil {
+			continue
+		}
+		s.conn.WriteTo(data, from)
+	}
+}
+
+// discoveryResponse describes the simulated device.
+func (s *Server) discoveryResponse(req *message) *message {
+	device := s.cfg.Device
+
+	version := make([]byte, 4)
+	version[0] = device.TwinCATVersion.Major
+	version[1] = device.TwinCATVersion.Minor
+	binary.LittleEndian.PutUint16(version[2:4], device.TwinCATVersion.Build)
+
+	name := utf16.Encode([]rune(device.OS.Name))
+	osVersion := make([]byte, 20+2*len(name)+2)
+	binary.LittleEndian.PutUint32(osVersion[0:4], uint32(len(osVersion)))
+	binary.LittleEndian.PutUint32(osVersion[4:8], device.OS.Major)
+	binary.LittleEndian.PutUint32(osVersion[8:12], device.OS.Minor)
+	binary.LittleEndian.PutUint32(osVersion[12:16], device.OS.Build)
+	binary.LittleEndian.PutUint32(osVersion[16:20], device.OS.Platform)
+	for i, u := range name {
+		binary.LittleEndian.PutUint16(osVersion[20+2*i:], u)
+	}
+
+	tags := []tag{
+		stringTag(tagHost, device.Hostname),
+		{id: tagVersion, data: version},
+		{id: tagOSVersion, data: osVersion},
+	}
+	if device.Fingerprint != "" {
+		tags = append(tags, stringTag(tagFingerprint, device.Fingerprint))
+	}
+
+	return &message{
+		invokeID: req.invokeID,
+		service:  serviceDiscovery | serviceResponse,
+		netID:    device.NetID,
+		port:     senderPort,
+		tags:     tags,
+	}
+}
+
+// addRoute checks the credentials and stores the route. Repeated requests
+// for the same route name replace the earlier route.
+func (s *Server) addRoute(req *message) *message {
+	var route Route
+	for _, t := range req.tags {
+		switch t.id {
+		case tagRouteName:
+			route.Name = cString(t.data)
+		case tagNetID:
+			copy(route.NetID[:], t.data)
+		case tagUsername:
+			route.Username = cString(t.data)
+		case tagPassword:
+			route.Password = cString(t.data)
+		case tagHost:
+			route.Host = cString(t.data)
+		}
+	}
+
+	var status ads.Error
+	if route.Username != s.cfg.Username || route.Password != s.cfg.Password {
+		status = errAccessDenied
+	} else {
+		s.mu.Lock()
+		replaced := false
+		for i := range s.routes {
+			if s.routes[i].Name == route.Name {
+				s.routes[i] = route
+				replaced = true
+			}
+		}
+		if !replaced {
+			s.routes = append(s.routes, route)
+		}
+		s.mu.Unlock()
+	}
+
+	return &message{
+		invokeID: req.invokeID,
+		service:  serviceAddRoute | serviceResponse,
+		netID:    s.cfg.Device.NetID,
+		port:     senderPort,
+		tags:     []tag{{id: tagStatus, data: binary.LittleEndian.AppendUint32(nil, uint32(status))}},
+	}
+}
@@ -0,0 +1,209 @@
+package goadstc
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// DispatchMode selects how notification callbacks registered with
+// SubscribeFunc are scheduled.
+type DispatchMode int
+
+const (
+	// DispatchOrdered runs the callbacks of each subscription on a goroutine of
+	// its own, one at a time and in arrival order (default).
+	DispatchOrdered DispatchMode = iota
+	// DispatchPool runs the callbacks of all subscriptions on a shared pool of
+	// workers. Callbacks of one subscription may run concurrently and out of order.
+	DispatchPool
+)
+
+// String returns the name of the dispatch mode.
+func (m DispatchMode) String() string {
+	switch m {
+	case DispatchOrdered:
+		return "ordered"
+	case DispatchPool:
+		return "pool"
+	default:
+		return fmt.Sprintf("DispatchMode(%d)", int(m))
+	}
+}
+
+const (
+	defaultDispatchQueueSize    = 256
+	defaultSlowHandlerThreshold = 100 * time.Millisecond
+)
+
+// DispatcherConfig configures how notification callbacks are run.
+type DispatcherConfig struct {
+	Mode DispatchMode
+
+	// Workers is the size of the worker pool in DispatchPool mode
+	// (default runtime.NumCPU()).
+	Workers int
+
+	// QueueSize is the number of callbacks waiting for a pool worker
+	// (default 256). When the queue is full, samples back up in the
+	// subscription buffer and its OverflowPolicy applies.
+	QueueSize int
+
+	// SlowHandlerThreshold is the callback duration above which a handler is
+	// reported as slow (default 100ms, negative disables the check).
+	SlowHandlerThreshold time.Duration
+}
+
+// WithDispatcher sets how callbacks registered with SubscribeFunc and
+// SubscribeSymbolFunc are run.
+func WithDispatcher(cfg DispatcherConfig) Option {
+	return func(c *clientConfig) error {
+		if cfg.Mode != DispatchOrdered && cfg.Mode != DispatchPool {
+			return fmt.Errorf("goadstc: unknown dispatch mode %v", cfg.Mode)
+		}
+		if cfg.Workers < 0 {
+			return fmt.Errorf("goadstc: dispatcher workers cannot be negative")
+		}
+		if cfg.QueueSize < 0 {
+			return fmt.Errorf("goadstc: dispatcher queue size cannot be negative")
+		}
+		c.dispatch = cfg
+		return nil
+	}
+}
+
+// SubscribeFunc creates a notification subscription whose notifications are
+// passed to handler instead of being read from Notifications(). The handler
+// runs on the client's dispatcher (see WithDispatcher); panics are recovered
+// and reported together with slow handlers through the Logger and Metrics.
+// Call Close() on the Subscription to stop the callbacks.
+func (c *Client) SubscribeFunc(ctx context.Context, opts NotificationOptions, handler func(Notification)) (*Subscription, error) {
+	if handler == nil {
+		return nil, NewValidationError("subscribe", "handler cannot be nil")
+	}
+	sub, err := c.Subscribe(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.dispatcher.dispatch(sub, handler)
+	return sub, nil
+}
+
+// SubscribeSymbolFunc is like SubscribeSymbol but passes the decoded
+// notifications to handler, see SubscribeFunc.
+func (c *Client) SubscribeSymbolFunc(ctx context.Context, symbolName string, opts SymbolNotificationOptions, handler func(Notification)) (*Subscription, error) {
+	if handler == nil {
+		return nil, NewValidationError("subscribe_symbol", "handler cannot be nil")
+	}
+	sub, err := c.SubscribeSymbol(ctx, symbolName, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.dispatcher.dispatch(sub, handler)
+	return sub, nil
+}
+
+// dispatcher runs notification callbacks.
+type dispatcher struct {
+	cfg     DispatcherConfig
+	ctx     context.Context // cancelled when the client is closed
+	logger  Logger
+	metrics Metrics
+
+	startOnce sync.Once
+	jobs      chan dispatchJob // pool mode only
+}
+
+// dispatchJob is a single callback invocation waiting for a pool worker.
+type dispatchJob struct {
+	sub     *Subscription
+	handler func(Notification)
+	notif   Notification
+}
+
+// newDispatcher creates a dispatcher, filling in defaults. Pool workers are
+// started with the first subscription.
+func newDispatcher(ctx context.Context, cfg DispatcherConfig, logger Logger, metrics Metrics) *dispatcher {
+	if cfg.Workers == 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = defaultDispatchQueueSize
+	}
+	if cfg.SlowHandlerThreshold == 0 {
+		cfg.SlowHandlerThreshold = defaultSlowHandlerThreshold
+	}
+	return &dispatcher{cfg: cfg, ctx: ctx, logger: logger, metrics: metrics}
+}
+
+// dispatch passes the notifications of a subscription to handler until the
+// subscription or the client is closed.
+func (d *dispatcher) dispatch(sub *Subscription, handler func(Notification)) {
+	if d.cfg.Mode == DispatchPool {
+		d.startOnce.Do(d.startWorkers)
+	}
+	go d.pump(sub, handler)
+}
+
+// pump reads the notification channel of a subscription. In ordered mode it
+// runs the callbacks itself; in pool mode it queues them for the workers and
+// blocks while the queue is full.
+func (d *dispatcher) pump(sub *Subscription, handler func(Notification)) {
+	for notif := range sub.Notifications() {
+		if d.cfg.Mode != DispatchPool {
+			d.run(sub, handler, notif)
+			continue
+		}
+		select {
+		case d.jobs <- dispatchJob{sub: sub, handler: handler, notif: notif}:
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+// startWorkers starts the worker pool.
+func (d *dispatcher) startWorkers() {
+	d.jobs = make(chan dispatchJob, d.cfg.QueueSize)
+	d.logger.Debug("starting notification workers", "workers", d.cfg.Workers)
+	for i := 0; i < d.cfg.Workers; i++ {
+		go d.worker()
+	}
+}
+
+// worker runs queued callbacks until the client is closed.
+func (d *dispatcher) worker() {
+	for {
+		select {
+		case job := <-d.jobs:
+			d.run(job.sub, job.handler, job.notif)
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+// run invokes a callback, recovering panics and reporting slow handlers.
+func (d *dispatcher) run(sub *Subscription, handler func(Notification), notif Notification) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			d.logger.Error("notification handler panicked",
+				"handle", sub.Handle(), "panic", r, "stack", string(debug.Stack()))
+			if m, ok := d.metrics.(HandlerMetrics); ok {
+				m.NotificationHandlerPanicked()
+			}
+		}
+		if elapsed := time.Since(start); d.cfg.SlowHandlerThreshold > 0 && elapsed > d.cfg.SlowHandlerThreshold {
+			d.logger.Warn("slow notification handler",
+				"handle", sub.Handle(), "duration", elapsed, "threshold", d.cfg.SlowHandlerThreshold)
+			if m, ok := d.metrics.(HandlerMetrics); ok {
+				m.NotificationHandlerSlow(elapsed)
+			}
+		}
+	}()
+	handler(notif)
+}
@@ -0,0 +1,107 @@
+package goadstc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDispatcherOrdered(t *testing.T) {
+	metrics := NewInMemoryMetrics()
+	d := newDispatcher(context.Background(), DispatcherConfig{SlowHandlerThreshold: 20 * time.Millisecond}, &noopLogger{}, metrics)
+
+	sub := &Subscription{notifCh: make(chan Notification, 8)}
+	var got []byte
+	done := make(chan struct{})
+	d.dispatch(sub, func(n Notification) {
+		switch n.Data[0] {
+		case 2:
+			panic("handler failure")
+		case 3:
+			time.Sleep(40 * time.Millisecond)
+		}
+		got = append(got, n.Data[0])
+		if len(got) == 4 {
+			close(done)
+		}
+	})
+
+	for i := byte(1); i <= 5; i++ {
+		sub.notifCh <- Notification{Data: []byte{i}}
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("callbacks not run")
+	}
+	if string(got) != "\x01\x03\x04\x05" {
+		t.Errorf("callback order = %v, want [1 3 4 5]", got)
+	}
+
+	snapshot := metrics.Snapshot()
+	if snapshot.HandlerPanics != 1 {
+		t.Errorf("handler panics = %d, want 1", snapshot.HandlerPanics)
+	}
+	if snapshot.SlowHandlers != 1 {
+		t.Errorf("slow handlers = %d, want 1", snapshot.SlowHandlers)
+	}
+}
+
+func TestDispatcherPool(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := newDispatcher(ctx, DispatcherConfig{Mode: DispatchPool, Workers: 3}, &noopLogger{}, &noopMetrics{})
+
+	// All workers must be busy at the same time for the barrier to release
+	var wg sync.WaitGroup
+	wg.Add(3)
+	release := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(release)
+	}()
+
+	subs := []*Subscription{
+		{notifCh: make(chan Notification, 2)},
+		{notifCh: make(chan Notification, 2)},
+	}
+	for _, sub := range subs {
+		d.dispatch(sub, func(Notification) {
+			wg.Done()
+			<-release
+		})
+	}
+	subs[0].notifCh <- Notification{}
+	subs[0].notifCh <- Notification{}
+	subs[1].notifCh <- Notification{}
+
+	select {
+	case <-release:
+	case <-time.After(time.Second):
+		t.Fatal("callbacks did not run concurrently on the pool")
+	}
+}
+
+func TestWithDispatcherValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     DispatcherConfig
+		wantErr bool
+	}{
+		{"default", DispatcherConfig{}, false},
+		{"pool", DispatcherConfig{Mode: DispatchPool, Workers: 4, QueueSize: 16}, false},
+		{"unknown mode", DispatcherConfig{Mode: DispatchMode(7)}, true},
+		{"negative workers", DispatcherConfig{Mode: DispatchPool, Workers: -1}, true},
+		{"negative queue", DispatcherConfig{QueueSize: -1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WithDispatcher(tt.cfg)(&clientConfig{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WithDispatcher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
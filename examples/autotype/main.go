@@ -100,9 +100,7 @@ func demonstrateBatchReading(ctx context.Context, client *goadstc.Client) {
 	fmt.Println("📦 Test 2: Batch Reading (Multiple Symbols)")
 	fmt.Println("═══════════════════════════════════════════════════════════")
 
-	// Read multiple symbols at once
-	// Note: This currently uses individual requests, but the API is ready for
-	// future optimization with SumCommand for true batched reads
+	// Read multiple symbols at once using a single sum command request
 	fmt.Println("Reading multiple symbols at once...")
 	results, err := client.ReadMultipleSymbolValues(ctx,
 		"MAIN.uUint",
@@ -30,9 +30,12 @@ func main() {
 
 	netID := ams.NetID{127, 0, 0, 1, 1, 1}
 	if netIDStr := os.Getenv("ADS_NET_ID"); netIDStr != "" {
-		// Parse NetID manually: "192.168.1.1.1.1" -> NetID{192, 168, 1, 1, 1, 1}
-		fmt.Sscanf(netIDStr, "%d.%d.%d.%d.%d.%d",
-			&netID[0], &netID[1], &netID[2], &netID[3], &netID[4], &netID[5])
+		// "192.168.1.1.1.1" -> NetID{192, 168, 1, 1, 1, 1}
+		parsed, err := ams.ParseNetID(netIDStr)
+		if err != nil {
+			log.Fatalf("Invalid ADS_NET_ID: %v", err)
+		}
+		netID = parsed
 	}
 
 	// Create client with logging and metrics
@@ -106,6 +106,16 @@ func (r *ReadRequest) MarshalBinary() ([]byte, error) {
 	return buf, nil
 }
 
+func (r *ReadRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < 12 {
+		return fmt.Errorf("ads: read request requires 12 bytes")
+	}
+	r.IndexGroup = binary.LittleEndian.Uint32(data[0:4])
+	r.IndexOffset = binary.LittleEndian.Uint32(data[4:8])
+	r.Length = binary.LittleEndian.Uint32(data[8:12])
+	return nil
+}
+
 type ReadResponse struct {
 	Result uint32
 	Length uint32
@@ -123,6 +133,14 @@ func (r *ReadResponse) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+func (r *ReadResponse) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 8+len(r.Data))
+	binary.LittleEndian.PutUint32(buf[0:4], r.Result)
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(len(r.Data)))
+	copy(buf[8:], r.Data)
+	return buf, nil
+}
+
 type WriteRequest struct {
 	IndexGroup  uint32
 	IndexOffset uint32
@@ -139,6 +157,21 @@ func (w *WriteRequest) MarshalBinary() ([]byte, error) {
 	return buf, nil
 }
 
+func (w *WriteRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < 12 {
+		return fmt.Errorf("ads: write request requires at least 12 bytes")
+	}
+	w.IndexGroup = binary.LittleEndian.Uint32(data[0:4])
+	w.IndexOffset = binary.LittleEndian.Uint32(data[4:8])
+	w.Length = binary.LittleEndian.Uint32(data[8:12])
+	if uint32(len(data)-12) < w.Length {
+		return fmt.Errorf("ads: insufficient data for write request (expected %d, got %d)", w.Length, len(data)-12)
+	}
+	w.Data = make([]byte, w.Length)
+	copy(w.Data, data[12:12+w.Length])
+	return nil
+}
+
 type WriteResponse struct {
 	Result uint32
 }
@@ -151,6 +184,12 @@ func (w *WriteResponse) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+func (w *WriteResponse) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf[0:4], w.Result)
+	return buf, nil
+}
+
 type ReadStateRequest struct{}
 
 func (r *ReadStateRequest) MarshalBinary() ([]byte, error) {
@@ -173,6 +212,14 @@ func (r *ReadStateResponse) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+func (r *ReadStateResponse) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint32(buf[0:4], r.Result)
+	binary.LittleEndian.PutUint16(buf[4:6], uint16(r.ADSState))
+	binary.LittleEndian.PutUint16(buf[6:8], r.DeviceState)
+	return buf, nil
+}
+
 type ReadDeviceInfoRequest struct{}
 
 func (r *ReadDeviceInfoRequest) MarshalBinary() ([]byte, error) {
@@ -211,6 +258,19 @@ func (r *ReadDeviceInfoResponse) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+func (r *ReadDeviceInfoResponse) MarshalBinary() ([]byte, error) {
+	if len(r.DeviceName) > 16 {
+		return nil, fmt.Errorf("ads: device name exceeds 16 bytes")
+	}
+	buf := make([]byte, 24)
+	binary.LittleEndian.PutUint32(buf[0:4], r.Result)
+	buf[4] = r.MajorVersion
+	buf[5] = r.MinorVersion
+	binary.LittleEndian.PutUint16(buf[6:8], r.VersionBuild)
+	copy(buf[8:24], r.DeviceName)
+	return buf, nil
+}
+
 type ReadWriteRequest struct {
 	IndexGroup  uint32
 	IndexOffset uint32
@@ -229,6 +289,22 @@ func (r *ReadWriteRequest) MarshalBinary() ([]byte, error) {
 	return buf, nil
 }
 
+func (r *ReadWriteRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < 16 {
+		return fmt.Errorf("ads: read/write request requires at least 16 bytes")
+	}
+	r.IndexGroup = binary.LittleEndian.Uint32(data[0:4])
+	r.IndexOffset = binary.LittleEndian.Uint32(data[4:8])
+	r.ReadLength = binary.LittleEndian.Uint32(data[8:12])
+	r.WriteLength = binary.LittleEndian.Uint32(data[12:16])
+	if uint32(len(data)-16) < r.WriteLength {
+		return fmt.Errorf("ads: insufficient data for read/write request (expected %d, got %d)", r.WriteLength, len(data)-16)
+	}
+	r.Data = make([]byte, r.WriteLength)
+	copy(r.Data, data[16:16+r.WriteLength])
+	return nil
+}
+
 type ReadWriteResponse struct {
 	Result uint32
 	Length uint32
@@ -241,11 +317,22 @@ func (r *ReadWriteResponse) UnmarshalBinary(data []byte) error {
 	}
 	r.Result = binary.LittleEndian.Uint32(data[0:4])
 	r.Length = binary.LittleEndian.Uint32(data[4:8])
+	if uint32(len(data)-8) < r.Length {
+		return fmt.Errorf("ads: insufficient data for read/write response (expected %d, got %d)", r.Length, len(data)-8)
+	}
 	r.Data = make([]byte, r.Length)
 	copy(r.Data, data[8:8+r.Length])
 	return nil
 }
 
+func (r *ReadWriteResponse) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 8+len(r.Data))
+	binary.LittleEndian.PutUint32(buf[0:4], r.Result)
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(len(r.Data)))
+	copy(buf[8:], r.Data)
+	return buf, nil
+}
+
 type WriteControlRequest struct {
 	ADSState    ADSState
 	DeviceState uint16
@@ -262,6 +349,21 @@ func (w *WriteControlRequest) MarshalBinary() ([]byte, error) {
 	return buf, nil
 }
 
+func (w *WriteControlRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < 8 {
+		return fmt.Errorf("ads: write control request requires at least 8 bytes")
+	}
+	w.ADSState = ADSState(binary.LittleEndian.Uint16(data[0:2]))
+	w.DeviceState = binary.LittleEndian.Uint16(data[2:4])
+	w.Length = binary.LittleEndian.Uint32(data[4:8])
+	if uint32(len(data)-8) < w.Length {
+		return fmt.Errorf("ads: insufficient data for write control request (expected %d, got %d)", w.Length, len(data)-8)
+	}
+	w.Data = make([]byte, w.Length)
+	copy(w.Data, data[8:8+w.Length])
+	return nil
+}
+
 type WriteControlResponse struct {
 	Result uint32
 }
@@ -274,6 +376,12 @@ func (w *WriteControlResponse) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+func (w *WriteControlResponse) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf[0:4], w.Result)
+	return buf, nil
+}
+
 // AddDeviceNotificationRequest represents an ADS AddDeviceNotification request.
 type AddDeviceNotificationRequest struct {
 	IndexGroup       uint32
@@ -296,6 +404,19 @@ func (a *AddDeviceNotificationRequest) MarshalBinary() ([]byte, error) {
 	return buf, nil
 }
 
+func (a *AddDeviceNotificationRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < 24 {
+		return fmt.Errorf("ads: add notification request requires at least 24 bytes")
+	}
+	a.IndexGroup = binary.LittleEndian.Uint32(data[0:4])
+	a.IndexOffset = binary.LittleEndian.Uint32(data[4:8])
+	a.Length = binary.LittleEndian.Uint32(data[8:12])
+	a.TransmissionMode = TransmissionMode(binary.LittleEndian.Uint32(data[12:16]))
+	a.MaxDelay = binary.LittleEndian.Uint32(data[16:20])
+	a.CycleTime = binary.LittleEndian.Uint32(data[20:24])
+	return nil
+}
+
 // AddDeviceNotificationResponse represents an ADS AddDeviceNotification response.
 type AddDeviceNotificationResponse struct {
 	Result             uint32
@@ -311,6 +432,13 @@ func (a *AddDeviceNotificationResponse) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+func (a *AddDeviceNotificationResponse) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint32(buf[0:4], a.Result)
+	binary.LittleEndian.PutUint32(buf[4:8], a.NotificationHandle)
+	return buf, nil
+}
+
 // DeleteDeviceNotificationRequest represents an ADS DeleteDeviceNotification request.
 type DeleteDeviceNotificationRequest struct {
 	NotificationHandle uint32
@@ -322,6 +450,14 @@ func (d *DeleteDeviceNotificationRequest) MarshalBinary() ([]byte, error) {
 	return buf, nil
 }
 
+func (d *DeleteDeviceNotificationRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("ads: delete notification request requires 4 bytes")
+	}
+	d.NotificationHandle = binary.LittleEndian.Uint32(data[0:4])
+	return nil
+}
+
 // DeleteDeviceNotificationResponse represents an ADS DeleteDeviceNotification response.
 type DeleteDeviceNotificationResponse struct {
 	Result uint32
@@ -335,6 +471,12 @@ func (d *DeleteDeviceNotificationResponse) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+func (d *DeleteDeviceNotificationResponse) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf[0:4], d.Result)
+	return buf, nil
+}
+
 // NotificationSample represents a single notification sample.
 type NotificationSample struct {
 	NotificationHandle uint32
@@ -352,6 +494,34 @@ type DeviceNotificationRequest struct {
 	StampHeaders []StampHeader
 }
 
+func (d *DeviceNotificationRequest) MarshalBinary() ([]byte, error) {
+	size := 8
+	for _, stamp := range d.StampHeaders {
+		size += 12
+		for _, sample := range stamp.Samples {
+			size += 8 + len(sample.Data)
+		}
+	}
+
+	buf := make([]byte, size)
+	// Length field does not include itself
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(size-4))
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(len(d.StampHeaders)))
+	offset := 8
+	for _, stamp := range d.StampHeaders {
+		binary.LittleEndian.PutUint64(buf[offset:offset+8], stamp.Timestamp)
+		binary.LittleEndian.PutUint32(buf[offset+8:offset+12], uint32(len(stamp.Samples)))
+		offset += 12
+		for _, sample := range stamp.Samples {
+			binary.LittleEndian.PutUint32(buf[offset:offset+4], sample.NotificationHandle)
+			binary.LittleEndian.PutUint32(buf[offset+4:offset+8], uint32(len(sample.Data)))
+			offset += 8
+			offset += copy(buf[offset:], sample.Data)
+		}
+	}
+	return buf, nil
+}
+
 func (d *DeviceNotificationRequest) UnmarshalBinary(data []byte) error {
 	if len(data) < 8 {
 		return fmt.Errorf("ads: device notification requires at least 8 bytes")
@@ -0,0 +1,67 @@
+package ads
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRequestRoundTrip(t *testing.T) {
+	write := WriteRequest{IndexGroup: 0x4020, IndexOffset: 8, Length: 2, Data: []byte{1, 2}}
+	data, _ := write.MarshalBinary()
+	var gotWrite WriteRequest
+	if err := gotWrite.UnmarshalBinary(data); err != nil {
+		t.Fatalf("WriteRequest.UnmarshalBinary() error = %v", err)
+	}
+	if gotWrite.IndexOffset != 8 || !bytes.Equal(gotWrite.Data, write.Data) {
+		t.Errorf("write request = %+v, want %+v", gotWrite, write)
+	}
+	if err := gotWrite.UnmarshalBinary(data[:13]); err == nil {
+		t.Error("truncated write request decoded without error")
+	}
+
+	readWrite := ReadWriteRequest{IndexGroup: 0xF003, ReadLength: 4, WriteLength: 5, Data: []byte("MAIN\x00")}
+	data, _ = readWrite.MarshalBinary()
+	var gotReadWrite ReadWriteRequest
+	if err := gotReadWrite.UnmarshalBinary(data); err != nil {
+		t.Fatalf("ReadWriteRequest.UnmarshalBinary() error = %v", err)
+	}
+	if gotReadWrite.ReadLength != 4 || string(gotReadWrite.Data) != "MAIN\x00" {
+		t.Errorf("read/write request = %+v", gotReadWrite)
+	}
+
+	notification := AddDeviceNotificationRequest{IndexGroup: 0x4020, Length: 4, TransmissionMode: TransModeOnChange, CycleTime: 100}
+	data, _ = notification.MarshalBinary()
+	var gotNotification AddDeviceNotificationRequest
+	if err := gotNotification.UnmarshalBinary(data); err != nil {
+		t.Fatalf("AddDeviceNotificationRequest.UnmarshalBinary() error = %v", err)
+	}
+	if gotNotification != notification {
+		t.Errorf("add notification request = %+v, want %+v", gotNotification, notification)
+	}
+}
+
+func TestResponseRoundTrip(t *testing.T) {
+	data, _ := (&ReadResponse{Data: []byte{0x2A, 0x00}}).MarshalBinary()
+	var read ReadResponse
+	if err := read.UnmarshalBinary(data); err != nil || !bytes.Equal(read.Data, []byte{0x2A, 0x00}) {
+		t.Errorf("read response = %+v, err = %v", read, err)
+	}
+
+	data, _ = (&ReadStateResponse{ADSState: StateRun, DeviceState: 3}).MarshalBinary()
+	var state ReadStateResponse
+	if err := state.UnmarshalBinary(data); err != nil || state.ADSState != StateRun || state.DeviceState != 3 {
+		t.Errorf("read state response = %+v, err = %v", state, err)
+	}
+
+	data, err := (&ReadDeviceInfoResponse{MajorVersion: 3, MinorVersion: 1, VersionBuild: 4024, DeviceName: "Plc30 App"}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("ReadDeviceInfoResponse.MarshalBinary() error = %v", err)
+	}
+	var info ReadDeviceInfoResponse
+	if err := info.UnmarshalBinary(data); err != nil || info.DeviceName != "Plc30 App" || info.VersionBuild != 4024 {
+		t.Errorf("device info response = %+v, err = %v", info, err)
+	}
+	if _, err := (&ReadDeviceInfoResponse{DeviceName: "a name longer than 16"}).MarshalBinary(); err == nil {
+		t.Error("device name longer than 16 bytes encoded without error")
+	}
+}
@@ -5,12 +5,17 @@ import "fmt"
 type Error uint32
 
 const (
-	ErrNoError                  Error = 0x0000
-	ErrInternal                 Error = 0x0001
-	ErrTargetPortNotFound       Error = 0x0006
-	ErrTargetMachineNotFound    Error = 0x0007
-	ErrDeviceInvalidIndexGroup  Error = 0x0702
-	ErrDeviceInvalidIndexOffset Error = 0x0703
+	ErrNoError                    Error = 0x0000
+	ErrInternal                   Error = 0x0001
+	ErrTargetPortNotFound         Error = 0x0006
+	ErrTargetMachineNotFound      Error = 0x0007
+	ErrDeviceServiceNotSupported  Error = 0x0701
+	ErrDeviceInvalidIndexGroup    Error = 0x0702
+	ErrDeviceInvalidIndexOffset   Error = 0x0703
+	ErrDeviceInvalidSize          Error = 0x0705
+	ErrDeviceSymbolNotFound       Error = 0x0710
+	ErrDeviceSymbolVersionInvalid Error = 0x0711
+	ErrDeviceNotifyHandleInvalid  Error = 0x0714
 )
 
 func (e Error) Error() string {
@@ -23,10 +28,20 @@ func (e Error) Error() string {
 		return "target port not found"
 	case ErrTargetMachineNotFound:
 		return "target machine not found"
+	case ErrDeviceServiceNotSupported:
+		return "service not supported"
 	case ErrDeviceInvalidIndexGroup:
 		return "invalid index group"
 	case ErrDeviceInvalidIndexOffset:
 		return "invalid index offset"
+	case ErrDeviceInvalidSize:
+		return "invalid size"
+	case ErrDeviceSymbolNotFound:
+		return "symbol not found"
+	case ErrDeviceSymbolVersionInvalid:
+		return "symbol version invalid"
+	case ErrDeviceNotifyHandleInvalid:
+		return "notification handle invalid"
 	default:
 		return fmt.Sprintf("ADS error 0x%04X", uint32(e))
 	}
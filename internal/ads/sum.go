@@ -0,0 +1,346 @@
+package ads
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// MaxSumCommands is the maximum number of sub-commands a TwinCAT runtime
+// accepts in a single sum command request.
+const MaxSumCommands = 500
+
+// SumReadItem describes a single read inside a sum read request.
+type SumReadItem struct {
+	IndexGroup  uint32
+	IndexOffset uint32
+	Length      uint32
+}
+
+// SumReadRequest bundles several reads into one ADS ReadWrite request.
+// IndexGroup: 0xF080, IndexOffset: number of sub-commands
+type SumReadRequest struct {
+	Items []SumReadItem
+}
+
+func (r *SumReadRequest) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 12*len(r.Items))
+	for i, item := range r.Items {
+		offset := i * 12
+		binary.LittleEndian.PutUint32(buf[offset:offset+4], item.IndexGroup)
+		binary.LittleEndian.PutUint32(buf[offset+4:offset+8], item.IndexOffset)
+		binary.LittleEndian.PutUint32(buf[offset+8:offset+12], item.Length)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a sum read request. Items must be allocated with the
+// number of sub-commands (the IndexOffset of the request) before unmarshalling.
+func (r *SumReadRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < 12*len(r.Items) {
+		return fmt.Errorf("ads: sum read request requires %d bytes, got %d", 12*len(r.Items), len(data))
+	}
+	for i := range r.Items {
+		offset := i * 12
+		r.Items[i] = SumReadItem{
+			IndexGroup:  binary.LittleEndian.Uint32(data[offset : offset+4]),
+			IndexOffset: binary.LittleEndian.Uint32(data[offset+4 : offset+8]),
+			Length:      binary.LittleEndian.Uint32(data[offset+8 : offset+12]),
+		}
+	}
+	return nil
+}
+
+// ReadLength returns the number of bytes the PLC returns for this request:
+// one 4-byte result per item followed by the requested data of every item.
+func (r *SumReadRequest) ReadLength() uint32 {
+	length := uint32(4 * len(r.Items))
+	for _, item := range r.Items {
+		length += item.Length
+	}
+	return length
+}
+
+// SumReadResponse contains the per-item results of a sum read.
+// Lengths must be set to the requested item lengths before unmarshalling,
+// because the PLC returns the data blocks back to back without length prefix.
+type SumReadResponse struct {
+	Lengths []uint32
+	Results []uint32
+	Data    [][]byte
+}
+
+func (r *SumReadResponse) UnmarshalBinary(data []byte) error {
+	count := len(r.Lengths)
+	if len(data) < 4*count {
+		return fmt.Errorf("ads: sum read response requires %d result bytes, got %d", 4*count, len(data))
+	}
+
+	r.Results = make([]uint32, count)
+	r.Data = make([][]byte, count)
+
+	offset := 0
+	for i := 0; i < count; i++ {
+		r.Results[i] = binary.LittleEndian.Uint32(data[offset : offset+4])
+		offset += 4
+	}
+
+	for i, length := range r.Lengths {
+		if offset+int(length) > len(data) {
+			return fmt.Errorf("ads: insufficient data for sum read item %d", i)
+		}
+		if r.Results[i] == 0 {
+			r.Data[i] = make([]byte, length)
+			copy(r.Data[i], data[offset:offset+int(length)])
+		}
+		offset += int(length)
+	}
+
+	return nil
+}
+
+// MarshalBinary encodes the response. Every data block is padded or cut to
+// its requested length, including the blocks of failed items.
+func (r *SumReadResponse) MarshalBinary() ([]byte, error) {
+	if len(r.Results) != len(r.Lengths) {
+		return nil, fmt.Errorf("ads: sum read response has %d results for %d items", len(r.Results), len(r.Lengths))
+	}
+
+	size := 4 * len(r.Lengths)
+	for _, length := range r.Lengths {
+		size += int(length)
+	}
+
+	buf := make([]byte, size)
+	for i, result := range r.Results {
+		binary.LittleEndian.PutUint32(buf[i*4:i*4+4], result)
+	}
+
+	offset := 4 * len(r.Lengths)
+	for i, length := range r.Lengths {
+		if i < len(r.Data) && r.Results[i] == 0 {
+			copy(buf[offset:offset+int(length)], r.Data[i])
+		}
+		offset += int(length)
+	}
+	return buf, nil
+}
+
+// SumWriteItem describes a single write inside a sum write request.
+type SumWriteItem struct {
+	IndexGroup  uint32
+	IndexOffset uint32
+	Data        []byte
+}
+
+// SumWriteRequest bundles several writes into one ADS ReadWrite request.
+// IndexGroup: 0xF081, IndexOffset: number of sub-commands
+type SumWriteRequest struct {
+	Items []SumWriteItem
+}
+
+func (r *SumWriteRequest) MarshalBinary() ([]byte, error) {
+	size := 12 * len(r.Items)
+	for _, item := range r.Items {
+		size += len(item.Data)
+	}
+
+	buf := make([]byte, size)
+	for i, item := range r.Items {
+		offset := i * 12
+		binary.LittleEndian.PutUint32(buf[offset:offset+4], item.IndexGroup)
+		binary.LittleEndian.PutUint32(buf[offset+4:offset+8], item.IndexOffset)
+		binary.LittleEndian.PutUint32(buf[offset+8:offset+12], uint32(len(item.Data)))
+	}
+
+	offset := 12 * len(r.Items)
+	for _, item := range r.Items {
+		copy(buf[offset:], item.Data)
+		offset += len(item.Data)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a sum write request. Items must be allocated with the
+// number of sub-commands (the IndexOffset of the request) before unmarshalling.
+func (r *SumWriteRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < 12*len(r.Items) {
+		return fmt.Errorf("ads: sum write request requires %d header bytes, got %d", 12*len(r.Items), len(data))
+	}
+
+	offset := 12 * len(r.Items)
+	for i := range r.Items {
+		header := data[i*12 : i*12+12]
+		length := int(binary.LittleEndian.Uint32(header[8:12]))
+		if offset+length > len(data) {
+			return fmt.Errorf("ads: insufficient data for sum write item %d", i)
+		}
+		r.Items[i] = SumWriteItem{
+			IndexGroup:  binary.LittleEndian.Uint32(header[0:4]),
+			IndexOffset: binary.LittleEndian.Uint32(header[4:8]),
+			Data:        make([]byte, length),
+		}
+		copy(r.Items[i].Data, data[offset:offset+length])
+		offset += length
+	}
+	return nil
+}
+
+// ReadLength returns the number of bytes the PLC returns: one 4-byte result per item.
+func (r *SumWriteRequest) ReadLength() uint32 {
+	return uint32(4 * len(r.Items))
+}
+
+// SumWriteResponse contains the per-item results of a sum write.
+// Count must be set to the number of items before unmarshalling.
+type SumWriteResponse struct {
+	Count   int
+	Results []uint32
+}
+
+func (r *SumWriteResponse) UnmarshalBinary(data []byte) error {
+	if len(data) < 4*r.Count {
+		return fmt.Errorf("ads: sum write response requires %d bytes, got %d", 4*r.Count, len(data))
+	}
+	r.Results = make([]uint32, r.Count)
+	for i := 0; i < r.Count; i++ {
+		r.Results[i] = binary.LittleEndian.Uint32(data[i*4 : i*4+4])
+	}
+	return nil
+}
+
+func (r *SumWriteResponse) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 4*len(r.Results))
+	for i, result := range r.Results {
+		binary.LittleEndian.PutUint32(buf[i*4:i*4+4], result)
+	}
+	return buf, nil
+}
+
+// SumReadWriteItem describes a single read/write inside a sum read/write request.
+type SumReadWriteItem struct {
+	IndexGroup  uint32
+	IndexOffset uint32
+	ReadLength  uint32
+	WriteData   []byte
+}
+
+// SumReadWriteRequest bundles several read/write operations into one ADS ReadWrite request.
+// IndexGroup: 0xF082, IndexOffset: number of sub-commands
+type SumReadWriteRequest struct {
+	Items []SumReadWriteItem
+}
+
+func (r *SumReadWriteRequest) MarshalBinary() ([]byte, error) {
+	size := 16 * len(r.Items)
+	for _, item := range r.Items {
+		size += len(item.WriteData)
+	}
+
+	buf := make([]byte, size)
+	for i, item := range r.Items {
+		offset := i * 16
+		binary.LittleEndian.PutUint32(buf[offset:offset+4], item.IndexGroup)
+		binary.LittleEndian.PutUint32(buf[offset+4:offset+8], item.IndexOffset)
+		binary.LittleEndian.PutUint32(buf[offset+8:offset+12], item.ReadLength)
+		binary.LittleEndian.PutUint32(buf[offset+12:offset+16], uint32(len(item.WriteData)))
+	}
+
+	offset := 16 * len(r.Items)
+	for _, item := range r.Items {
+		copy(buf[offset:], item.WriteData)
+		offset += len(item.WriteData)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a sum read/write request. Items must be allocated with
+// the number of sub-commands (the IndexOffset of the request) before unmarshalling.
+func (r *SumReadWriteRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < 16*len(r.Items) {
+		return fmt.Errorf("ads: sum read/write request requires %d header bytes, got %d", 16*len(r.Items), len(data))
+	}
+
+	offset := 16 * len(r.Items)
+	for i := range r.Items {
+		header := data[i*16 : i*16+16]
+		length := int(binary.LittleEndian.Uint32(header[12:16]))
+		if offset+length > len(data) {
+			return fmt.Errorf("ads: insufficient data for sum read/write item %d", i)
+		}
+		r.Items[i] = SumReadWriteItem{
+			IndexGroup:  binary.LittleEndian.Uint32(header[0:4]),
+			IndexOffset: binary.LittleEndian.Uint32(header[4:8]),
+			ReadLength:  binary.LittleEndian.Uint32(header[8:12]),
+			WriteData:   make([]byte, length),
+		}
+		copy(r.Items[i].WriteData, data[offset:offset+length])
+		offset += length
+	}
+	return nil
+}
+
+// ReadLength returns the maximum number of bytes the PLC returns: a result and
+// length per item followed by up to ReadLength bytes of data per item.
+func (r *SumReadWriteRequest) ReadLength() uint32 {
+	length := uint32(8 * len(r.Items))
+	for _, item := range r.Items {
+		length += item.ReadLength
+	}
+	return length
+}
+
+// SumReadWriteResponse contains the per-item results of a sum read/write.
+// Unlike sum reads, the data blocks are packed using the lengths returned by the PLC.
+// Count must be set to the number of items before unmarshalling.
+type SumReadWriteResponse struct {
+	Count   int
+	Results []uint32
+	Data    [][]byte
+}
+
+func (r *SumReadWriteResponse) UnmarshalBinary(data []byte) error {
+	if len(data) < 8*r.Count {
+		return fmt.Errorf("ads: sum read/write response requires %d header bytes, got %d", 8*r.Count, len(data))
+	}
+
+	r.Results = make([]uint32, r.Count)
+	r.Data = make([][]byte, r.Count)
+	lengths := make([]uint32, r.Count)
+
+	for i := 0; i < r.Count; i++ {
+		r.Results[i] = binary.LittleEndian.Uint32(data[i*8 : i*8+4])
+		lengths[i] = binary.LittleEndian.Uint32(data[i*8+4 : i*8+8])
+	}
+
+	offset := 8 * r.Count
+	for i, length := range lengths {
+		if offset+int(length) > len(data) {
+			return fmt.Errorf("ads: insufficient data for sum read/write item %d", i)
+		}
+		r.Data[i] = make([]byte, length)
+		copy(r.Data[i], data[offset:offset+int(length)])
+		offset += int(length)
+	}
+
+	return nil
+}
+
+func (r *SumReadWriteResponse) MarshalBinary() ([]byte, error) {
+	if len(r.Data) != len(r.Results) {
+		return nil, fmt.Errorf("ads: sum read/write response has %d data blocks for %d results", len(r.Data), len(r.Results))
+	}
+
+	size := 8 * len(r.Results)
+	for _, data := range r.Data {
+		size += len(data)
+	}
+
+	buf := make([]byte, size)
+	offset := 8 * len(r.Results)
+	for i, result := range r.Results {
+		binary.LittleEndian.PutUint32(buf[i*8:i*8+4], result)
+		binary.LittleEndian.PutUint32(buf[i*8+4:i*8+8], uint32(len(r.Data[i])))
+		offset += copy(buf[offset:], r.Data[i])
+	}
+	return buf, nil
+}
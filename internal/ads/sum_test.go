@@ -0,0 +1,131 @@
+package ads
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestSumReadRequestMarshal(t *testing.T) {
+	req := SumReadRequest{Items: []SumReadItem{
+		{IndexGroup: 0x4020, IndexOffset: 0, Length: 2},
+		{IndexGroup: 0x4020, IndexOffset: 8, Length: 4},
+	}}
+
+	data, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	if len(data) != 24 {
+		t.Fatalf("expected 24 bytes, got %d", len(data))
+	}
+	if got := binary.LittleEndian.Uint32(data[16:20]); got != 8 {
+		t.Errorf("second item offset = %d, want 8", got)
+	}
+	if got := req.ReadLength(); got != 4*2+2+4 {
+		t.Errorf("ReadLength() = %d, want 14", got)
+	}
+}
+
+func TestSumReadResponseUnmarshal(t *testing.T) {
+	data := []byte{
+		0x00, 0x00, 0x00, 0x00, // item 0: ok
+		0x03, 0x07, 0x00, 0x00, // item 1: invalid index offset
+		0x2A, 0x00, // item 0 data
+		0x00, 0x00, 0x00, 0x00, // item 1 data (slot is still reserved)
+	}
+
+	resp := SumReadResponse{Lengths: []uint32{2, 4}}
+	if err := resp.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+
+	if resp.Results[0] != 0 || !bytes.Equal(resp.Data[0], []byte{0x2A, 0x00}) {
+		t.Errorf("item 0 = (%d, %v), want (0, [42 0])", resp.Results[0], resp.Data[0])
+	}
+	if Error(resp.Results[1]) != ErrDeviceInvalidIndexOffset || resp.Data[1] != nil {
+		t.Errorf("item 1 = (%d, %v), want (0x703, nil)", resp.Results[1], resp.Data[1])
+	}
+}
+
+func TestSumReadResponseTruncated(t *testing.T) {
+	resp := SumReadResponse{Lengths: []uint32{4}}
+	if err := resp.UnmarshalBinary([]byte{0, 0, 0, 0, 1}); err == nil {
+		t.Error("expected error for truncated response")
+	}
+}
+
+func TestSumWriteRequestMarshal(t *testing.T) {
+	req := SumWriteRequest{Items: []SumWriteItem{
+		{IndexGroup: 0x4020, IndexOffset: 0, Data: []byte{1, 2}},
+		{IndexGroup: 0x4020, IndexOffset: 4, Data: []byte{3}},
+	}}
+
+	data, _ := req.MarshalBinary()
+	want := []byte{1, 2, 3}
+	if !bytes.Equal(data[24:], want) {
+		t.Errorf("payload = %v, want %v", data[24:], want)
+	}
+	if got := binary.LittleEndian.Uint32(data[20:24]); got != 1 {
+		t.Errorf("second item length = %d, want 1", got)
+	}
+}
+
+func TestSumReadWriteResponseUnmarshal(t *testing.T) {
+	data := []byte{
+		0x00, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, // item 0: ok, 4 bytes
+		0x10, 0x07, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // item 1: symbol not found, 0 bytes
+		0x78, 0x56, 0x34, 0x12,
+	}
+
+	resp := SumReadWriteResponse{Count: 2}
+	if err := resp.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if got := binary.LittleEndian.Uint32(resp.Data[0]); got != 0x12345678 {
+		t.Errorf("item 0 data = 0x%X, want 0x12345678", got)
+	}
+	if resp.Results[1] != 0x710 || len(resp.Data[1]) != 0 {
+		t.Errorf("item 1 = (0x%X, %v), want (0x710, [])", resp.Results[1], resp.Data[1])
+	}
+}
+
+func TestSumRoundTrip(t *testing.T) {
+	write := SumWriteRequest{Items: []SumWriteItem{
+		{IndexGroup: 0x4020, IndexOffset: 0, Data: []byte{1, 2}},
+		{IndexGroup: 0x4020, IndexOffset: 4, Data: []byte{3}},
+	}}
+	data, _ := write.MarshalBinary()
+	gotWrite := SumWriteRequest{Items: make([]SumWriteItem, 2)}
+	if err := gotWrite.UnmarshalBinary(data); err != nil {
+		t.Fatalf("SumWriteRequest.UnmarshalBinary() error = %v", err)
+	}
+	if gotWrite.Items[1].IndexOffset != 4 || !bytes.Equal(gotWrite.Items[1].Data, []byte{3}) {
+		t.Errorf("sum write items = %+v", gotWrite.Items)
+	}
+
+	// Failed items keep their reserved data slot
+	readResp := SumReadResponse{
+		Lengths: []uint32{2, 4},
+		Results: []uint32{0, uint32(ErrDeviceInvalidIndexOffset)},
+		Data:    [][]byte{{0x2A, 0x00}, nil},
+	}
+	data, err := readResp.MarshalBinary()
+	if err != nil {
+		t.Fatalf("SumReadResponse.MarshalBinary() error = %v", err)
+	}
+	gotRead := SumReadResponse{Lengths: []uint32{2, 4}}
+	if err := gotRead.UnmarshalBinary(data); err != nil {
+		t.Fatalf("SumReadResponse.UnmarshalBinary() error = %v", err)
+	}
+	if len(data) != 14 || !bytes.Equal(gotRead.Data[0], []byte{0x2A, 0x00}) || Error(gotRead.Results[1]) != ErrDeviceInvalidIndexOffset {
+		t.Errorf("sum read response = %+v (%d bytes)", gotRead, len(data))
+	}
+
+	readWriteResp := SumReadWriteResponse{Results: []uint32{0, 0}, Data: [][]byte{{1, 0, 0, 0}, {2, 0, 0, 0}}}
+	data, _ = readWriteResp.MarshalBinary()
+	gotReadWrite := SumReadWriteResponse{Count: 2}
+	if err := gotReadWrite.UnmarshalBinary(data); err != nil || gotReadWrite.Data[1][0] != 2 {
+		t.Errorf("sum read/write response = %+v, err = %v", gotReadWrite, err)
+	}
+}
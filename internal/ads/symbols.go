@@ -9,10 +9,12 @@ import (
 // Symbol-related index groups as per ADS specification.
 const (
 	IndexGroupSymbolHandleByName  uint32 = 0xF003 // Get symbol handle by name
+	IndexGroupSymbolValueByName   uint32 = 0xF004 // Read/write symbol by name directly
+	IndexGroupSymbolValueByHandle uint32 = 0xF005 // Read/write symbol value by handle
 	IndexGroupReleaseSymbolHandle uint32 = 0xF006 // Release symbol handle
-	IndexGroupSymbolValueByName   uint32 = 0xF005 // Read/write symbol by name directly
 	IndexGroupSymbolInfoByName    uint32 = 0xF007 // Get symbol info by name
 	IndexGroupSymbolVersion       uint32 = 0xF008 // Get symbol version
+	IndexGroupSymbolInfoByNameEx  uint32 = 0xF009 // Get symbol entry by name, resolves pointers and references
 	IndexGroupSymbolUploadInfo    uint32 = 0xF00B // Get upload info (symbol count)
 	IndexGroupSymbolUpload        uint32 = 0xF00C // Upload symbol table
 	IndexGroupSymbolUploadInfo2   uint32 = 0xF00E // Extended upload info (TC3)
@@ -4,6 +4,8 @@ package ams
 import (
 	"encoding/binary"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 // NetID represents a 6-byte AMS NetID address (e.g., 192.168.1.100.1.1).
@@ -15,6 +17,24 @@ func (n NetID) String() string {
 	return fmt.Sprintf("%d.%d.%d.%d.%d.%d", n[0], n[1], n[2], n[3], n[4], n[5])
 }
 
+// ParseNetID parses the dot-separated form of a NetID, such as
+// "192.168.1.100.1.1": exactly six decimal octets from 0 to 255.
+func ParseNetID(s string) (NetID, error) {
+	var netID NetID
+	parts := strings.Split(s, ".")
+	if len(parts) != len(netID) {
+		return NetID{}, fmt.Errorf("invalid AMS NetID %q: want 6 dot-separated octets, got %d", s, len(parts))
+	}
+	for i, part := range parts {
+		octet, err := strconv.ParseUint(part, 10, 8)
+		if err != nil {
+			return NetID{}, fmt.Errorf("invalid AMS NetID %q: octet %q is not a number from 0 to 255", s, part)
+		}
+		netID[i] = byte(octet)
+	}
+	return netID, nil
+}
+
 // Port represents a 2-byte AMS port identifier.
 type Port uint16
 
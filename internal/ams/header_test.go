@@ -0,0 +1,22 @@
+package ams
+
+import "testing"
+
+func TestParseNetID(t *testing.T) {
+	netID, err := ParseNetID("192.168.1.100.1.1")
+	if err != nil {
+		t.Fatalf("ParseNetID() error = %v", err)
+	}
+	if want := (NetID{192, 168, 1, 100, 1, 1}); netID != want {
+		t.Errorf("ParseNetID() = %v, want %v", netID, want)
+	}
+
+	for _, s := range []string{
+		"", "5.1.2.3.1", "5.1.2.3.1.1.1", "5.1.2.3.1.1xyz", "5.1.2.3.1.256",
+		"5.1.2.3.-1.1", "5.1.2..1.1", "5.1.2.3.1.1.", " 5.1.2.3.1.1", "5.1.2.3.1.+1",
+	} {
+		if _, err := ParseNetID(s); err == nil {
+			t.Errorf("ParseNetID(%q) expected error", s)
+		}
+	}
+}
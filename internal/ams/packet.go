@@ -109,6 +109,10 @@ func ReadPacket(r io.Reader) (*Packet, error) {
 		return nil, fmt.Errorf("ams: unmarshal TCP header: %w", err)
 	}
 
+	if tcpHeader.Length < 32 {
+		return nil, fmt.Errorf("ams: TCP header length %d is shorter than the AMS header", tcpHeader.Length)
+	}
+
 	// Read AMS header + data (length from TCP header)
 	payloadBuf := make([]byte, tcpHeader.Length)
 	if _, err := io.ReadFull(r, payloadBuf); err != nil {
@@ -150,3 +154,49 @@ func WritePacket(w io.Writer, p *Packet) error {
 
 	return nil
 }
+
+// MarshalUDP encodes the packet as an AMS/UDP datagram: the AMS header and
+// data without the AMS/TCP header. The UDP state flag is set.
+func (p *Packet) MarshalUDP() ([]byte, error) {
+	header := p.Header
+	header.StateFlags |= StateFlagUDP
+	header.DataLength = uint32(len(p.Data))
+
+	amsBuf, err := header.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("ams: marshal AMS header: %w", err)
+	}
+
+	buf := make([]byte, len(amsBuf)+len(p.Data))
+	copy(buf, amsBuf)
+	copy(buf[len(amsBuf):], p.Data)
+	return buf, nil
+}
+
+// ParseUDPPacket decodes an AMS/UDP datagram. The data is copied, so the
+// datagram buffer can be reused.
+func ParseUDPPacket(datagram []byte) (*Packet, error) {
+	if len(datagram) < 32 {
+		return nil, fmt.Errorf("ams: UDP packet requires at least 32 bytes, got %d", len(datagram))
+	}
+
+	var header Header
+	if err := header.UnmarshalBinary(datagram[0:32]); err != nil {
+		return nil, fmt.Errorf("ams: unmarshal AMS header: %w", err)
+	}
+	if uint32(len(datagram)) < 32+header.DataLength {
+		return nil, fmt.Errorf("ams: insufficient data: expected %d bytes, got %d", 32+header.DataLength, len(datagram))
+	}
+
+	var data []byte
+	if header.DataLength > 0 {
+		data = make([]byte, header.DataLength)
+		copy(data, datagram[32:32+header.DataLength])
+	}
+
+	return &Packet{
+		TCPHeader: TCPHeader{Length: 32 + header.DataLength},
+		Header:    header,
+		Data:      data,
+	}, nil
+}
@@ -0,0 +1,448 @@
+package symbols
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// DataTypeBigType is the data type ID of structs, function blocks and other
+// types without a fixed primitive representation.
+const DataTypeBigType DataType = 65
+
+// Data type entry flags (ADSDATATYPEFLAG_*).
+const (
+	DataTypeFlagDataType    uint32 = 0x00000001
+	DataTypeFlagDataItem    uint32 = 0x00000002
+	DataTypeFlagReferenceTo uint32 = 0x00000004
+	DataTypeFlagMethodDeref uint32 = 0x00000008
+	DataTypeFlagBitValues   uint32 = 0x00000020
+	DataTypeFlagPropItem    uint32 = 0x00000040
+	DataTypeFlagTypeGUID    uint32 = 0x00000080
+	DataTypeFlagPersistent  uint32 = 0x00000100
+	DataTypeFlagCopyMask    uint32 = 0x00000200
+	DataTypeFlagMethodInfos uint32 = 0x00000800
+	DataTypeFlagAttributes  uint32 = 0x00001000
+	DataTypeFlagEnumInfos   uint32 = 0x00002000
+	DataTypeFlagAligned     uint32 = 0x00010000
+	DataTypeFlagStatic      uint32 = 0x00020000
+)
+
+// dataTypeEntryHeaderSize is the fixed part of an AdsDatatypeEntry.
+const dataTypeEntryHeaderSize = 42
+
+// ArrayDimension describes one dimension of an array type.
+type ArrayDimension struct {
+	LowerBound int32
+	Elements   uint32
+}
+
+// UpperBound returns the highest valid index of the dimension.
+func (d ArrayDimension) UpperBound() int32 {
+	return d.LowerBound + int32(d.Elements) - 1
+}
+
+// EnumValue is a named value of an ENUM type.
+type EnumValue struct {
+	Name  string
+	Value int64
+}
+
+// DataTypeEntry is a parsed AdsDatatypeEntry from the data type upload (0xF011).
+// Sub-items (struct fields) only carry their own entry; the layout of their type
+// is a separate top-level entry referenced by Type.
+type DataTypeEntry struct {
+	Name       string
+	Type       string // Base or element type name
+	Comment    string
+	Size       uint32
+	Offset     uint32 // Byte offset for sub-items
+	DataType   DataType
+	Flags      uint32
+	GUID       [16]byte
+	ArrayInfo  []ArrayDimension
+	SubItems   []DataTypeEntry
+	Attributes map[string]string
+	EnumValues []EnumValue
+}
+
+// ParseDataTypeTable parses the raw data type upload (0xF011) into its top-level entries.
+func ParseDataTypeTable(data []byte) ([]DataTypeEntry, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("data type data is empty")
+	}
+
+	var entries []DataTypeEntry
+	offset := 0
+
+	for offset+4 <= len(data) {
+		entryLength := binary.LittleEndian.Uint32(data[offset : offset+4])
+		if entryLength == 0 {
+			break
+		}
+
+		if offset+int(entryLength) > len(data) {
+			return nil, fmt.Errorf("invalid entry length %d at offset %d", entryLength, offset)
+		}
+
+		entry, err := ParseDataTypeEntry(data[offset : offset+int(entryLength)])
+		if err != nil {
+			return nil, fmt.Errorf("parse data type at offset %d: %w", offset, err)
+		}
+
+		entries = append(entries, entry)
+		offset += int(entryLength)
+	}
+
+	return entries, nil
+}
+
+// ParseDataTypeEntry parses a single AdsDatatypeEntry including its sub-items.
+func ParseDataTypeEntry(data []byte) (DataTypeEntry, error) {
+	if len(data) < dataTypeEntryHeaderSize {
+		return DataTypeEntry{}, fmt.Errorf("data type entry too short: %d bytes", len(data))
+	}
+
+	entryLength := binary.LittleEndian.Uint32(data[0:4])
+	if entryLength > uint32(len(data)) {
+		return DataTypeEntry{}, fmt.Errorf("entry length %d exceeds data length %d", entryLength, len(data))
+	}
+	if entryLength >= dataTypeEntryHeaderSize {
+		data = data[:entryLength]
+	}
+
+	entry := DataTypeEntry{
+		Size:     binary.LittleEndian.Uint32(data[16:20]),
+		Offset:   binary.LittleEndian.Uint32(data[20:24]),
+		DataType: DataType(binary.LittleEndian.Uint32(data[24:28])),
+		Flags:    binary.LittleEndian.Uint32(data[28:32]),
+	}
+
+	nameLength := int(binary.LittleEndian.Uint16(data[32:34]))
+	typeLength := int(binary.LittleEndian.Uint16(data[34:36]))
+	commentLength := int(binary.LittleEndian.Uint16(data[36:38]))
+	arrayDims := int(binary.LittleEndian.Uint16(data[38:40]))
+	subItems := int(binary.LittleEndian.Uint16(data[40:42]))
+
+	r := entryReader{data: data, offset: dataTypeEntryHeaderSize}
+
+	var err error
+	if entry.Name, err = r.string(nameLength); err != nil {
+		return DataTypeEntry{}, fmt.Errorf("invalid name length: %w", err)
+	}
+	if entry.Type, err = r.string(typeLength); err != nil {
+		return DataTypeEntry{}, fmt.Errorf("invalid type length: %w", err)
+	}
+	if entry.Comment, err = r.string(commentLength); err != nil {
+		return DataTypeEntry{}, fmt.Errorf("invalid comment length: %w", err)
+	}
+
+	for i := 0; i < arrayDims; i++ {
+		dim, err := r.bytes(8)
+		if err != nil {
+			return DataTypeEntry{}, fmt.Errorf("array dimension %d: %w", i, err)
+		}
+		entry.ArrayInfo = append(entry.ArrayInfo, ArrayDimension{
+			LowerBound: int32(binary.LittleEndian.Uint32(dim[0:4])),
+			Elements:   binary.LittleEndian.Uint32(dim[4:8]),
+		})
+	}
+
+	for i := 0; i < subItems; i++ {
+		if r.offset+4 > len(data) {
+			return DataTypeEntry{}, fmt.Errorf("sub-item %d: truncated", i)
+		}
+		subLength := int(binary.LittleEndian.Uint32(data[r.offset : r.offset+4]))
+		subData, err := r.bytes(subLength)
+		if err != nil {
+			return DataTypeEntry{}, fmt.Errorf("sub-item %d: %w", i, err)
+		}
+		sub, err := ParseDataTypeEntry(subData)
+		if err != nil {
+			return DataTypeEntry{}, fmt.Errorf("sub-item %d: %w", i, err)
+		}
+		entry.SubItems = append(entry.SubItems, sub)
+	}
+
+	// The optional sections follow in flag order. Parsing stops quietly at the
+	// first truncated section, the fixed part of the entry is still valid.
+	if entry.Flags&DataTypeFlagTypeGUID != 0 {
+		guid, err := r.bytes(16)
+		if err != nil {
+			return entry, nil
+		}
+		copy(entry.GUID[:], guid)
+	}
+
+	if entry.Flags&DataTypeFlagCopyMask != 0 {
+		if _, err := r.bytes(int(entry.Size)); err != nil {
+			return entry, nil
+		}
+	}
+
+	if entry.Flags&DataTypeFlagMethodInfos != 0 {
+		count, err := r.uint16()
+		if err != nil {
+			return entry, nil
+		}
+		for i := 0; i < int(count); i++ {
+			if r.offset+4 > len(data) {
+				return entry, nil
+			}
+			methodLength := int(binary.LittleEndian.Uint32(data[r.offset : r.offset+4]))
+			if methodLength == 0 {
+				return entry, nil
+			}
+			if _, err := r.bytes(methodLength); err != nil {
+				return entry, nil
+			}
+		}
+	}
+
+	if entry.Flags&DataTypeFlagAttributes != 0 {
+		attributes, err := r.attributes()
+		if err != nil {
+			return entry, nil
+		}
+		entry.Attributes = attributes
+	}
+
+	if entry.Flags&DataTypeFlagEnumInfos != 0 {
+		values, err := r.enumValues(entry.DataType, int(entry.Size))
+		if err != nil {
+			return entry, nil
+		}
+		entry.EnumValues = values
+	}
+
+	return entry, nil
+}
+
+// TypeInfo converts the entry into a TypeInfo. Field types are resolved with
+// lookup; if lookup is nil or does not know a type, the field keeps the
+// information of its sub-item entry.
+func (e DataTypeEntry) TypeInfo(lookup func(typeName string) (TypeInfo, bool)) TypeInfo {
+	typeInfo := TypeInfo{
+		Name:       e.Name,
+		BaseType:   e.DataType,
+		Size:       e.Size,
+		Comment:    e.Comment,
+		Flags:      e.Flags,
+		GUID:       e.GUID,
+		Attributes: e.Attributes,
+		EnumValues: e.EnumValues,
+	}
+
+	if len(e.ArrayInfo) > 0 {
+		typeInfo.IsArray = true
+		typeInfo.ElementType = e.Type
+		typeInfo.ArrayInfo = e.ArrayInfo
+		for _, dim := range e.ArrayInfo {
+			typeInfo.ArrayDims = append(typeInfo.ArrayDims, dim.Elements)
+		}
+	}
+
+	if len(e.SubItems) > 0 {
+		typeInfo.IsStruct = true
+		typeInfo.Fields = make([]FieldInfo, 0, len(e.SubItems))
+		for _, sub := range e.SubItems {
+			typeInfo.Fields = append(typeInfo.Fields, sub.fieldInfo(lookup))
+		}
+	}
+
+	return typeInfo
+}
+
+// fieldInfo converts a sub-item entry into a FieldInfo.
+func (e DataTypeEntry) fieldInfo(lookup func(typeName string) (TypeInfo, bool)) FieldInfo {
+	field := FieldInfo{
+		Name:       e.Name,
+		Offset:     e.Offset,
+		Comment:    e.Comment,
+		Attributes: e.Attributes,
+	}
+
+	// BIT members give offset and size in bits relative to the struct start
+	if e.Flags&DataTypeFlagBitValues != 0 {
+		field.Offset = e.Offset / 8
+		field.BitOffset = uint8(e.Offset % 8)
+		field.BitSize = uint8(e.Size)
+		field.Type = TypeInfo{
+			Name:     e.Type,
+			BaseType: e.DataType,
+			Size:     (uint32(field.BitOffset) + e.Size + 7) / 8,
+		}
+		return field
+	}
+
+	if lookup != nil {
+		if fieldType, ok := lookup(e.Type); ok {
+			field.Type = fieldType
+			return field
+		}
+	}
+
+	field.Type = TypeInfo{
+		Name:     e.Type,
+		BaseType: e.DataType,
+		Size:     e.Size,
+		IsStruct: e.DataType == DataTypeBigType && len(e.ArrayInfo) == 0,
+		GUID:     e.GUID,
+	}
+	if len(e.ArrayInfo) > 0 {
+		field.Type.IsArray = true
+		field.Type.ElementType = elementTypeName(e.Type)
+		field.Type.ArrayInfo = e.ArrayInfo
+		for _, dim := range e.ArrayInfo {
+			field.Type.ArrayDims = append(field.Type.ArrayDims, dim.Elements)
+		}
+	}
+	return field
+}
+
+// ResolveDataTypes converts uploaded data type entries into TypeInfos keyed by
+// type name, with nested field types resolved against the other entries.
+func ResolveDataTypes(entries []DataTypeEntry) map[string]TypeInfo {
+	byName := make(map[string]*DataTypeEntry, len(entries))
+	for i := range entries {
+		byName[entries[i].Name] = &entries[i]
+	}
+
+	resolved := make(map[string]TypeInfo, len(entries))
+	resolving := make(map[string]bool)
+
+	var lookup func(typeName string) (TypeInfo, bool)
+	lookup = func(typeName string) (TypeInfo, bool) {
+		if typeInfo, ok := resolved[typeName]; ok {
+			return typeInfo, true
+		}
+		entry, ok := byName[typeName]
+		if !ok || resolving[typeName] {
+			// Unknown or recursive type (only possible through pointers)
+			return TypeInfo{}, false
+		}
+
+		resolving[typeName] = true
+		typeInfo := entry.TypeInfo(lookup)
+		delete(resolving, typeName)
+
+		resolved[typeName] = typeInfo
+		return typeInfo, true
+	}
+
+	for name := range byName {
+		lookup(name)
+	}
+
+	return resolved
+}
+
+// entryReader reads the variable-length parts of a data type entry.
+type entryReader struct {
+	data   []byte
+	offset int
+}
+
+func (r *entryReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.offset+n > len(r.data) {
+		return nil, fmt.Errorf("need %d bytes at offset %d, have %d", n, r.offset, len(r.data)-r.offset)
+	}
+	b := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return b, nil
+}
+
+func (r *entryReader) uint16() (uint16, error) {
+	b, err := r.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+// string reads a string of the given length followed by its null terminator.
+func (r *entryReader) string(length int) (string, error) {
+	b, err := r.bytes(length + 1)
+	if err != nil {
+		return "", err
+	}
+	return parseString(b), nil
+}
+
+// attributes reads a count-prefixed list of name/value attribute pairs.
+func (r *entryReader) attributes() (map[string]string, error) {
+	count, err := r.uint16()
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := make(map[string]string, count)
+	for i := 0; i < int(count); i++ {
+		lengths, err := r.bytes(2)
+		if err != nil {
+			return nil, err
+		}
+		name, err := r.string(int(lengths[0]))
+		if err != nil {
+			return nil, err
+		}
+		value, err := r.string(int(lengths[1]))
+		if err != nil {
+			return nil, err
+		}
+		attributes[name] = value
+	}
+	return attributes, nil
+}
+
+// enumValues reads a count-prefixed list of enum names with values of the enum size.
+func (r *entryReader) enumValues(dataType DataType, size int) ([]EnumValue, error) {
+	count, err := r.uint16()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]EnumValue, 0, count)
+	for i := 0; i < int(count); i++ {
+		nameLength, err := r.bytes(1)
+		if err != nil {
+			return nil, err
+		}
+		name, err := r.string(int(nameLength[0]))
+		if err != nil {
+			return nil, err
+		}
+		raw, err := r.bytes(size)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, EnumValue{Name: name, Value: DecodeEnumValue(raw, dataType)})
+	}
+	return values, nil
+}
+
+// DecodeEnumValue decodes a little-endian enum value, sign-extending signed base types.
+func DecodeEnumValue(raw []byte, dataType DataType) int64 {
+	switch len(raw) {
+	case 1:
+		if dataType == DataTypeInt8 {
+			return int64(int8(raw[0]))
+		}
+		return int64(raw[0])
+	case 2:
+		v := binary.LittleEndian.Uint16(raw)
+		if dataType == DataTypeInt16 {
+			return int64(int16(v))
+		}
+		return int64(v)
+	case 4:
+		v := binary.LittleEndian.Uint32(raw)
+		if dataType == DataTypeInt32 {
+			return int64(int32(v))
+		}
+		return int64(v)
+	case 8:
+		return int64(binary.LittleEndian.Uint64(raw))
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,168 @@
+package symbols
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// testEntry describes a data type entry to encode for tests.
+type testEntry struct {
+	name, typeName, comment string
+	size, offset            uint32
+	dataType                DataType
+	flags                   uint32
+	arrayInfo               []ArrayDimension
+	subItems                []testEntry
+	tail                    []byte // optional sections after the sub-items
+}
+
+func (e testEntry) encode() []byte {
+	buf := make([]byte, dataTypeEntryHeaderSize)
+	binary.LittleEndian.PutUint32(buf[16:20], e.size)
+	binary.LittleEndian.PutUint32(buf[20:24], e.offset)
+	binary.LittleEndian.PutUint32(buf[24:28], uint32(e.dataType))
+	binary.LittleEndian.PutUint32(buf[28:32], e.flags)
+	binary.LittleEndian.PutUint16(buf[32:34], uint16(len(e.name)))
+	binary.LittleEndian.PutUint16(buf[34:36], uint16(len(e.typeName)))
+	binary.LittleEndian.PutUint16(buf[36:38], uint16(len(e.comment)))
+	binary.LittleEndian.PutUint16(buf[38:40], uint16(len(e.arrayInfo)))
+	binary.LittleEndian.PutUint16(buf[40:42], uint16(len(e.subItems)))
+
+	for _, s := range []string{e.name, e.typeName, e.comment} {
+		buf = append(buf, s...)
+		buf = append(buf, 0)
+	}
+	for _, dim := range e.arrayInfo {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(dim.LowerBound))
+		buf = binary.LittleEndian.AppendUint32(buf, dim.Elements)
+	}
+	for _, sub := range e.subItems {
+		buf = append(buf, sub.encode()...)
+	}
+	buf = append(buf, e.tail...)
+
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(buf)))
+	return buf
+}
+
+func TestParseDataTypeTable(t *testing.T) {
+	inner := testEntry{
+		name: "ST_Inner", size: 4, dataType: DataTypeBigType,
+		subItems: []testEntry{
+			{name: "value", typeName: "DINT", size: 4, dataType: DataTypeInt32},
+		},
+	}
+	outer := testEntry{
+		name: "ST_Outer", comment: "outer struct", size: 8, dataType: DataTypeBigType,
+		flags: DataTypeFlagDataType | DataTypeFlagAttributes,
+		subItems: []testEntry{
+			{name: "flag", typeName: "BOOL", size: 1, dataType: DataTypeBool, comment: "a flag"},
+			{name: "inner", typeName: "ST_Inner", size: 4, offset: 4, dataType: DataTypeBigType},
+		},
+		// 1 attribute: pack_mode := 1
+		tail: append([]byte{1, 0, 9, 1}, "pack_mode\x001\x00"...),
+	}
+	array := testEntry{
+		name: "ARRAY [1..3] OF INT", typeName: "INT", size: 6, dataType: DataTypeInt16,
+		arrayInfo: []ArrayDimension{{LowerBound: 1, Elements: 3}},
+	}
+
+	var data []byte
+	data = append(data, outer.encode()...)
+	data = append(data, inner.encode()...)
+	data = append(data, array.encode()...)
+
+	entries, err := ParseDataTypeTable(data)
+	if err != nil {
+		t.Fatalf("ParseDataTypeTable() error = %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	if entries[0].Attributes["pack_mode"] != "1" {
+		t.Errorf("attributes = %v, want pack_mode=1", entries[0].Attributes)
+	}
+
+	types := ResolveDataTypes(entries)
+
+	st := types["ST_Outer"]
+	if !st.IsStruct || len(st.Fields) != 2 || st.Comment != "outer struct" {
+		t.Fatalf("ST_Outer = %+v", st)
+	}
+	if st.Fields[0].Comment != "a flag" {
+		t.Errorf("field comment = %q, want %q", st.Fields[0].Comment, "a flag")
+	}
+	nested := st.Fields[1].Type
+	if !nested.IsStruct || len(nested.Fields) != 1 || nested.Fields[0].Name != "value" {
+		t.Errorf("nested field type not resolved: %+v", nested)
+	}
+
+	arr := types["ARRAY [1..3] OF INT"]
+	if !arr.IsArray || arr.ElementType != "INT" || len(arr.ArrayInfo) != 1 || arr.ArrayInfo[0].LowerBound != 1 {
+		t.Errorf("array type = %+v", arr)
+	}
+}
+
+func TestParseDataTypeEntryEnum(t *testing.T) {
+	tail := []byte{2, 0} // 2 enum values
+	tail = append(tail, 4)
+	tail = append(tail, "Idle\x00"...)
+	tail = append(tail, 0, 0)
+	tail = append(tail, 5)
+	tail = append(tail, "Error\x00"...)
+	tail = append(tail, 0xFF, 0xFF) // -1
+
+	entry := testEntry{
+		name: "E_State", typeName: "INT", size: 2, dataType: DataTypeInt16,
+		flags: DataTypeFlagDataType | DataTypeFlagEnumInfos,
+		tail:  tail,
+	}
+
+	parsed, err := ParseDataTypeEntry(entry.encode())
+	if err != nil {
+		t.Fatalf("ParseDataTypeEntry() error = %v", err)
+	}
+	want := []EnumValue{{Name: "Idle", Value: 0}, {Name: "Error", Value: -1}}
+	if len(parsed.EnumValues) != len(want) {
+		t.Fatalf("enum values = %v, want %v", parsed.EnumValues, want)
+	}
+	for i := range want {
+		if parsed.EnumValues[i] != want[i] {
+			t.Errorf("enum value %d = %v, want %v", i, parsed.EnumValues[i], want[i])
+		}
+	}
+}
+
+func TestParseDataTypeEntryBitFields(t *testing.T) {
+	entry := testEntry{
+		name: "ST_SafetyFlags", size: 2, dataType: DataTypeBigType,
+		subItems: []testEntry{
+			{name: "bEstop", typeName: "BIT", size: 1, offset: 0, dataType: DataTypeBool, flags: DataTypeFlagBitValues},
+			{name: "nMode", typeName: "BITARR8", size: 3, offset: 6, dataType: DataTypeBool, flags: DataTypeFlagBitValues},
+			{name: "bDoor", typeName: "BIT", size: 1, offset: 9, dataType: DataTypeBool, flags: DataTypeFlagBitValues},
+		},
+	}
+
+	parsed, err := ParseDataTypeEntry(entry.encode())
+	if err != nil {
+		t.Fatalf("ParseDataTypeEntry() error = %v", err)
+	}
+
+	fields := parsed.TypeInfo(nil).Fields
+	want := []struct {
+		offset             uint32
+		bitOffset, bitSize uint8
+		size               uint32
+	}{
+		{0, 0, 1, 1},
+		{0, 6, 3, 2}, // spans into the second byte
+		{1, 1, 1, 1},
+	}
+	for i, w := range want {
+		f := fields[i]
+		if f.Offset != w.offset || f.BitOffset != w.bitOffset || f.BitSize != w.bitSize || f.Type.Size != w.size {
+			t.Errorf("field %s = offset %d bit %d/%d size %d, want %+v",
+				f.Name, f.Offset, f.BitOffset, f.BitSize, f.Type.Size, w)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package symbols
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// EncodeSymbolEntry encodes a symbol as an AdsSymbolEntry, the format of the
+// symbol upload (0xF00B) and the symbol info by name (0xF009). Bit-valued
+// symbols are written with their size in bits.
+func EncodeSymbolEntry(s Symbol) []byte {
+	size := s.Size
+	flags := s.Flags
+	if s.BitSize > 0 {
+		size = s.BitSize
+		flags |= SymbolFlagBitValue
+	}
+
+	buf := make([]byte, 30)
+	binary.LittleEndian.PutUint32(buf[4:8], s.IndexGroup)
+	binary.LittleEndian.PutUint32(buf[8:12], s.IndexOffset)
+	binary.LittleEndian.PutUint32(buf[12:16], size)
+	binary.LittleEndian.PutUint32(buf[16:20], uint32(s.Type.BaseType))
+	binary.LittleEndian.PutUint32(buf[20:24], flags)
+	binary.LittleEndian.PutUint16(buf[24:26], uint16(len(s.Name)))
+	binary.LittleEndian.PutUint16(buf[26:28], uint16(len(s.Type.Name)))
+	binary.LittleEndian.PutUint16(buf[28:30], uint16(len(s.Comment)))
+
+	for _, str := range []string{s.Name, s.Type.Name, s.Comment} {
+		buf = append(buf, str...)
+		buf = append(buf, 0)
+	}
+
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(buf)))
+	return buf
+}
+
+// EncodeDataTypeEntry encodes a data type as an AdsDatatypeEntry, the format of
+// the data type upload (0xF011), including its sub-items. Of the optional
+// sections only the GUID, attributes and enum values are written; the flags of
+// the other sections are cleared.
+func EncodeDataTypeEntry(e DataTypeEntry) []byte {
+	flags := e.Flags &^ (DataTypeFlagCopyMask | DataTypeFlagMethodInfos)
+	if len(e.Attributes) > 0 {
+		flags |= DataTypeFlagAttributes
+	} else {
+		flags &^= DataTypeFlagAttributes
+	}
+	if len(e.EnumValues) > 0 {
+		flags |= DataTypeFlagEnumInfos
+	} else {
+		flags &^= DataTypeFlagEnumInfos
+	}
+
+	buf := make([]byte, dataTypeEntryHeaderSize)
+	binary.LittleEndian.PutUint32(buf[16:20], e.Size)
+	binary.LittleEndian.PutUint32(buf[20:24], e.Offset)
+	binary.LittleEndian.PutUint32(buf[24:28], uint32(e.DataType))
+	binary.LittleEndian.PutUint32(buf[28:32], flags)
+	binary.LittleEndian.PutUint16(buf[32:34], uint16(len(e.Name)))
+	binary.LittleEndian.PutUint16(buf[34:36], uint16(len(e.Type)))
+	binary.LittleEndian.PutUint16(buf[36:38], uint16(len(e.Comment)))
+	binary.LittleEndian.PutUint16(buf[38:40], uint16(len(e.ArrayInfo)))
+	binary.LittleEndian.PutUint16(buf[40:42], uint16(len(e.SubItems)))
+
+	for _, str := range []string{e.Name, e.Type, e.Comment} {
+		buf = append(buf, str...)
+		buf = append(buf, 0)
+	}
+	for _, dim := range e.ArrayInfo {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(dim.LowerBound))
+		buf = binary.LittleEndian.AppendUint32(buf, dim.Elements)
+	}
+	for _, sub := range e.SubItems {
+		buf = append(buf, EncodeDataTypeEntry(sub)...)
+	}
+
+	if flags&DataTypeFlagTypeGUID != 0 {
+		buf = append(buf, e.GUID[:]...)
+	}
+
+	if flags&DataTypeFlagAttributes != 0 {
+		names := make([]string, 0, len(e.Attributes))
+		for name := range e.Attributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(len(names)))
+		for _, name := range names {
+			value := e.Attributes[name]
+			buf = append(buf, byte(len(name)), byte(len(value)))
+			buf = append(buf, name...)
+			buf = append(buf, 0)
+			buf = append(buf, value...)
+			buf = append(buf, 0)
+		}
+	}
+
+	if flags&DataTypeFlagEnumInfos != 0 {
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(len(e.EnumValues)))
+		for _, v := range e.EnumValues {
+			buf = append(buf, byte(len(v.Name)))
+			buf = append(buf, v.Name...)
+			buf = append(buf, 0)
+			raw := make([]byte, max(e.Size, 8))
+			binary.LittleEndian.PutUint64(raw, uint64(v.Value))
+			buf = append(buf, raw[:e.Size]...)
+		}
+	}
+
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(buf)))
+	return buf
+}
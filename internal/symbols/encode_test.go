@@ -0,0 +1,63 @@
+package symbols
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeSymbolEntryRoundTrip(t *testing.T) {
+	want := []Symbol{
+		{Name: "MAIN.counter", Type: ParseTypeInfo("DINT", DataTypeInt32, 4), IndexGroup: 0x4020, IndexOffset: 8, Size: 4, Comment: "cycles"},
+		{Name: "MAIN.flag", Type: ParseTypeInfo("BIT", DataTypeBit, 1), IndexGroup: 0x4021, IndexOffset: 97, Size: 1, BitSize: 1, Flags: SymbolFlagBitValue},
+	}
+
+	var data []byte
+	for _, s := range want {
+		data = append(data, EncodeSymbolEntry(s)...)
+	}
+	got, err := ParseSymbolTable(data)
+	if err != nil {
+		t.Fatalf("ParseSymbolTable() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded symbols = %+v, want %+v", got, want)
+	}
+}
+
+func TestEncodeDataTypeEntryRoundTrip(t *testing.T) {
+	want := DataTypeEntry{
+		Name: "ST_Motor", Comment: "motor", Size: 8, DataType: DataTypeBigType,
+		Flags:      DataTypeFlagDataType | DataTypeFlagTypeGUID | DataTypeFlagAttributes,
+		GUID:       [16]byte{1, 2, 3},
+		Attributes: map[string]string{"pack_mode": "1", "hide": ""},
+		SubItems: []DataTypeEntry{
+			{Name: "speed", Type: "REAL", Size: 4, DataType: DataTypeReal32, Flags: DataTypeFlagDataItem},
+			{Name: "mode", Type: "E_Mode", Size: 2, Offset: 4, DataType: DataTypeInt16, Flags: DataTypeFlagDataItem},
+		},
+	}
+	enum := DataTypeEntry{
+		Name: "E_Mode", Type: "INT", Size: 2, DataType: DataTypeInt16,
+		Flags:      DataTypeFlagDataType | DataTypeFlagEnumInfos,
+		EnumValues: []EnumValue{{Name: "Off", Value: 0}, {Name: "Reverse", Value: -1}},
+	}
+	array := DataTypeEntry{
+		Name: "ARRAY [1..3] OF INT", Type: "INT", Size: 6, DataType: DataTypeInt16,
+		Flags:     DataTypeFlagDataType,
+		ArrayInfo: []ArrayDimension{{LowerBound: 1, Elements: 3}},
+	}
+
+	data := append(EncodeDataTypeEntry(want), EncodeDataTypeEntry(enum)...)
+	data = append(data, EncodeDataTypeEntry(array)...)
+	got, err := ParseDataTypeTable(data)
+	if err != nil {
+		t.Fatalf("ParseDataTypeTable() error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("decoded %d entries, want 3", len(got))
+	}
+	for i, entry := range []DataTypeEntry{want, enum, array} {
+		if !reflect.DeepEqual(got[i], entry) {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], entry)
+		}
+	}
+}
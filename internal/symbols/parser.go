@@ -4,6 +4,7 @@ package symbols
 import (
 	"encoding/binary"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -35,32 +36,52 @@ const (
 
 // TypeInfo represents parsed type information.
 type TypeInfo struct {
-	Name      string      // Type name
-	BaseType  DataType    // Base data type
-	Size      uint32      // Size in bytes
-	ArrayDims []uint32    // Array dimensions
-	IsArray   bool        // True if array type
-	IsStruct  bool        // True if struct type
-	Fields    []FieldInfo // Struct fields
-	Comment   string      // Type comment
+	Name        string            // Type name
+	BaseType    DataType          // Base data type
+	Size        uint32            // Size in bytes
+	ArrayDims   []uint32          // Array dimensions
+	IsArray     bool              // True if array type
+	IsStruct    bool              // True if struct type
+	Fields      []FieldInfo       // Struct fields
+	Comment     string            // Type comment
+	ElementType string            // Element type name for arrays from the data type table
+	ArrayInfo   []ArrayDimension  // Lower bound and element count per dimension
+	EnumValues  []EnumValue       // Named values for ENUM types
+	Attributes  map[string]string // PLC attributes ({attribute 'name' := 'value'})
+	GUID        [16]byte          // Type GUID, zero if not provided
+	Flags       uint32            // Data type entry flags (DataTypeFlag*)
 }
 
 // FieldInfo represents a struct field.
+// For BIT fields, Offset is the byte holding the first bit, BitOffset the bit
+// within that byte and BitSize the number of bits; Type.Size covers the spanned bytes.
 type FieldInfo struct {
-	Name      string
-	Offset    uint32
-	Type      TypeInfo
-	BitOffset uint8
-	BitSize   uint8
+	Name       string
+	Offset     uint32
+	Type       TypeInfo
+	BitOffset  uint8
+	BitSize    uint8 // 0 for byte-aligned fields
+	Comment    string
+	Attributes map[string]string
 }
 
+// Symbol flags (ADSSYMBOLFLAG_*).
+const (
+	SymbolFlagPersistent  uint32 = 0x0001
+	SymbolFlagBitValue    uint32 = 0x0002 // Size is given in bits
+	SymbolFlagReferenceTo uint32 = 0x0004
+	SymbolFlagTypeGUID    uint32 = 0x0008
+	SymbolFlagReadOnly    uint32 = 0x0020
+)
+
 // Symbol represents a parsed PLC symbol.
 type Symbol struct {
 	Name        string
 	Type        TypeInfo
 	IndexGroup  uint32
 	IndexOffset uint32
-	Size        uint32
+	Size        uint32 // Size in bytes, rounded up for bit-valued symbols
+	BitSize     uint32 // Size in bits for bit-valued symbols, 0 otherwise
 	Flags       uint32
 	Comment     string
 }
@@ -136,12 +157,20 @@ func parseSymbolEntry(data []byte) (Symbol, error) {
 	}
 	symbol.Comment = parseString(data[stringOffset : stringOffset+int(commentLength)+1])
 
-	symbol.Type = parseTypeInfo(typeName, DataType(dataTypeID), symbol.Size)
+	if symbol.Flags&SymbolFlagBitValue != 0 {
+		symbol.BitSize = symbol.Size
+		symbol.Size = (symbol.BitSize + 7) / 8
+	}
+
+	symbol.Type = ParseTypeInfo(typeName, DataType(dataTypeID), symbol.Size)
 
 	return symbol, nil
 }
 
-func parseTypeInfo(typeName string, dataTypeID DataType, size uint32) TypeInfo {
+// ParseTypeInfo builds type information from a type name as found in the
+// symbol table. Array types ("ARRAY [1..10, -5..5] OF REAL") get their bounds
+// and element type from the name.
+func ParseTypeInfo(typeName string, dataTypeID DataType, size uint32) TypeInfo {
 	typeInfo := TypeInfo{
 		Name:     typeName,
 		BaseType: dataTypeID,
@@ -150,23 +179,39 @@ func parseTypeInfo(typeName string, dataTypeID DataType, size uint32) TypeInfo {
 
 	if strings.Contains(typeName, "ARRAY") {
 		typeInfo.IsArray = true
-		typeInfo.ArrayDims = parseArrayDimensions(typeName)
+		typeInfo.ArrayInfo = parseArrayDimensions(typeName)
+		for _, dim := range typeInfo.ArrayInfo {
+			typeInfo.ArrayDims = append(typeInfo.ArrayDims, dim.Elements)
+		}
+		typeInfo.ElementType = elementTypeName(typeName)
 	}
 
-	if dataTypeID == 65 || !isSimpleType(dataTypeID) {
+	if dataTypeID == DataTypeBigType || !isSimpleType(dataTypeID) {
 		typeInfo.IsStruct = true
 	}
 
 	return typeInfo
 }
 
-func parseArrayDimensions(typeName string) []uint32 {
-	var dims []uint32
+// elementTypeName returns the element type of an array type name
+// ("ARRAY [0..9] OF INT" -> "INT"), or the name itself if it is no array.
+func elementTypeName(typeName string) string {
+	ofIndex := strings.Index(typeName, " OF ")
+	if ofIndex == -1 {
+		return typeName
+	}
+	return strings.TrimSpace(typeName[ofIndex+4:])
+}
+
+// parseArrayDimensions parses the bounds of the outermost array in a type name.
+// For "ARRAY [1..10, -5..5] OF REAL" it returns {1, 10} and {-5, 11}.
+func parseArrayDimensions(typeName string) []ArrayDimension {
+	var dims []ArrayDimension
 
 	start := strings.Index(typeName, "[")
 	end := strings.Index(typeName, "]")
 
-	if start == -1 || end == -1 {
+	if start == -1 || end == -1 || end < start {
 		return dims
 	}
 
@@ -175,12 +220,18 @@ func parseArrayDimensions(typeName string) []uint32 {
 
 	for _, r := range ranges {
 		parts := strings.Split(strings.TrimSpace(r), "..")
-		if len(parts) == 2 {
-			var low, high uint32
-			fmt.Sscanf(parts[0], "%d", &low)
-			fmt.Sscanf(parts[1], "%d", &high)
-			dims = append(dims, high-low+1)
+		if len(parts) != 2 {
+			continue
+		}
+		low, errLow := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 32)
+		high, errHigh := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 32)
+		if errLow != nil || errHigh != nil || high < low {
+			continue
 		}
+		dims = append(dims, ArrayDimension{
+			LowerBound: int32(low),
+			Elements:   uint32(high - low + 1),
+		})
 	}
 
 	return dims
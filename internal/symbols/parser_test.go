@@ -0,0 +1,21 @@
+package symbols
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTypeInfoArrayBounds(t *testing.T) {
+	typeInfo := ParseTypeInfo("ARRAY [1..10, -5..5] OF REAL", DataTypeReal32, 440)
+
+	want := []ArrayDimension{{LowerBound: 1, Elements: 10}, {LowerBound: -5, Elements: 11}}
+	if !reflect.DeepEqual(typeInfo.ArrayInfo, want) {
+		t.Fatalf("ArrayInfo = %+v, want %+v", typeInfo.ArrayInfo, want)
+	}
+	if typeInfo.ArrayInfo[1].UpperBound() != 5 {
+		t.Errorf("UpperBound() = %d, want 5", typeInfo.ArrayInfo[1].UpperBound())
+	}
+	if !reflect.DeepEqual(typeInfo.ArrayDims, []uint32{10, 11}) || typeInfo.ElementType != "REAL" {
+		t.Errorf("ArrayDims = %v, ElementType = %q", typeInfo.ArrayDims, typeInfo.ElementType)
+	}
+}
@@ -7,14 +7,16 @@ import (
 
 // TypeRegistry holds registered custom type definitions for automatic struct parsing.
 type TypeRegistry struct {
-	mu    sync.RWMutex
-	types map[string]TypeInfo
+	mu     sync.RWMutex
+	types  map[string]TypeInfo
+	cached map[string]bool // types fetched from the PLC rather than registered by the user
 }
 
 // NewTypeRegistry creates a new type registry.
 func NewTypeRegistry() *TypeRegistry {
 	return &TypeRegistry{
-		types: make(map[string]TypeInfo),
+		types:  make(map[string]TypeInfo),
+		cached: make(map[string]bool),
 	}
 }
 
@@ -23,6 +25,33 @@ func (r *TypeRegistry) Register(typeName string, typeInfo TypeInfo) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.types[typeName] = typeInfo
+	delete(r.cached, typeName)
+}
+
+// Cache adds a type definition that was fetched from the PLC.
+// Cached definitions are dropped by ClearCached, registered ones are kept.
+func (r *TypeRegistry) Cache(typeName string, typeInfo TypeInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types[typeName] = typeInfo
+	r.cached[typeName] = true
+}
+
+// IsCached reports whether a type definition was added with Cache.
+func (r *TypeRegistry) IsCached(typeName string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cached[typeName]
+}
+
+// ClearCached removes all type definitions added with Cache.
+func (r *TypeRegistry) ClearCached() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for typeName := range r.cached {
+		delete(r.types, typeName)
+	}
+	r.cached = make(map[string]bool)
 }
 
 // Get retrieves a type definition by name.
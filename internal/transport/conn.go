@@ -1,4 +1,4 @@
-// Package transport implements TCP transport for AMS/ADS communication.
+// Package transport implements TCP and UDP transport for AMS/ADS communication.
 package transport
 
 import (
@@ -41,6 +41,10 @@ func (s ConnectionState) String() string {
 	}
 }
 
+// Number of notification packets queued for the handler before the read loop
+// waits for it
+const notificationQueueSize = 256
+
 var (
 	ErrConnectionClosed = errors.New("connection closed")
 	ErrConnectionFailed = errors.New("connection failed")
@@ -49,6 +53,10 @@ var (
 // NotificationHandler is called when a notification packet is received.
 type NotificationHandler func(*ams.Packet)
 
+// Recorder is called with every packet sent and received on a connection, for
+// example to capture the traffic. It must not modify or retain the packet data.
+type Recorder func(sent bool, packet *ams.Packet)
+
 type Conn struct {
 	conn                net.Conn
 	mu                  sync.Mutex
@@ -56,14 +64,32 @@ type Conn struct {
 	timeout             time.Duration
 	invokeID            atomic.Uint32
 	responses           chan *pendingResponse
-	pending             map[uint32]chan<- *ams.Packet
+	readDone            chan struct{} // closed when readLoop returns
+	pending             map[pendingKey]chan<- *ams.Packet
 	pendingMu           sync.RWMutex
+	notifications       chan *ams.Packet // notification packets in arrival order
 	notificationHandler NotificationHandler
 	notifHandlerMu      sync.RWMutex
+	recorder            Recorder
+	recorderMu          sync.RWMutex
 	shutdownCtx         context.Context
 	shutdownCancel      context.CancelFunc
 	lastError           error
 	errorMu             sync.RWMutex
+
+	// AMS/UDP only, see DialUDP
+	udp        bool
+	retransmit time.Duration
+	recent     *recentIDs
+}
+
+// pendingKey identifies a request awaiting its response: the invoke ID and the
+// source port it was sent from, which the response targets. Connections shared
+// by several source ports (see goadstc.Router) pair responses with requests by
+// both.
+type pendingKey struct {
+	invokeID uint32
+	port     ams.Port
 }
 
 type pendingResponse struct {
@@ -105,22 +131,34 @@ func Dial(ctx context.Context, address string, timeout time.Duration) (*Conn, er
 		}
 	}
 
+	conn := newConn(netConn, timeout)
+	conn.start()
+	return conn, nil
+}
+
+// newConn wraps a connected socket and starts the read and dispatch loops.
+func newConn(netConn net.Conn, timeout time.Duration) *Conn {
 	shutdownCtx, shutdownCancel := context.WithCancel(context.Background())
 
-	conn := &Conn{
+	return &Conn{
 		conn:           netConn,
 		timeout:        timeout,
 		responses:      make(chan *pendingResponse, 16),
-		pending:        make(map[uint32]chan<- *ams.Packet),
+		readDone:       make(chan struct{}),
+		notifications:  make(chan *ams.Packet, notificationQueueSize),
+		pending:        make(map[pendingKey]chan<- *ams.Packet),
 		shutdownCtx:    shutdownCtx,
 		shutdownCancel: shutdownCancel,
 	}
-	conn.state.Store(int32(StateConnected))
+}
 
-	go conn.readLoop()
-	go conn.dispatchLoop()
+// start marks the connection as connected and starts its loops.
+func (c *Conn) start() {
+	c.state.Store(int32(StateConnected))
 
-	return conn, nil
+	go c.readLoop()
+	go c.dispatchLoop()
+	go c.notifyLoop()
 }
 
 func (c *Conn) Close() error {
@@ -164,7 +202,9 @@ func (c *Conn) CloseWithTimeout(timeout time.Duration) error {
 	// Close the network connection
 	err := c.conn.Close()
 
-	// Close response channel (will terminate dispatchLoop)
+	// Close response channel (will terminate dispatchLoop) once readLoop,
+	// its only sender, has noticed the closed connection
+	<-c.readDone
 	close(c.responses)
 
 	// Mark as fully closed
@@ -177,6 +217,11 @@ func (c *Conn) compareAndSwapState(old, new ConnectionState) bool {
 	return c.state.CompareAndSwap(int32(old), int32(new))
 }
 
+// State returns the current state of the connection.
+func (c *Conn) State() ConnectionState {
+	return c.getState()
+}
+
 func (c *Conn) getState() ConnectionState {
 	return ConnectionState(c.state.Load())
 }
@@ -199,12 +244,32 @@ func (c *Conn) NextInvokeID() uint32 {
 }
 
 // SetNotificationHandler sets the handler for notification packets (CommandID 0x0008).
+// The handler is called for one packet at a time, in arrival order; while it
+// runs, further packets are queued and reading stops once the queue is full.
 func (c *Conn) SetNotificationHandler(handler NotificationHandler) {
 	c.notifHandlerMu.Lock()
 	c.notificationHandler = handler
 	c.notifHandlerMu.Unlock()
 }
 
+// SetRecorder sets the recorder of sent and received packets, nil to stop recording.
+func (c *Conn) SetRecorder(recorder Recorder) {
+	c.recorderMu.Lock()
+	c.recorder = recorder
+	c.recorderMu.Unlock()
+}
+
+// record passes a packet to the recorder, if any.
+func (c *Conn) record(sent bool, packet *ams.Packet) {
+	c.recorderMu.RLock()
+	recorder := c.recorder
+	c.recorderMu.RUnlock()
+
+	if recorder != nil {
+		recorder(sent, packet)
+	}
+}
+
 func (c *Conn) SendRequest(ctx context.Context, req *ams.Packet) (*ams.Packet, error) {
 	state := c.getState()
 	if state != StateConnected {
@@ -215,15 +280,15 @@ func (c *Conn) SendRequest(ctx context.Context, req *ams.Packet) (*ams.Packet, e
 	}
 
 	respCh := make(chan *ams.Packet, 1)
-	invokeID := req.Header.InvokeID
+	key := pendingKey{invokeID: req.Header.InvokeID, port: req.Header.SourcePort}
 
 	c.pendingMu.Lock()
-	c.pending[invokeID] = respCh
+	c.pending[key] = respCh
 	c.pendingMu.Unlock()
 
 	defer func() {
 		c.pendingMu.Lock()
-		delete(c.pending, invokeID)
+		delete(c.pending, key)
 		c.pendingMu.Unlock()
 	}()
 
@@ -234,34 +299,66 @@ func (c *Conn) SendRequest(ctx context.Context, req *ams.Packet) (*ams.Packet, e
 		}
 	}
 
-	c.mu.Lock()
-	err := ams.WritePacket(c.conn, req)
-	c.mu.Unlock()
-
-	if err != nil {
+	if err := c.writePacket(req); err != nil {
 		c.setError(err)
 		return nil, fmt.Errorf("transport: write failed: %w", err)
 	}
 
-	select {
-	case resp := <-respCh:
-		if resp == nil {
-			if err := c.getError(); err != nil {
-				return nil, fmt.Errorf("transport: connection closed: %w", err)
+	// Datagrams may be lost, so UDP requests are sent again with the same
+	// invoke ID until a response arrives
+	var retransmit <-chan time.Time
+	if c.udp {
+		ticker := time.NewTicker(c.retransmit)
+		defer ticker.Stop()
+		retransmit = ticker.C
+	}
+
+	timeout := time.NewTimer(c.timeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case <-retransmit:
+			if err := c.writePacket(req); err != nil {
+				return nil, fmt.Errorf("transport: retransmit failed: %w", err)
+			}
+		case resp := <-respCh:
+			if resp == nil {
+				if err := c.getError(); err != nil {
+					return nil, fmt.Errorf("transport: connection closed: %w", err)
+				}
+				return nil, ErrConnectionClosed
 			}
+			return resp, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.shutdownCtx.Done():
 			return nil, ErrConnectionClosed
+		case <-timeout.C:
+			return nil, fmt.Errorf("transport: request timeout after %v", c.timeout)
 		}
-		return resp, nil
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case <-c.shutdownCtx.Done():
-		return nil, ErrConnectionClosed
-	case <-time.After(c.timeout):
-		return nil, fmt.Errorf("transport: request timeout after %v", c.timeout)
 	}
 }
 
+// writePacket sends a packet with the framing of the transport.
+func (c *Conn) writePacket(p *ams.Packet) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	if c.udp {
+		err = c.writeDatagram(p)
+	} else {
+		err = ams.WritePacket(c.conn, p)
+	}
+	if err == nil {
+		c.record(true, p)
+	}
+	return err
+}
+
 func (c *Conn) readLoop() {
+	defer close(c.readDone)
 	defer func() {
 		// Ensure connection is marked as closed if readLoop exits
 		if c.getState() == StateConnected {
@@ -269,6 +366,11 @@ func (c *Conn) readLoop() {
 		}
 	}()
 
+	var buf []byte
+	if c.udp {
+		buf = make([]byte, maxDatagramSize)
+	}
+
 	for {
 		select {
 		case <-c.shutdownCtx.Done():
@@ -280,6 +382,18 @@ func (c *Conn) readLoop() {
 			return
 		}
 
+		if c.udp {
+			packet, ok := c.readDatagram(buf)
+			if !ok {
+				return
+			}
+			if packet != nil {
+				c.record(false, packet)
+				c.responses <- &pendingResponse{invokeID: packet.Header.InvokeID, packet: packet}
+			}
+			continue
+		}
+
 		if c.timeout > 0 {
 			if err := c.conn.SetReadDeadline(time.Now().Add(c.timeout * 2)); err != nil {
 				c.setError(fmt.Errorf("failed to set read deadline: %w", err))
@@ -297,6 +411,7 @@ func (c *Conn) readLoop() {
 			return
 		}
 
+		c.record(false, packet)
 		c.responses <- &pendingResponse{
 			invokeID: packet.Header.InvokeID,
 			packet:   packet,
@@ -305,6 +420,8 @@ func (c *Conn) readLoop() {
 }
 
 func (c *Conn) dispatchLoop() {
+	defer close(c.notifications)
+
 	for resp := range c.responses {
 		if resp.err != nil {
 			// Error in read loop - initiate graceful close
@@ -314,19 +431,19 @@ func (c *Conn) dispatchLoop() {
 
 		// Check if this is a notification packet (CommandID 0x0008)
 		if resp.packet.Header.CommandID == 0x0008 {
-			c.notifHandlerMu.RLock()
-			handler := c.notificationHandler
-			c.notifHandlerMu.RUnlock()
-
-			if handler != nil {
-				go handler(resp.packet)
+			// Queued for notifyLoop; a full queue holds up the read loop
+			// rather than reordering samples
+			select {
+			case c.notifications <- resp.packet:
+			case <-c.shutdownCtx.Done():
 			}
 			continue
 		}
 
-		// Regular response packet - match by InvokeID
+		// Regular response packet - match by InvokeID and the port it targets;
+		// a response for another port does not complete the request
 		c.pendingMu.RLock()
-		ch, ok := c.pending[resp.invokeID]
+		ch, ok := c.pending[pendingKey{invokeID: resp.invokeID, port: resp.packet.Header.TargetPort}]
 		c.pendingMu.RUnlock()
 
 		if ok && ch != nil {
@@ -338,3 +455,17 @@ func (c *Conn) dispatchLoop() {
 		}
 	}
 }
+
+// notifyLoop passes notification packets to the handler one at a time, in the
+// order they were received, so the samples of a subscription keep their order.
+func (c *Conn) notifyLoop() {
+	for packet := range c.notifications {
+		c.notifHandlerMu.RLock()
+		handler := c.notificationHandler
+		c.notifHandlerMu.RUnlock()
+
+		if handler != nil {
+			handler(packet)
+		}
+	}
+}
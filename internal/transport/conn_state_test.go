@@ -88,7 +88,7 @@ func TestGracefulShutdown(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	conn := &Conn{
-		pending:        make(map[uint32]chan<- *ams.Packet),
+		pending:        make(map[pendingKey]chan<- *ams.Packet),
 		shutdownCtx:    ctx,
 		shutdownCancel: cancel,
 	}
@@ -97,8 +97,8 @@ func TestGracefulShutdown(t *testing.T) {
 	// Add some pending requests
 	ch1 := make(chan *ams.Packet, 1)
 	ch2 := make(chan *ams.Packet, 1)
-	conn.pending[1] = ch1
-	conn.pending[2] = ch2
+	conn.pending[pendingKey{invokeID: 1}] = ch1
+	conn.pending[pendingKey{invokeID: 2}] = ch2
 
 	// Shutdown should clear pending
 	go func() {
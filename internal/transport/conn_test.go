@@ -0,0 +1,112 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+func TestNotificationOrder(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+
+	const count = 1000
+	start := make(chan struct{})
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		<-start
+
+		// Notifications numbered by invoke ID, sent back to back
+		for i := uint32(1); i <= count; i++ {
+			packet := ams.NewRequestPacket(ams.NetID{}, 30000, ams.NetID{}, 851, 0x0008, i, []byte{0, 0, 0, 0})
+			if err := ams.WritePacket(conn, packet); err != nil {
+				return
+			}
+		}
+		time.Sleep(time.Second)
+	}()
+
+	conn, err := Dial(context.Background(), listener.Addr().String(), 5*time.Second)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+
+	received := make(chan uint32, count)
+	conn.SetNotificationHandler(func(packet *ams.Packet) {
+		// Uneven handler time would reorder concurrently handled packets
+		if packet.Header.InvokeID%7 == 0 {
+			time.Sleep(100 * time.Microsecond)
+		}
+		received <- packet.Header.InvokeID
+	})
+	close(start)
+
+	for want := uint32(1); want <= count; want++ {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("notification %d received as number %d", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for notification %d", want)
+		}
+	}
+}
+
+func TestResponseMatchedByPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := ams.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+
+		// A response with the invoke ID of the request for another port first
+		for _, port := range []ams.Port{req.Header.SourcePort + 1, req.Header.SourcePort} {
+			resp := ams.NewRequestPacket(req.Header.SourceNetID, port, req.Header.TargetNetID, req.Header.TargetPort,
+				req.Header.CommandID, req.Header.InvokeID, []byte{byte(port)})
+			resp.Header.StateFlags = ams.StateFlagsTCPResponse
+			if err := ams.WritePacket(conn, resp); err != nil {
+				return
+			}
+		}
+		time.Sleep(time.Second)
+	}()
+
+	conn, err := Dial(context.Background(), listener.Addr().String(), 2*time.Second)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+
+	req := ams.NewRequestPacket(ams.NetID{127, 0, 0, 1, 1, 1}, 851, ams.NetID{127, 0, 0, 1, 1, 2}, 32905,
+		0x0004, conn.NextInvokeID(), nil)
+	resp, err := conn.SendRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("SendRequest() error = %v", err)
+	}
+	if resp.Header.TargetPort != 32905 {
+		t.Errorf("request completed by the response for port %d", resp.Header.TargetPort)
+	}
+}
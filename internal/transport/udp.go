@@ -0,0 +1,134 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+const (
+	// DefaultUDPPort is the UDP port of the TwinCAT AMS router.
+	DefaultUDPPort = 48899
+
+	// DefaultRetransmitInterval is the wait before an unanswered UDP request is sent again.
+	DefaultRetransmitInterval = 200 * time.Millisecond
+
+	maxDatagramSize = 64 * 1024
+
+	// Number of incoming invoke IDs remembered for duplicate suppression
+	recentIDCount = 256
+)
+
+// DialUDP opens an AMS/UDP connection to address (host:port, usually port 48899).
+//
+// Each datagram carries one AMS header and its data without the AMS/TCP header,
+// with the UDP state flag set. There is no session: requests are correlated
+// with responses by invoke ID as over TCP, unanswered requests are sent again
+// every retransmit interval until the timeout expires, and duplicate datagrams
+// are dropped. Retransmitted requests may be executed twice by the target if
+// only the response was lost.
+func DialUDP(ctx context.Context, address string, timeout, retransmit time.Duration) (*Conn, error) {
+	if retransmit <= 0 {
+		retransmit = DefaultRetransmitInterval
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	netConn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("transport: dial udp %s: %w", address, err)
+	}
+
+	conn := newConn(netConn, timeout)
+	conn.udp = true
+	conn.retransmit = retransmit
+	conn.recent = newRecentIDs(recentIDCount)
+	conn.start()
+	return conn, nil
+}
+
+// writeDatagram sends a packet as one AMS/UDP datagram. Must be called with mu held.
+func (c *Conn) writeDatagram(p *ams.Packet) error {
+	buf, err := p.MarshalUDP()
+	if err != nil {
+		return err
+	}
+	_, err = c.conn.Write(buf)
+	return err
+}
+
+// readDatagram reads one datagram into buf. It returns nil for datagrams that
+// are malformed, duplicates or could not be read for transient reasons, and
+// ok=false once the socket is closed.
+func (c *Conn) readDatagram(buf []byte) (packet *ams.Packet, ok bool) {
+	n, err := c.conn.Read(buf)
+	if err != nil {
+		if errors.Is(err, net.ErrClosed) || c.getState() != StateConnected {
+			return nil, false
+		}
+		// Without a session, errors such as ICMP port unreachable only affect
+		// the requests in flight, which time out
+		return nil, true
+	}
+
+	packet, err = ams.ParseUDPPacket(buf[:n])
+	if err != nil {
+		return nil, true
+	}
+
+	// Responses to retransmitted requests are dropped by invoke ID matching;
+	// requests from the target, such as notifications, are checked here
+	if packet.Header.StateFlags&ams.StateFlagResponse == 0 &&
+		c.recent.seen(packet.Header.SourceNetID, packet.Header.SourcePort, packet.Header.InvokeID) {
+		return nil, true
+	}
+	return packet, true
+}
+
+// recentID identifies a request from a remote AMS address.
+type recentID struct {
+	netID    ams.NetID
+	port     ams.Port
+	invokeID uint32
+}
+
+// recentIDs remembers the last invoke IDs received to suppress duplicates.
+type recentIDs struct {
+	mu   sync.Mutex
+	ids  map[recentID]struct{}
+	ring []recentID
+	next int
+}
+
+func newRecentIDs(size int) *recentIDs {
+	return &recentIDs{
+		ids:  make(map[recentID]struct{}, size),
+		ring: make([]recentID, 0, size),
+	}
+}
+
+// seen reports whether the ID was received before and remembers it otherwise,
+// forgetting the oldest ID when full.
+func (r *recentIDs) seen(netID ams.NetID, port ams.Port, invokeID uint32) bool {
+	id := recentID{netID: netID, port: port, invokeID: invokeID}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.ids[id]; ok {
+		return true
+	}
+	if len(r.ring) < cap(r.ring) {
+		r.ring = append(r.ring, id)
+	} else {
+		delete(r.ids, r.ring[r.next])
+		r.ring[r.next] = id
+		r.next = (r.next + 1) % len(r.ring)
+	}
+	r.ids[id] = struct{}{}
+	return false
+}
@@ -0,0 +1,103 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+func TestUDPRetransmitAndDuplicates(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer server.Close()
+
+	var requests atomic.Int32
+	go func() {
+		buf := make([]byte, maxDatagramSize)
+		for {
+			n, addr, err := server.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			req, err := ams.ParseUDPPacket(buf[:n])
+			if err != nil || req.Header.StateFlags != ams.StateFlagsUDPRequest {
+				t.Errorf("unexpected request datagram: %v, flags %#x", err, req.Header.StateFlags)
+				return
+			}
+
+			// Lose the first request to force a retransmission
+			if requests.Add(1) == 1 {
+				continue
+			}
+
+			// Responses and notifications target the source of the request
+			header := req.Header
+			header.TargetNetID, header.TargetPort = req.Header.SourceNetID, req.Header.SourcePort
+			header.SourceNetID, header.SourcePort = req.Header.TargetNetID, req.Header.TargetPort
+
+			resp := &ams.Packet{Header: header, Data: []byte{1, 2, 3}}
+			resp.Header.StateFlags = ams.StateFlagsUDPResponse
+			notif := &ams.Packet{Header: header, Data: []byte{9}}
+			notif.Header.CommandID = 0x0008
+			notif.Header.InvokeID = 77
+
+			// Deliver response and notification twice each
+			for _, p := range []*ams.Packet{resp, resp, notif, notif} {
+				data, _ := p.MarshalUDP()
+				server.WriteTo(data, addr)
+			}
+		}
+	}()
+
+	conn, err := DialUDP(context.Background(), server.LocalAddr().String(), time.Second, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("DialUDP() error = %v", err)
+	}
+	defer conn.Close()
+
+	var notifications atomic.Int32
+	conn.SetNotificationHandler(func(*ams.Packet) { notifications.Add(1) })
+
+	req := ams.NewRequestPacket(ams.NetID{127, 0, 0, 1, 1, 1}, 851, ams.NetID{127, 0, 0, 1, 1, 2}, 32905,
+		0x0004, conn.NextInvokeID(), nil)
+	resp, err := conn.SendRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("SendRequest() error = %v", err)
+	}
+	if string(resp.Data) != "\x01\x02\x03" {
+		t.Errorf("response data = %v", resp.Data)
+	}
+	if got := requests.Load(); got < 2 {
+		t.Errorf("server received %d requests, want a retransmission", got)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if got := notifications.Load(); got != 1 {
+		t.Errorf("notification handler called %d times, want 1", got)
+	}
+}
+
+func TestRecentIDs(t *testing.T) {
+	recent := newRecentIDs(2)
+	netID := ams.NetID{1, 2, 3, 4, 1, 1}
+
+	if recent.seen(netID, 851, 1) || recent.seen(netID, 851, 2) {
+		t.Fatal("new IDs reported as seen")
+	}
+	if !recent.seen(netID, 851, 1) {
+		t.Error("duplicate ID not detected")
+	}
+	if recent.seen(netID, 852, 1) {
+		t.Error("ID from another port reported as seen")
+	}
+	// 852/1 replaced 851/1 as the oldest entry
+	if recent.seen(netID, 851, 1) {
+		t.Error("evicted ID reported as seen")
+	}
+}
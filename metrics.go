@@ -37,6 +37,15 @@ type Metrics interface {
 	HealthCheckCompleted(success bool)
 }
 
+// HandlerMetrics is implemented by Metrics that also count problems of
+// notification callbacks. It is optional, so existing Metrics implementations
+// keep compiling; callbacks report to it when the configured Metrics has these
+// methods.
+type HandlerMetrics interface {
+	NotificationHandlerPanicked()
+	NotificationHandlerSlow(duration time.Duration)
+}
+
 // noopMetrics implements Metrics with no-op operations for minimal overhead.
 type noopMetrics struct{}
 
@@ -52,6 +61,8 @@ func (n *noopMetrics) BytesReceived(bytes int64)
 func (n *noopMetrics) NotificationReceived()                                                  {}
 func (n *noopMetrics) NotificationDropped()                                                   {}
 func (n *noopMetrics) SubscriptionsActive(count int)                                          {}
+func (n *noopMetrics) NotificationHandlerPanicked()                                           {}
+func (n *noopMetrics) NotificationHandlerSlow(duration time.Duration)                         {}
 func (n *noopMetrics) ErrorOccurred(category ErrorCategory, operation string)                 {}
 func (n *noopMetrics) HealthCheckStarted()                                                    {}
 func (n *noopMetrics) HealthCheckCompleted(success bool)                                      {}
@@ -85,6 +96,8 @@ type InMemoryMetrics struct {
 	NotificationsReceivedCount atomic.Int64
 	NotificationsDroppedCount  atomic.Int64
 	SubscriptionsActiveCount   atomic.Int64
+	HandlerPanicsCount         atomic.Int64
+	SlowHandlersCount          atomic.Int64
 
 	// Error metrics
 	ErrorsByCategory  map[ErrorCategory]*atomic.Int64
@@ -173,6 +186,14 @@ func (m *InMemoryMetrics) SubscriptionsActive(count int) {
 	m.SubscriptionsActiveCount.Store(int64(count))
 }
 
+func (m *InMemoryMetrics) NotificationHandlerPanicked() {
+	m.HandlerPanicsCount.Add(1)
+}
+
+func (m *InMemoryMetrics) NotificationHandlerSlow(duration time.Duration) {
+	m.SlowHandlersCount.Add(1)
+}
+
 func (m *InMemoryMetrics) ErrorOccurred(category ErrorCategory, operation string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -216,6 +237,8 @@ func (m *InMemoryMetrics) Snapshot() MetricsSnapshot {
 		NotificationsReceived: m.NotificationsReceivedCount.Load(),
 		NotificationsDropped:  m.NotificationsDroppedCount.Load(),
 		SubscriptionsActive:   m.SubscriptionsActiveCount.Load(),
+		HandlerPanics:         m.HandlerPanicsCount.Load(),
+		SlowHandlers:          m.SlowHandlersCount.Load(),
 		HealthChecksStarted:   m.HealthChecksStartedCount.Load(),
 		HealthChecksSuccess:   m.HealthChecksSuccessCount.Load(),
 		HealthChecksFailure:   m.HealthChecksFailureCount.Load(),
@@ -256,6 +279,8 @@ type MetricsSnapshot struct {
 	NotificationsReceived int64
 	NotificationsDropped  int64
 	SubscriptionsActive   int64
+	HandlerPanics         int64
+	SlowHandlers          int64
 	HealthChecksStarted   int64
 	HealthChecksSuccess   int64
 	HealthChecksFailure   int64
@@ -93,17 +93,21 @@ func (m *Middleware) WriteSymbol(ctx context.Context, symbolName string, value i
 	}, nil
 }
 
-// BatchWrite writes multiple symbols
+// BatchWrite writes multiple symbols in a single sum write request
 func (m *Middleware) BatchWrite(ctx context.Context, writes map[string]interface{}) (*BatchWriteResponse, error) {
 	if len(writes) > m.config.Middleware.MaxBatchSize {
 		return nil, NewBatchSizeExceededError(len(writes), m.config.Middleware.MaxBatchSize)
 	}
 
+	writeResults, err := m.client.WriteMultipleSymbolValues(ctx, writes)
+	if err != nil {
+		return nil, NewInternalError(fmt.Sprintf("batch write failed: %v", err))
+	}
+
 	results := make(map[string]bool)
 	errors := make(map[string]string)
 
-	for symbolName, value := range writes {
-		err := m.client.WriteSymbolValue(ctx, symbolName, value)
+	for symbolName, err := range writeResults {
 		if err != nil {
 			results[symbolName] = false
 			errors[symbolName] = err.Error()
@@ -147,14 +151,7 @@ func (m *Middleware) GetSymbolTable(ctx context.Context) (*SymbolTableResponse,
 
 	symbols := make([]SymbolInfo, len(symbolsList))
 	for i, sym := range symbolsList {
-		symbols[i] = SymbolInfo{
-			Name:        sym.Name,
-			Type:        sym.Type.Name,
-			Size:        sym.Size,
-			IndexGroup:  sym.IndexGroup,
-			IndexOffset: sym.IndexOffset,
-			Comment:     sym.Comment,
-		}
+		symbols[i] = m.symbolInfo(ctx, sym)
 	}
 
 	return &SymbolTableResponse{
@@ -174,14 +171,8 @@ func (m *Middleware) GetSymbolInfo(ctx context.Context, symbolName string) (*Sym
 
 	for _, sym := range symbolsList {
 		if sym.Name == symbolName {
-			return &SymbolInfo{
-				Name:        sym.Name,
-				Type:        sym.Type.Name,
-				Size:        sym.Size,
-				IndexGroup:  sym.IndexGroup,
-				IndexOffset: sym.IndexOffset,
-				Comment:     sym.Comment,
-			}, nil
+			info := m.symbolInfo(ctx, sym)
+			return &info, nil
 		}
 	}
 
@@ -301,3 +292,17 @@ func symbolToInfo(sym *symbols.Symbol) SymbolInfo {
 		Comment:     sym.Comment,
 	}
 }
+
+// symbolInfo converts a symbol to SymbolInfo, including enum members for ENUM types
+func (m *Middleware) symbolInfo(ctx context.Context, sym *symbols.Symbol) SymbolInfo {
+	info := symbolToInfo(sym)
+
+	members, err := m.client.GetEnumMembers(ctx, sym.Type.Name)
+	if err != nil {
+		return info
+	}
+	for _, member := range members {
+		info.EnumMembers = append(info.EnumMembers, EnumMember{Name: member.Name, Value: member.Value})
+	}
+	return info
+}
@@ -28,15 +28,13 @@ type Server struct {
 // NewServer creates a new HTTP server
 func NewServer(config *Config) (*Server, error) {
 	// Parse AMS Net IDs
-	var plcNetID ams.NetID
-	if _, err := fmt.Sscanf(config.PLC.AMSNetID, "%d.%d.%d.%d.%d.%d",
-		&plcNetID[0], &plcNetID[1], &plcNetID[2], &plcNetID[3], &plcNetID[4], &plcNetID[5]); err != nil {
+	plcNetID, err := ams.ParseNetID(config.PLC.AMSNetID)
+	if err != nil {
 		return nil, fmt.Errorf("invalid PLC AMS Net ID: %w", err)
 	}
 
-	var sourceNetID ams.NetID
-	if _, err := fmt.Sscanf(config.PLC.SourceNetID, "%d.%d.%d.%d.%d.%d",
-		&sourceNetID[0], &sourceNetID[1], &sourceNetID[2], &sourceNetID[3], &sourceNetID[4], &sourceNetID[5]); err != nil {
+	sourceNetID, err := ams.ParseNetID(config.PLC.SourceNetID)
+	if err != nil {
 		return nil, fmt.Errorf("invalid source AMS Net ID: %w", err)
 	}
 
@@ -67,6 +67,14 @@ type SymbolInfo struct {
 	IndexGroup  uint32 `json:"index_group"`
 	IndexOffset uint32 `json:"index_offset"`
 	Comment     string `json:"comment,omitempty"`
+	// EnumMembers lists the named values if the symbol is an ENUM
+	EnumMembers []EnumMember `json:"enum_members,omitempty"`
+}
+
+// EnumMember represents a named value of an ENUM type
+type EnumMember struct {
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
 }
 
 // SymbolTableResponse represents the symbol table response
@@ -0,0 +1,242 @@
+package goadstc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+	"github.com/mrpasztoradam/goadstc/internal/transport"
+)
+
+// firstRouterPort is the first virtual source port handed out by a Router.
+const firstRouterPort ams.Port = 32905
+
+// Router multiplexes many Clients over a single connection per target, like
+// the AMS router of a TwinCAT system. Each Client using the router gets a
+// virtual source port of its own, so the PLC sees distinct NetID/port pairs on
+// one TCP connection instead of one connection per client with the same port.
+//
+// Responses are matched by invoke ID and by the port they target, so a request
+// is only completed by a response for its own Client, and notifications are
+// delivered to the Client whose port they target.
+type Router struct {
+	mu       sync.Mutex
+	targets  map[string]*routerTarget // by address
+	ports    map[ams.Port]struct{}    // allocated virtual ports
+	nextPort ams.Port
+	closed   bool
+
+	// dialMu serializes opening target connections, so a target is dialed
+	// once while notifications keep being routed
+	dialMu sync.Mutex
+}
+
+// routerTarget is the shared connection to one target.
+type routerTarget struct {
+	address string
+	conn    *transport.Conn
+	routes  map[ams.Port]*routedConn // guarded by Router.mu
+}
+
+// NewRouter creates a router without connections. Targets are connected when
+// the first Client using them connects and disconnected when the last one is
+// closed.
+func NewRouter() *Router {
+	return &Router{
+		targets:  make(map[string]*routerTarget),
+		ports:    make(map[ams.Port]struct{}),
+		nextPort: firstRouterPort,
+	}
+}
+
+// WithRouter connects the client through a shared router connection
+// (optional). The client gets a virtual source port from the router, which
+// replaces the port set with WithSourcePort. The connection to a target uses
+// the timeout of the first client that opens it. The UDP transport cannot be
+// routed.
+func WithRouter(router *Router) Option {
+	return func(c *clientConfig) error {
+		if router == nil {
+			return fmt.Errorf("goadstc: router cannot be nil")
+		}
+		c.router = router
+		return nil
+	}
+}
+
+// Close closes all target connections. Clients using the router fail their
+// requests afterwards and cannot reconnect.
+func (r *Router) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	targets := r.targets
+	r.targets = make(map[string]*routerTarget)
+	r.mu.Unlock()
+
+	var firstErr error
+	for _, t := range targets {
+		if err := t.conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Connections returns the number of open target connections.
+func (r *Router) Connections() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.targets)
+}
+
+// allocatePort reserves a virtual source port for a client.
+func (r *Router) allocatePort() (ams.Port, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, fmt.Errorf("goadstc: router closed")
+	}
+	for i := 0; i < 1<<16; i++ {
+		port := r.nextPort
+		r.nextPort++
+		if r.nextPort < firstRouterPort {
+			r.nextPort = firstRouterPort
+		}
+		if _, used := r.ports[port]; !used {
+			r.ports[port] = struct{}{}
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("goadstc: no free router port")
+}
+
+// releasePort returns a virtual source port to the router.
+func (r *Router) releasePort(port ams.Port) {
+	r.mu.Lock()
+	delete(r.ports, port)
+	r.mu.Unlock()
+}
+
+// open routes a port through the connection to address, dialing it if there
+// is no working connection yet.
+func (r *Router) open(ctx context.Context, address string, timeout time.Duration, port ams.Port) (*routedConn, error) {
+	r.dialMu.Lock()
+	defer r.dialMu.Unlock()
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("goadstc: router closed")
+	}
+	t := r.targets[address]
+	var stale *transport.Conn
+	if t != nil && t.conn.State() != transport.StateConnected {
+		// Clients still on the broken connection fail and reconnect here
+		delete(r.targets, address)
+		stale = t.conn
+		t = nil
+	}
+	r.mu.Unlock()
+
+	if stale != nil {
+		stale.Close()
+	}
+
+	if t == nil {
+		conn, err := transport.Dial(ctx, address, timeout)
+		if err != nil {
+			return nil, err
+		}
+		t = &routerTarget{
+			address: address,
+			conn:    conn,
+			routes:  make(map[ams.Port]*routedConn),
+		}
+		conn.SetNotificationHandler(func(packet *ams.Packet) { r.dispatch(t, packet) })
+
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			conn.Close()
+			return nil, fmt.Errorf("goadstc: router closed")
+		}
+		r.targets[address] = t
+		r.mu.Unlock()
+	}
+
+	rc := &routedConn{router: r, target: t, port: port}
+	r.mu.Lock()
+	t.routes[port] = rc
+	r.mu.Unlock()
+	return rc, nil
+}
+
+// dispatch delivers a notification to the client owning its target port.
+func (r *Router) dispatch(t *routerTarget, packet *ams.Packet) {
+	r.mu.Lock()
+	var handler transport.NotificationHandler
+	if rc, ok := t.routes[packet.Header.TargetPort]; ok {
+		handler = rc.handler
+	}
+	r.mu.Unlock()
+
+	if handler != nil {
+		handler(packet)
+	}
+}
+
+// detach removes a route and closes the target connection once no client
+// uses it anymore.
+func (r *Router) detach(rc *routedConn) error {
+	r.mu.Lock()
+	t := rc.target
+	if t.routes[rc.port] != rc {
+		r.mu.Unlock()
+		return nil
+	}
+	delete(t.routes, rc.port)
+	last := len(t.routes) == 0 && r.targets[t.address] == t
+	if last {
+		delete(r.targets, t.address)
+	}
+	r.mu.Unlock()
+
+	if last {
+		return t.conn.Close()
+	}
+	return nil
+}
+
+// routedConn is the view of one client on a shared target connection.
+type routedConn struct {
+	router  *Router
+	target  *routerTarget
+	port    ams.Port
+	handler transport.NotificationHandler // guarded by Router.mu
+}
+
+func (c *routedConn) NextInvokeID() uint32 {
+	return c.target.conn.NextInvokeID()
+}
+
+func (c *routedConn) SendRequest(ctx context.Context, req *ams.Packet) (*ams.Packet, error) {
+	return c.target.conn.SendRequest(ctx, req)
+}
+
+func (c *routedConn) SetNotificationHandler(handler transport.NotificationHandler) {
+	c.router.mu.Lock()
+	c.handler = handler
+	c.router.mu.Unlock()
+}
+
+// Close detaches the client; the shared connection stays open for others.
+func (c *routedConn) Close() error {
+	return c.router.detach(c)
+}
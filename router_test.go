@@ -0,0 +1,102 @@
+package goadstc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/adssim"
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+)
+
+func newRoutedClient(t *testing.T, server *adssim.Server, router *Router) *Client {
+	t.Helper()
+	return connectSimulator(t, server, WithTimeout(300*time.Millisecond), WithAutoReconnect(true), WithRouter(router))
+}
+
+func TestRouterSharesConnection(t *testing.T) {
+	server := startServer(t, counterConfig)
+	router := NewRouter()
+	defer router.Close()
+	ctx := context.Background()
+
+	clients := []*Client{newRoutedClient(t, server, router), newRoutedClient(t, server, router)}
+	if clients[0].sourcePort == clients[1].sourcePort {
+		t.Fatalf("clients share source port %d", clients[0].sourcePort)
+	}
+	if n := server.Connections(); n != 1 {
+		t.Errorf("server has %d connections, want 1", n)
+	}
+
+	// Notifications reach the client whose port they target
+	subs := make([]*Subscription, len(clients))
+	for i, client := range clients {
+		var err error
+		subs[i], err = client.Subscribe(ctx, NotificationOptions{
+			IndexGroup:       0x4020,
+			Length:           4,
+			TransmissionMode: ads.TransModeOnChange,
+		})
+		if err != nil {
+			t.Fatalf("Subscribe() error = %v", err)
+		}
+	}
+	next := func(sub *Subscription) []byte {
+		t.Helper()
+		select {
+		case notif := <-sub.Notifications():
+			return notif.Data
+		case <-time.After(2 * time.Second):
+			t.Fatal("no notification received")
+			return nil
+		}
+	}
+	for _, sub := range subs {
+		next(sub) // Initial sample
+	}
+	if err := server.WriteSymbol("MAIN.counter", []byte{2, 0, 0, 0}); err != nil {
+		t.Fatalf("WriteSymbol() error = %v", err)
+	}
+	for i, sub := range subs {
+		if data := next(sub); data[0] != 2 {
+			t.Errorf("client %d sample = %v, want 2", i, data)
+		}
+	}
+
+	// Closing one client keeps the connection for the other
+	clients[0].Close()
+	if _, err := clients[1].ReadState(ctx); err != nil {
+		t.Errorf("ReadState() after closing other client error = %v", err)
+	}
+	if n := server.Notifications(); n != 1 {
+		t.Errorf("server has %d notifications, want 1", n)
+	}
+
+	clients[1].Close()
+	if n := router.Connections(); n != 0 {
+		t.Errorf("router has %d connections after closing all clients", n)
+	}
+}
+
+func TestRouterReconnect(t *testing.T) {
+	server := startServer(t, adssim.Config{})
+	router := NewRouter()
+	defer router.Close()
+
+	clients := []*Client{newRoutedClient(t, server, router), newRoutedClient(t, server, router)}
+
+	// Both clients lose the shared connection and come back on a new one
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultReset, Count: 1})
+	for _, client := range clients {
+		client.ReadState(context.Background())
+	}
+	for _, client := range clients {
+		waitReconnected(t, client)
+	}
+	if n := router.Connections(); n != 1 {
+		t.Errorf("router has %d connections, want 1", n)
+	}
+	if n := server.Connections(); n != 1 {
+		t.Errorf("server has %d connections, want 1", n)
+	}
+}
@@ -0,0 +1,315 @@
+package goadstc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/adssim"
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+// startSimulator starts a simulated PLC with config and connects a client to
+// it, see connectSimulator. Both are closed when the test ends.
+func startSimulator(t *testing.T, config adssim.Config, opts ...Option) (*adssim.Server, *Client) {
+	t.Helper()
+	server := startServer(t, config)
+	return server, connectSimulator(t, server, opts...)
+}
+
+// startServer starts a simulated PLC that is closed when the test ends.
+func startServer(t *testing.T, config adssim.Config) *adssim.Server {
+	t.Helper()
+	server, err := adssim.StartServer("127.0.0.1:0", config)
+	if err != nil {
+		t.Fatalf("StartServer() error = %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+// connectSimulator returns a client of server with a 2s timeout, closed when
+// the test ends. opts are applied last and may override the timeout.
+func connectSimulator(t *testing.T, server *adssim.Server, opts ...Option) *Client {
+	t.Helper()
+	opts = append([]Option{
+		WithTarget(server.Addr()),
+		WithAMSNetID(server.NetID()),
+		WithTimeout(2 * time.Second),
+	}, opts...)
+	client, err := New(opts...)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// counterSymbols returns count DINT symbols MAIN.v<i> holding i.
+func counterSymbols(count int) []adssim.Symbol {
+	symbols := make([]adssim.Symbol, count)
+	for i := range symbols {
+		symbols[i] = adssim.Symbol{
+			Name: fmt.Sprintf("MAIN.v%d", i), Type: "DINT", Value: binary.LittleEndian.AppendUint32(nil, uint32(i)),
+		}
+	}
+	return symbols
+}
+
+// simulatorConfig is a small PLC with elementary and struct symbols.
+var simulatorConfig = adssim.Config{
+	DeviceName: "TestPLC",
+	DataTypes: []symbols.DataTypeEntry{{
+		Name: "ST_Motor", Size: 8, DataType: symbols.DataTypeBigType, Flags: symbols.DataTypeFlagDataType,
+		SubItems: []symbols.DataTypeEntry{
+			{Name: "enabled", Type: "BOOL", Size: 1, DataType: symbols.DataTypeBool, Flags: symbols.DataTypeFlagDataItem},
+			{Name: "speed", Type: "REAL", Size: 4, Offset: 4, DataType: symbols.DataTypeReal32, Flags: symbols.DataTypeFlagDataItem},
+		},
+	}},
+	Symbols: []adssim.Symbol{
+		{Name: "MAIN.counter", Type: "DINT", Value: []byte{42, 0, 0, 0}},
+		{Name: "MAIN.level", Type: "INT"},
+		{Name: "MAIN.motor", Type: "ST_Motor"},
+	},
+}
+
+func TestSimulatorDeviceInfoAndState(t *testing.T) {
+	server, client := startSimulator(t, simulatorConfig)
+	ctx := context.Background()
+
+	info, err := client.ReadDeviceInfo(ctx)
+	if err != nil {
+		t.Fatalf("ReadDeviceInfo() error = %v", err)
+	}
+	if info.Name != "TestPLC" || info.MajorVersion != 3 || info.VersionBuild != 4024 {
+		t.Errorf("device info = %+v", info)
+	}
+
+	if err := client.WriteControl(ctx, ads.StateStop, 0, nil); err != nil {
+		t.Fatalf("WriteControl() error = %v", err)
+	}
+	state, err := client.ReadState(ctx)
+	if err != nil {
+		t.Fatalf("ReadState() error = %v", err)
+	}
+	if state.ADSState != ads.StateStop {
+		t.Errorf("ADS state = %v, want Stop", state.ADSState)
+	}
+	if got, _ := server.State(); got != ads.StateStop {
+		t.Errorf("server state = %v, want Stop", got)
+	}
+}
+
+func TestSimulatorSymbolsAndMemory(t *testing.T) {
+	server, client := startSimulator(t, simulatorConfig)
+	ctx := context.Background()
+
+	if err := client.RefreshSymbols(ctx); err != nil {
+		t.Fatalf("RefreshSymbols() error = %v", err)
+	}
+	value, err := client.ReadSymbolValue(ctx, "MAIN.counter")
+	if err != nil || value != int32(42) {
+		t.Errorf("ReadSymbolValue(MAIN.counter) = %v, %v, want 42", value, err)
+	}
+
+	if err := client.WriteSymbolValue(ctx, "MAIN.level", int16(-7)); err != nil {
+		t.Fatalf("WriteSymbolValue() error = %v", err)
+	}
+	if data, _ := server.ReadSymbol("MAIN.level"); !bytes.Equal(data, []byte{0xF9, 0xFF}) {
+		t.Errorf("server value of MAIN.level = %v", data)
+	}
+
+	// Struct fields resolve through the data type table
+	if err := server.WriteSymbol("MAIN.motor.speed", binary.LittleEndian.AppendUint32(nil, 0x40490000)); err != nil {
+		t.Fatalf("WriteSymbol() error = %v", err)
+	}
+	speed, err := client.ReadSymbolValue(ctx, "MAIN.motor.speed")
+	if err != nil || speed != float32(3.140625) {
+		t.Errorf("ReadSymbolValue(MAIN.motor.speed) = %v, %v", speed, err)
+	}
+
+	values, err := client.ReadMultipleSymbolValues(ctx, "MAIN.counter", "MAIN.level")
+	if err != nil || values["MAIN.level"] != int16(-7) {
+		t.Errorf("ReadMultipleSymbolValues() = %v, %v", values, err)
+	}
+
+	symbol, _ := client.GetSymbol("MAIN.level")
+	if _, err := client.Read(ctx, symbol.IndexGroup, 1<<20, 2); !errors.Is(err, ads.ErrDeviceInvalidIndexOffset) {
+		t.Errorf("Read() beyond memory error = %v, want invalid index offset", err)
+	}
+}
+
+func TestSimulatorHandles(t *testing.T) {
+	server, client := startSimulator(t, simulatorConfig)
+	ctx := context.Background()
+
+	handle, err := client.GetSymbolHandle(ctx, "MAIN.counter")
+	if err != nil {
+		t.Fatalf("GetSymbolHandle() error = %v", err)
+	}
+	if err := client.WriteByHandle(ctx, handle, []byte{7, 0, 0, 0}); err != nil {
+		t.Fatalf("WriteByHandle() error = %v", err)
+	}
+	data, err := client.ReadByHandle(ctx, handle, 4)
+	if err != nil || !bytes.Equal(data, []byte{7, 0, 0, 0}) {
+		t.Errorf("ReadByHandle() = %v, %v", data, err)
+	}
+	if err := client.ReleaseSymbolHandle(ctx, handle); err != nil {
+		t.Fatalf("ReleaseSymbolHandle() error = %v", err)
+	}
+	if n := server.Handles(); n != 0 {
+		t.Errorf("server holds %d handles after release", n)
+	}
+
+	if _, err := client.GetSymbolHandle(ctx, "MAIN.missing"); !errors.Is(err, ads.ErrDeviceSymbolNotFound) {
+		t.Errorf("GetSymbolHandle(MAIN.missing) error = %v, want symbol not found", err)
+	}
+}
+
+func TestSimulatorNotifications(t *testing.T) {
+	server, client := startSimulator(t, simulatorConfig)
+	ctx := context.Background()
+
+	if err := client.RefreshSymbols(ctx); err != nil {
+		t.Fatalf("RefreshSymbols() error = %v", err)
+	}
+	sub, err := client.SubscribeSymbol(ctx, "MAIN.counter", SymbolNotificationOptions{
+		TransmissionMode: ads.TransModeOnChange,
+	})
+	if err != nil {
+		t.Fatalf("SubscribeSymbol() error = %v", err)
+	}
+
+	next := func() Notification {
+		t.Helper()
+		select {
+		case notif := <-sub.Notifications():
+			return notif
+		case <-time.After(2 * time.Second):
+			t.Fatal("no notification received")
+			return Notification{}
+		}
+	}
+
+	if notif := next(); notif.Value != int32(42) {
+		t.Errorf("first sample = %v, want 42", notif.Value)
+	}
+	if err := server.WriteSymbol("MAIN.counter", []byte{43, 0, 0, 0}); err != nil {
+		t.Fatalf("WriteSymbol() error = %v", err)
+	}
+	if notif := next(); notif.Value != int32(43) {
+		t.Errorf("sample after change = %v, want 43", notif.Value)
+	}
+
+	// Writing the same value again is no change
+	server.WriteSymbol("MAIN.counter", []byte{43, 0, 0, 0})
+	select {
+	case notif := <-sub.Notifications():
+		t.Errorf("unexpected sample %v", notif.Value)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	active := server.Notifications()
+	if err := sub.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if n := server.Notifications(); n != active-1 {
+		t.Errorf("server has %d notifications after close, want %d", n, active-1)
+	}
+}
+
+func TestSimulatorOnlineChange(t *testing.T) {
+	server, client := startSimulator(t, simulatorConfig)
+	ctx := context.Background()
+
+	if err := client.RefreshSymbols(ctx); err != nil {
+		t.Fatalf("RefreshSymbols() error = %v", err)
+	}
+	// The first sample of the symbol version watch, sent one PLC cycle after
+	// subscribing, sets the client's baseline
+	time.Sleep(100 * time.Millisecond)
+
+	version := server.SymbolVersion()
+	if err := server.AddSymbol(adssim.Symbol{Name: "MAIN.added", Type: "UDINT", Value: []byte{5, 0, 0, 0}}); err != nil {
+		t.Fatalf("AddSymbol() error = %v", err)
+	}
+	if got, err := client.ReadSymbolVersion(ctx); err != nil || got != version+1 {
+		t.Errorf("ReadSymbolVersion() = %d, %v, want %d", got, err, version+1)
+	}
+
+	// The client reloads its symbol table when the version changes
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		value, err := client.ReadSymbolValue(ctx, "MAIN.added")
+		if err == nil {
+			if value != uint32(5) {
+				t.Errorf("ReadSymbolValue(MAIN.added) = %v, want 5", value)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("added symbol not visible: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSimulatorScriptedFaults(t *testing.T) {
+	server, client := startSimulator(t, simulatorConfig)
+	ctx := context.Background()
+
+	// Counted faults fail consecutive requests in the order they were added
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultError, Command: ads.CmdReadState, Error: ads.ErrDeviceServiceNotSupported, Count: 1})
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultError, Command: ads.CmdReadState, Error: ads.ErrInternal, Count: 1})
+
+	want := []error{ads.ErrDeviceServiceNotSupported, ads.ErrInternal, nil}
+	for i, wantErr := range want {
+		_, err := client.ReadState(ctx)
+		if wantErr == nil && err != nil || wantErr != nil && !errors.Is(err, wantErr) {
+			t.Errorf("request %d error = %v, want %v", i, err, wantErr)
+		}
+	}
+	if n := server.FaultsTriggered(); n != 2 {
+		t.Errorf("faults triggered = %d, want 2", n)
+	}
+
+	// Faults for an index group leave other requests alone
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultError, IndexGroup: 0x4020, Error: ads.ErrDeviceInvalidSize})
+	if _, err := client.Read(ctx, 0x4020, 0, 4); !errors.Is(err, ads.ErrDeviceInvalidSize) {
+		t.Errorf("Read(0x4020) error = %v, want invalid size", err)
+	}
+	if _, err := client.ReadDeviceInfo(ctx); err != nil {
+		t.Errorf("ReadDeviceInfo() error = %v", err)
+	}
+	server.ClearFaults()
+	if _, err := client.Read(ctx, 0x4020, 0, 4); err != nil {
+		t.Errorf("Read() after ClearFaults error = %v", err)
+	}
+}
+
+func TestSimulatorProbabilisticFaults(t *testing.T) {
+	server, client := startSimulator(t, adssim.Config{
+		Memory:    map[uint32]uint32{0x4020: 4},
+		Faults:    []adssim.Fault{{Kind: adssim.FaultError, Command: ads.CmdRead, Error: ads.ErrInternal, Probability: 0.5}},
+		FaultSeed: 1,
+	})
+
+	const requests = 100
+	failed := 0
+	for i := 0; i < requests; i++ {
+		if _, err := client.Read(context.Background(), 0x4020, 0, 4); err != nil {
+			failed++
+		}
+	}
+	if failed == 0 || failed == requests {
+		t.Errorf("%d of %d requests failed", failed, requests)
+	}
+	if n := server.FaultsTriggered(); n != failed {
+		t.Errorf("faults triggered = %d, want %d", n, failed)
+	}
+}
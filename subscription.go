@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mrpasztoradam/goadstc/internal/ads"
@@ -13,19 +14,102 @@ import (
 type Notification struct {
 	Data      []byte
 	Timestamp time.Time
+
+	// Set for subscriptions created with SubscribeSymbol
+	Symbol string      // Symbol path as passed to SubscribeSymbol
+	Type   string      // PLC type name
+	Value  interface{} // Decoded value, as returned by ReadSymbolValue
+	Err    error       // Decoding error, Value is nil
+}
+
+// SubscriptionEventType identifies a state change of a subscription.
+type SubscriptionEventType int
+
+const (
+	// SubscriptionSuspended reports that the connection was lost; no
+	// notifications arrive until the subscription is restored.
+	SubscriptionSuspended SubscriptionEventType = iota
+	// SubscriptionRestored reports that the subscription was re-created on the
+	// PLC after a reconnect. The notification channel stays the same.
+	SubscriptionRestored
+	// SubscriptionRestoreFailed reports that re-creating the subscription after
+	// a reconnect failed. It is retried after the next reconnect.
+	SubscriptionRestoreFailed
+)
+
+// String returns the name of the event type.
+func (t SubscriptionEventType) String() string {
+	switch t {
+	case SubscriptionSuspended:
+		return "suspended"
+	case SubscriptionRestored:
+		return "restored"
+	case SubscriptionRestoreFailed:
+		return "restore_failed"
+	default:
+		return fmt.Sprintf("SubscriptionEventType(%d)", int(t))
+	}
+}
+
+// SubscriptionEvent reports a state change of a subscription.
+type SubscriptionEvent struct {
+	Type      SubscriptionEventType
+	Handle    uint32 // New PLC notification handle (SubscriptionRestored only)
+	OldHandle uint32 // Handle before the event
+	Err       error  // Cause for SubscriptionSuspended and SubscriptionRestoreFailed
+	Symbol    string // Member symbol path, for events of a GroupSubscription
+	Time      time.Time
 }
 
 // Subscription represents an active ADS notification subscription.
+// The Subscription and its channels stay valid across reconnects: the client
+// re-creates the notification on the PLC and only the handle changes.
 type Subscription struct {
-	handle   uint32
-	client   *Client
-	notifCh  chan Notification
-	closed   bool
-	closeMu  sync.Mutex
-	closeErr error
+	handle  uint32
+	client  *Client
+	notifCh chan Notification
+	events  chan SubscriptionEvent
+	active  bool // the handle is valid on the PLC
+	closed  bool
+	closeMu sync.Mutex // guards handle, active, closed, closeDone and sends on the channels
+
+	// Closed when the Close that deletes the notification on the PLC is done;
+	// closeErr is its result
+	closeDone chan struct{}
+	closeErr  error
+
+	// Closed by Close before it takes closeMu, to end an OverflowBlock wait
+	stop     chan struct{}
+	stopOnce sync.Once
 
 	// Stored for re-establishment after reconnect
 	opts NotificationOptions
+
+	// Owned by the client itself (e.g. symbol version watch), not re-established
+	internal bool
+
+	// Decodes samples of symbol subscriptions, nil for raw subscriptions
+	decoder *notificationDecoder
+
+	// Set for members of a group subscription, whose samples are delivered
+	// through the group instead of notifCh
+	group      *GroupSubscription
+	groupIndex int
+
+	dropped atomic.Uint64
+}
+
+// notificationDecoder decodes the samples of a symbol subscription.
+// Decoding can require type requests to the PLC, so it runs on its own goroutine
+// instead of the connection's read loop: samples are queued on rawCh and
+// forwarded to the subscription channel once decoded.
+type notificationDecoder struct {
+	symbol   string
+	typeName string
+	decode   func(ctx context.Context, data []byte) (interface{}, error)
+
+	rawCh chan Notification
+	done  chan struct{}
 }
 
 // NotificationOptions configures a notification subscription.
@@ -36,6 +120,10 @@ type NotificationOptions struct {
 	TransmissionMode ads.TransmissionMode
 	MaxDelay         time.Duration // Maximum delay before notification is sent
 	CycleTime        time.Duration // Cycle time for cyclic notifications
+
+	BufferSize     int            // Notification channel capacity, 0 for the default of 16
+	OverflowPolicy OverflowPolicy // What happens when the channel is full
+	BlockTimeout   time.Duration  // Maximum wait for OverflowBlock, 0 for the default of 1s
 }
 
 // SymbolNotificationOptions configures a symbol-based notification subscription.
@@ -43,6 +131,90 @@ type SymbolNotificationOptions struct {
 	TransmissionMode ads.TransmissionMode
 	MaxDelay         time.Duration // Maximum delay before notification is sent
 	CycleTime        time.Duration // Cycle time for cyclic notifications
+
+	BufferSize     int            // Notification channel capacity, 0 for the default of 16
+	OverflowPolicy OverflowPolicy // What happens when the channel is full
+	BlockTimeout   time.Duration  // Maximum wait for OverflowBlock, 0 for the default of 1s
+}
+
+// notificationOptions returns the options for subscribing to an address.
+func (o SymbolNotificationOptions) notificationOptions(indexGroup, indexOffset, length uint32) NotificationOptions {
+	return NotificationOptions{
+		IndexGroup:       indexGroup,
+		IndexOffset:      indexOffset,
+		Length:           length,
+		TransmissionMode: o.TransmissionMode,
+		MaxDelay:         o.MaxDelay,
+		CycleTime:        o.CycleTime,
+		BufferSize:       o.BufferSize,
+		OverflowPolicy:   o.OverflowPolicy,
+		BlockTimeout:     o.BlockTimeout,
+	}
+}
+
+// OverflowPolicy decides what happens to a notification when the
+// subscription channel is full. Every sample that is not delivered is counted
+// by Subscription.Dropped and reported through Metrics.NotificationDropped.
+type OverflowPolicy int
+
+const (
+	// OverflowDropNewest discards the incoming sample (default).
+	OverflowDropNewest OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest queued sample to make room.
+	OverflowDropOldest
+	// OverflowBlock waits up to BlockTimeout for room and then discards the
+	// sample. Notifications are handled one at a time in arrival order, so
+	// waiting holds up the connection: responses and samples of other
+	// subscriptions are delayed as well. Closing the subscription ends the
+	// wait.
+	OverflowBlock
+	// OverflowCoalesce keeps only the latest sample: the channel holds one
+	// value that is replaced by newer samples until it is received.
+	OverflowCoalesce
+)
+
+const (
+	defaultNotificationBuffer = 16
+	defaultBlockTimeout       = time.Second
+)
+
+// String returns the name of the overflow policy.
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDropNewest:
+		return "drop_newest"
+	case OverflowDropOldest:
+		return "drop_oldest"
+	case OverflowBlock:
+		return "block"
+	case OverflowCoalesce:
+		return "coalesce"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+	}
+}
+
+// bufferSize returns the channel capacity for the options.
+func (o NotificationOptions) bufferSize() int {
+	switch {
+	case o.OverflowPolicy == OverflowCoalesce:
+		return 1
+	case o.BufferSize > 0:
+		return o.BufferSize
+	default:
+		return defaultNotificationBuffer
+	}
+}
+
+// validate checks the buffering options.
+func (o NotificationOptions) validate() error {
+	if o.BufferSize < 0 {
+		return NewValidationError("subscribe", fmt.Sprintf("buffer size must not be negative, got %d", o.BufferSize))
+	}
+	if o.OverflowPolicy < OverflowDropNewest || o.OverflowPolicy > OverflowCoalesce {
+		return NewValidationError("subscribe", fmt.Sprintf("unknown overflow policy %d", int(o.OverflowPolicy)))
+	}
+	return nil
 }
 
 // Notifications returns the channel for receiving notifications.
@@ -51,78 +223,205 @@ func (s *Subscription) Notifications() <-chan Notification {
 	return s.notifCh
 }
 
+// Dropped returns the number of samples that were not delivered because the
+// notification channel was full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Events returns the channel of state changes, such as suspension on
+// connection loss and re-establishment after a reconnect. Events are dropped
+// if the channel is full. The channel is closed when the subscription is closed.
+func (s *Subscription) Events() <-chan SubscriptionEvent {
+	return s.events
+}
+
 // Handle returns the notification handle assigned by the PLC.
+// The handle changes when the subscription is re-established after a reconnect.
 func (s *Subscription) Handle() uint32 {
+	s.closeMu.Lock()
+	defer s.closeMu.Unlock()
 	return s.handle
 }
 
+// Active reports whether the subscription currently exists on the PLC.
+// It is false while the connection is down or re-establishment failed.
+func (s *Subscription) Active() bool {
+	s.closeMu.Lock()
+	defer s.closeMu.Unlock()
+	return s.active && !s.closed
+}
+
 // Close unsubscribes from the notification and closes the notification channel.
 // It's safe to call Close multiple times.
 func (s *Subscription) Close() error {
+	s.stopDelivery()
 	s.closeMu.Lock()
-	defer s.closeMu.Unlock()
 
 	if s.closed {
+		done := s.closeDone
+		s.closeMu.Unlock()
+		if done != nil {
+			<-done
+		}
 		return s.closeErr
 	}
 
 	s.closed = true
+	s.closeDone = make(chan struct{})
+	active, handle := s.active, s.handle
 
 	// Remove from client's registry
-	s.client.unregisterSubscription(s.handle)
+	s.client.unregisterSubscription(s)
+	s.closeChannel()
+	s.closeMu.Unlock()
 
-	// Delete notification on PLC
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// Delete notification on PLC, unless it was lost with the connection.
+	// closeMu is not held for the round trip, notify takes it for every sample.
+	if active {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		s.closeErr = s.client.deleteNotification(ctx, handle)
+		cancel()
+	}
+	close(s.closeDone)
+	return s.closeErr
+}
 
-	req := ads.DeleteDeviceNotificationRequest{
-		NotificationHandle: s.handle,
+// notify is called internally to send a notification to the subscription.
+func (s *Subscription) notify(data []byte, timestamp time.Time) {
+	s.closeMu.Lock()
+	defer s.closeMu.Unlock()
+
+	if s.closed {
+		return
 	}
 
-	reqData, err := req.MarshalBinary()
-	if err != nil {
-		s.closeErr = fmt.Errorf("marshal delete notification request: %w", err)
-		close(s.notifCh)
-		return s.closeErr
+	ch := s.notifCh
+	if s.decoder != nil {
+		ch = s.decoder.rawCh
 	}
 
-	respPacket, err := s.client.sendRequest(ctx, ads.CmdDelDeviceNotification, reqData)
-	if err != nil {
-		s.closeErr = fmt.Errorf("delete notification: %w", err)
-		close(s.notifCh)
-		return s.closeErr
+	s.deliver(ch, Notification{Data: data, Timestamp: timestamp})
+}
+
+// deliver queues a notification according to the overflow policy.
+// Must be called with closeMu held; notify is the only sender on ch.
+func (s *Subscription) deliver(ch chan Notification, notif Notification) {
+	deliverWithPolicy(ch, notif, s.opts, s.stop, s.recordDrop)
+}
+
+// stopDelivery ends a blocked delivery, so Close does not wait for it.
+func (s *Subscription) stopDelivery() {
+	if s.stop == nil {
+		return
 	}
+	s.stopOnce.Do(func() { close(s.stop) })
+}
 
-	var resp ads.DeleteDeviceNotificationResponse
-	if err := resp.UnmarshalBinary(respPacket.Data); err != nil {
-		s.closeErr = fmt.Errorf("unmarshal delete notification response: %w", err)
-		close(s.notifCh)
-		return s.closeErr
+// deliverWithPolicy queues v on ch according to the overflow policy of opts.
+// drop is called for every value that is discarded. A blocked delivery gives
+// up when stop is closed. The caller must be the only sender on ch.
+func deliverWithPolicy[T any](ch chan T, v T, opts NotificationOptions, stop <-chan struct{}, drop func()) {
+	select {
+	case ch <- v:
+		return
+	default:
 	}
 
-	if resp.Result != 0 {
-		s.closeErr = ads.Error(resp.Result)
-		close(s.notifCh)
-		return s.closeErr
+	switch opts.OverflowPolicy {
+	case OverflowDropOldest, OverflowCoalesce:
+		// Discard queued values until v fits, so the latest sample is
+		// always kept
+		for {
+			select {
+			case <-ch:
+				drop()
+			default:
+				// Receiver took a value in the meantime
+			}
+			select {
+			case ch <- v:
+				return
+			default:
+			}
+		}
+
+	case OverflowBlock:
+		timeout := opts.BlockTimeout
+		if timeout <= 0 {
+			timeout = defaultBlockTimeout
+		}
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case ch <- v:
+			return
+		case <-timer.C:
+		case <-stop:
+		}
 	}
 
-	close(s.notifCh)
-	return nil
+	drop()
 }
 
-// notify is called internally to send a notification to the subscription.
-func (s *Subscription) notify(data []byte, timestamp time.Time) {
-	s.closeMu.Lock()
-	defer s.closeMu.Unlock()
+// recordDrop counts a sample that was not delivered.
+func (s *Subscription) recordDrop() {
+	s.dropped.Add(1)
+	s.client.metrics.NotificationDropped()
+}
 
-	if s.closed {
+// emit sends a state change event without blocking. Events of group members
+// are sent to the group. Must be called with closeMu held.
+func (s *Subscription) emit(event SubscriptionEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if s.group != nil {
+		event.Symbol = s.group.members[s.groupIndex].symbol
+		s.group.emit(event)
+		return
+	}
+	if s.events == nil {
 		return
 	}
-
-	// Non-blocking send to prevent deadlock
 	select {
-	case s.notifCh <- Notification{Data: data, Timestamp: timestamp}:
+	case s.events <- event:
 	default:
-		// Channel full, drop notification
+		s.client.logger.Warn("subscription event dropped", "type", event.Type, "handle", s.handle)
+	}
+}
+
+// closeChannel closes the notification channel, through the decoder if there is
+// one, and the event channel. Must be called with closeMu held.
+func (s *Subscription) closeChannel() {
+	if s.events != nil {
+		close(s.events)
+	}
+	if s.decoder != nil {
+		close(s.decoder.rawCh)
+		close(s.decoder.done)
+		return
+	}
+	close(s.notifCh)
+}
+
+// runDecoder decodes queued samples until the subscription is closed, then
+// closes the notification channel.
+func (s *Subscription) runDecoder() {
+	defer close(s.notifCh)
+
+	for notif := range s.decoder.rawCh {
+		notif.Symbol = s.decoder.symbol
+		notif.Type = s.decoder.typeName
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		notif.Value, notif.Err = s.decoder.decode(ctx, notif.Data)
+		cancel()
+
+		select {
+		case s.notifCh <- notif:
+		case <-s.decoder.done:
+			return
+		}
 	}
 }
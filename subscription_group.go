@@ -0,0 +1,244 @@
+package goadstc
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// GroupValue is the value of one member of a group notification.
+type GroupValue struct {
+	Symbol string      // Symbol path as passed to SubscribeGroup
+	Type   string      // PLC type name
+	Data   []byte      // Raw sample
+	Value  interface{} // Decoded value, as returned by ReadSymbolValue
+	Err    error       // Decoding error, Value is nil
+}
+
+// GroupNotification holds the values of all group members that the PLC sent
+// with the same timestamp.
+type GroupNotification struct {
+	Timestamp time.Time
+	Values    []GroupValue // In the order of the symbols passed to SubscribeGroup
+}
+
+// Get returns the value of a member by symbol path.
+func (n GroupNotification) Get(symbol string) (GroupValue, bool) {
+	for _, v := range n.Values {
+		if v.Symbol == symbol {
+			return v, true
+		}
+	}
+	return GroupValue{}, false
+}
+
+// GroupSubscription delivers the samples of several symbols grouped by PLC
+// timestamp. Like a Subscription it stays valid across reconnects.
+type GroupSubscription struct {
+	client  *Client
+	members []groupMember
+	opts    NotificationOptions
+
+	mu     sync.Mutex // guards subs, closed and sends on rawCh
+	subs   []*Subscription
+	closed bool
+
+	rawCh   chan groupStamp
+	notifCh chan GroupNotification
+	done    chan struct{}
+
+	eventsMu     sync.Mutex // guards eventsClosed and sends on events
+	events       chan SubscriptionEvent
+	eventsClosed bool
+
+	// Closed by Close before it takes mu, to end an OverflowBlock wait
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	dropped atomic.Uint64
+}
+
+// groupMember describes one symbol of a group subscription.
+type groupMember struct {
+	symbol   string
+	typeName string
+	opts     NotificationOptions
+	decode   func(ctx context.Context, data []byte) (interface{}, error)
+}
+
+// groupSample is a raw sample of a group member.
+type groupSample struct {
+	index int // member index
+	data  []byte
+}
+
+// groupStamp holds the samples of a group that share a PLC timestamp.
+type groupStamp struct {
+	timestamp time.Time
+	samples   []groupSample
+}
+
+// newGroupSubscription creates a group subscription and starts decoding.
+// Buffering and the overflow policy of opts apply to the raw stamps.
+func newGroupSubscription(c *Client, members []groupMember, opts NotificationOptions) *GroupSubscription {
+	g := &GroupSubscription{
+		client:  c,
+		members: members,
+		opts:    opts,
+		rawCh:   make(chan groupStamp, opts.bufferSize()),
+		notifCh: make(chan GroupNotification),
+		done:    make(chan struct{}),
+		events:  make(chan SubscriptionEvent, 8*len(members)),
+		stop:    make(chan struct{}),
+	}
+	go g.run()
+	return g
+}
+
+// Notifications returns the channel of grouped notifications.
+// The channel is closed when the group subscription is closed.
+func (g *GroupSubscription) Notifications() <-chan GroupNotification {
+	return g.notifCh
+}
+
+// Events returns the channel of state changes of the members, such as
+// suspension on connection loss and re-establishment or a failed
+// re-establishment after a reconnect. SubscriptionEvent.Symbol names the
+// member. Events are dropped if the channel is full. The channel is closed
+// when the group subscription is closed.
+func (g *GroupSubscription) Events() <-chan SubscriptionEvent {
+	return g.events
+}
+
+// Symbols returns the symbol paths of the group members.
+func (g *GroupSubscription) Symbols() []string {
+	symbols := make([]string, len(g.members))
+	for i, member := range g.members {
+		symbols[i] = member.symbol
+	}
+	return symbols
+}
+
+// Dropped returns the number of stamps that were not delivered because the
+// notification channel was full.
+func (g *GroupSubscription) Dropped() uint64 {
+	return g.dropped.Load()
+}
+
+// Active reports whether all members currently exist on the PLC.
+func (g *GroupSubscription) Active() bool {
+	g.mu.Lock()
+	subs := g.subs
+	closed := g.closed
+	g.mu.Unlock()
+
+	if closed || len(subs) < len(g.members) {
+		return false
+	}
+	for _, sub := range subs {
+		if !sub.Active() {
+			return false
+		}
+	}
+	return true
+}
+
+// Close deletes the notifications of all members and closes the notification
+// and event channels. It returns the first error. It's safe to call Close
+// multiple times.
+func (g *GroupSubscription) Close() error {
+	g.stopOnce.Do(func() { close(g.stop) })
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return nil
+	}
+	g.closed = true
+	subs := g.subs
+	close(g.rawCh)
+	close(g.done)
+	g.mu.Unlock()
+
+	var firstErr error
+	for _, sub := range subs {
+		if err := sub.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	g.eventsMu.Lock()
+	g.eventsClosed = true
+	close(g.events)
+	g.eventsMu.Unlock()
+	return firstErr
+}
+
+// emit sends a state change event of a member without blocking.
+func (g *GroupSubscription) emit(event SubscriptionEvent) {
+	g.eventsMu.Lock()
+	defer g.eventsMu.Unlock()
+
+	if g.eventsClosed {
+		return
+	}
+	select {
+	case g.events <- event:
+	default:
+		g.client.logger.Warn("group subscription event dropped", "type", event.Type, "symbol", event.Symbol)
+	}
+}
+
+// addSubscription adds the subscription of a member.
+func (g *GroupSubscription) addSubscription(sub *Subscription) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.subs = append(g.subs, sub)
+}
+
+// notify is called from the notification loop with the samples of one stamp.
+func (g *GroupSubscription) notify(samples []groupSample, timestamp time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return
+	}
+	deliverWithPolicy(g.rawCh, groupStamp{timestamp: timestamp, samples: samples}, g.opts, g.stop, g.recordDrop)
+}
+
+// recordDrop counts a stamp that was not delivered.
+func (g *GroupSubscription) recordDrop() {
+	g.dropped.Add(1)
+	g.client.metrics.NotificationDropped()
+}
+
+// run decodes queued stamps until the group is closed, then closes the
+// notification channel.
+func (g *GroupSubscription) run() {
+	defer close(g.notifCh)
+
+	for stamp := range g.rawCh {
+		sort.Slice(stamp.samples, func(i, j int) bool { return stamp.samples[i].index < stamp.samples[j].index })
+
+		notif := GroupNotification{
+			Timestamp: stamp.timestamp,
+			Values:    make([]GroupValue, len(stamp.samples)),
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		for i, sample := range stamp.samples {
+			member := g.members[sample.index]
+			value := GroupValue{Symbol: member.symbol, Type: member.typeName, Data: sample.data}
+			value.Value, value.Err = member.decode(ctx, sample.data)
+			notif.Values[i] = value
+		}
+		cancel()
+
+		select {
+		case g.notifCh <- notif:
+		case <-g.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package goadstc
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/adssim"
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+	"github.com/mrpasztoradam/goadstc/internal/ams"
+)
+
+func TestGroupSubscriptionStamps(t *testing.T) {
+	c := &Client{
+		subscriptions: make(map[uint32]*Subscription),
+		suspended:     make(map[*Subscription]struct{}),
+		logger:        &noopLogger{},
+		metrics:       &noopMetrics{},
+	}
+
+	decodeDINT := func(ctx context.Context, data []byte) (interface{}, error) {
+		return int32(binary.LittleEndian.Uint32(data)), nil
+	}
+	group := newGroupSubscription(c, []groupMember{
+		{symbol: "MAIN.axis.position", typeName: "DINT", decode: decodeDINT},
+		{symbol: "MAIN.axis.status", typeName: "DINT", decode: decodeDINT},
+	}, NotificationOptions{})
+	for i, handle := range []uint32{10, 11} {
+		sub := &Subscription{handle: handle, client: c, notifCh: make(chan Notification), group: group, groupIndex: i}
+		group.addSubscription(sub)
+		c.subscriptions[handle] = sub
+	}
+	raw := &Subscription{handle: 12, client: c, notifCh: make(chan Notification, 1)}
+	c.subscriptions[raw.handle] = raw
+
+	const fileTime = 133444736000000000
+	sample := func(handle uint32, value uint32) ads.NotificationSample {
+		return ads.NotificationSample{NotificationHandle: handle, Data: binary.LittleEndian.AppendUint32(nil, value)}
+	}
+	req := ads.DeviceNotificationRequest{StampHeaders: []ads.StampHeader{
+		{Timestamp: fileTime, Samples: []ads.NotificationSample{sample(11, 3), sample(12, 99), sample(10, 1500)}},
+		{Timestamp: fileTime + 10000, Samples: []ads.NotificationSample{sample(11, 4)}},
+	}}
+	data, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	c.handleNotification(&ams.Packet{Data: data})
+
+	receive := func() GroupNotification {
+		t.Helper()
+		select {
+		case notif := <-group.Notifications():
+			return notif
+		case <-time.After(time.Second):
+			t.Fatal("no group notification received")
+			return GroupNotification{}
+		}
+	}
+
+	first := receive()
+	if len(first.Values) != 2 || first.Values[0].Value != int32(1500) || first.Values[1].Value != int32(3) {
+		t.Errorf("first stamp values = %+v, want position 1500 and status 3 in member order", first.Values)
+	}
+	if status, ok := first.Get("MAIN.axis.status"); !ok || status.Type != "DINT" {
+		t.Errorf("Get(status) = %+v, %v", status, ok)
+	}
+
+	second := receive()
+	if len(second.Values) != 1 || second.Values[0].Symbol != "MAIN.axis.status" || second.Values[0].Value != int32(4) {
+		t.Errorf("second stamp values = %+v, want only status 4", second.Values)
+	}
+	if got := second.Timestamp.Sub(first.Timestamp); got != time.Millisecond {
+		t.Errorf("timestamp difference = %v, want 1ms", got)
+	}
+
+	if notif := <-raw.Notifications(); binary.LittleEndian.Uint32(notif.Data) != 99 {
+		t.Errorf("raw subscription got %v, want 99", notif.Data)
+	}
+
+	// Members were lost with the connection, Close must not send requests
+	for _, sub := range group.subs {
+		sub.active = false
+	}
+	if err := group.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, ok := <-group.Notifications(); ok {
+		t.Error("group channel not closed")
+	}
+	if len(c.subscriptions) != 1 {
+		t.Errorf("members still registered: %v", c.subscriptions)
+	}
+}
+
+func TestGroupSubscriptionEventsAfterReset(t *testing.T) {
+	server, client := startSimulator(t, adssim.Config{
+		Symbols: []adssim.Symbol{
+			{Name: "MAIN.position", Type: "DINT"},
+			{Name: "MAIN.status", Type: "DINT"},
+		},
+	}, WithTimeout(300*time.Millisecond), WithAutoReconnect(true))
+	ctx := context.Background()
+
+	group, err := client.SubscribeGroup(ctx, []string{"MAIN.position", "MAIN.status"}, SymbolNotificationOptions{
+		TransmissionMode: ads.TransModeOnChange,
+	})
+	if err != nil {
+		t.Fatalf("SubscribeGroup() error = %v", err)
+	}
+	defer group.Close()
+
+	nextEvent := func() SubscriptionEvent {
+		t.Helper()
+		select {
+		case event := <-group.Events():
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no group event received")
+			return SubscriptionEvent{}
+		}
+	}
+	// eventsOf collects one event per member
+	eventsOf := func() map[string]SubscriptionEvent {
+		t.Helper()
+		events := make(map[string]SubscriptionEvent)
+		for len(events) < 2 {
+			event := nextEvent()
+			events[event.Symbol] = event
+		}
+		return events
+	}
+
+	// Restoring the first member fails after the reset, it is retried after
+	// the next reconnect
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultReset, Count: 1})
+	server.InjectFault(adssim.Fault{
+		Kind: adssim.FaultError, Error: ads.ErrDeviceServiceNotSupported,
+		Command: ads.CmdAddDeviceNotification, IndexGroup: 0x4020, Count: 1,
+	})
+	if _, err := client.ReadState(ctx); err == nil {
+		t.Fatal("ReadState() on reset connection succeeded")
+	}
+
+	for symbol, event := range eventsOf() {
+		if event.Type != SubscriptionSuspended || event.Err == nil {
+			t.Errorf("%s: event = %+v, want suspended", symbol, event)
+		}
+	}
+	var failed, restored int
+	for symbol, event := range eventsOf() {
+		switch event.Type {
+		case SubscriptionRestoreFailed:
+			failed++
+		case SubscriptionRestored:
+			restored++
+		default:
+			t.Errorf("%s: event = %+v, want restored or restore failed", symbol, event)
+		}
+	}
+	if failed != 1 || restored != 1 {
+		t.Errorf("%d members restored and %d failed, want 1 each", restored, failed)
+	}
+	if group.Active() {
+		t.Error("Active() = true with a member not restored")
+	}
+
+	if err := group.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, ok := <-group.Events(); ok {
+		t.Error("event channel not closed")
+	}
+}
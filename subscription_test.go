@@ -0,0 +1,214 @@
+package goadstc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/adssim"
+	"github.com/mrpasztoradam/goadstc/internal/ads"
+)
+
+func TestSubscriptionDecoder(t *testing.T) {
+	sub := &Subscription{
+		notifCh: make(chan Notification, 4),
+		decoder: &notificationDecoder{
+			symbol:   "MAIN.nCount",
+			typeName: "DINT",
+			decode: func(ctx context.Context, data []byte) (interface{}, error) {
+				return int32(binary.LittleEndian.Uint32(data)), nil
+			},
+			rawCh: make(chan Notification, 4),
+			done:  make(chan struct{}),
+		},
+	}
+	go sub.runDecoder()
+
+	timestamp := time.Unix(1700000000, 0)
+	sub.notify([]byte{0xF9, 0xFF, 0xFF, 0xFF}, timestamp)
+
+	select {
+	case notif := <-sub.Notifications():
+		if notif.Symbol != "MAIN.nCount" || notif.Type != "DINT" || notif.Value != int32(-7) || notif.Err != nil {
+			t.Errorf("notification = %+v", notif)
+		}
+		if !notif.Timestamp.Equal(timestamp) || len(notif.Data) != 4 {
+			t.Errorf("notification lost raw data or timestamp: %+v", notif)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no decoded notification received")
+	}
+
+	sub.closeMu.Lock()
+	sub.closed = true
+	sub.closeChannel()
+	sub.closeMu.Unlock()
+
+	select {
+	case _, ok := <-sub.Notifications():
+		if ok {
+			t.Error("unexpected notification after close")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("notification channel not closed")
+	}
+}
+
+func TestSubscriptionSuspendAndClose(t *testing.T) {
+	c := &Client{
+		subscriptions: make(map[uint32]*Subscription),
+		suspended:     make(map[*Subscription]struct{}),
+		logger:        &noopLogger{},
+		metrics:       &noopMetrics{},
+	}
+	sub := &Subscription{
+		handle:  7,
+		client:  c,
+		notifCh: make(chan Notification, 4),
+		events:  make(chan SubscriptionEvent, 4),
+		active:  true,
+	}
+	c.subscriptions[sub.handle] = sub
+
+	c.suspendSubscriptions(errors.New("connection reset"))
+
+	event := <-sub.Events()
+	if event.Type != SubscriptionSuspended || event.OldHandle != 7 || event.Err == nil {
+		t.Errorf("event = %+v, want suspended for handle 7", event)
+	}
+	if sub.Active() {
+		t.Error("Active() = true after suspension")
+	}
+
+	// A suspended subscription has no handle on the PLC, Close must not send a request
+	if err := sub.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, ok := <-sub.Notifications(); ok {
+		t.Error("notification channel not closed")
+	}
+	if _, ok := <-sub.Events(); ok {
+		t.Error("event channel not closed")
+	}
+	if len(c.subscriptions) != 0 {
+		t.Errorf("subscription still registered: %v", c.subscriptions)
+	}
+}
+
+func TestSubscriptionOverflowPolicies(t *testing.T) {
+	c := &Client{logger: &noopLogger{}, metrics: &noopMetrics{}}
+
+	tests := []struct {
+		opts    NotificationOptions
+		want    []byte // first data byte of the queued samples
+		dropped uint64
+	}{
+		{NotificationOptions{BufferSize: 2}, []byte{1, 2}, 2},
+		{NotificationOptions{BufferSize: 2, OverflowPolicy: OverflowDropOldest}, []byte{3, 4}, 2},
+		{NotificationOptions{BufferSize: 8, OverflowPolicy: OverflowCoalesce}, []byte{4}, 3},
+		{NotificationOptions{BufferSize: 2, OverflowPolicy: OverflowBlock, BlockTimeout: time.Millisecond}, []byte{1, 2}, 2},
+	}
+
+	for _, tt := range tests {
+		sub := &Subscription{client: c, opts: tt.opts, notifCh: make(chan Notification, tt.opts.bufferSize())}
+		for i := byte(1); i <= 4; i++ {
+			sub.notify([]byte{i}, time.Time{})
+		}
+
+		var got []byte
+		for len(sub.notifCh) > 0 {
+			got = append(got, (<-sub.notifCh).Data[0])
+		}
+		if string(got) != string(tt.want) || sub.Dropped() != tt.dropped {
+			t.Errorf("%s: queued %v, dropped %d; want %v, dropped %d",
+				tt.opts.OverflowPolicy, got, sub.Dropped(), tt.want, tt.dropped)
+		}
+	}
+
+	if err := (NotificationOptions{BufferSize: -1}).validate(); err == nil {
+		t.Error("validate() accepted a negative buffer size")
+	}
+}
+
+func TestSubscriptionCloseEndsBlockedDelivery(t *testing.T) {
+	c := &Client{
+		subscriptions: make(map[uint32]*Subscription),
+		suspended:     make(map[*Subscription]struct{}),
+		logger:        &noopLogger{},
+		metrics:       &noopMetrics{},
+	}
+	sub := &Subscription{
+		handle:  7,
+		client:  c,
+		notifCh: make(chan Notification, 1),
+		stop:    make(chan struct{}),
+		opts:    NotificationOptions{OverflowPolicy: OverflowBlock, BlockTimeout: time.Minute},
+	}
+	c.subscriptions[sub.handle] = sub
+
+	sub.notify([]byte{1}, time.Time{})
+	delivered := make(chan struct{})
+	go func() {
+		sub.notify([]byte{2}, time.Time{}) // waits for room
+		close(delivered)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() { closed <- sub.Close() }()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close() waited for the blocked delivery")
+	}
+	<-delivered
+	if sub.Dropped() != 1 {
+		t.Errorf("Dropped() = %d, want 1", sub.Dropped())
+	}
+}
+
+func TestSubscriptionCloseUnlockedDuringDelete(t *testing.T) {
+	server, client := startSimulator(t, simulatorConfig)
+	sub, err := client.SubscribeSymbol(context.Background(), "MAIN.counter", SymbolNotificationOptions{
+		TransmissionMode: ads.TransModeOnChange,
+	})
+	if err != nil {
+		t.Fatalf("SubscribeSymbol() error = %v", err)
+	}
+
+	before := server.Notifications()
+	const delay = 300 * time.Millisecond
+	server.InjectFault(adssim.Fault{Kind: adssim.FaultDelay, Delay: delay, Command: ads.CmdDelDeviceNotification})
+
+	start := time.Now()
+	first := make(chan error, 1)
+	go func() { first <- sub.Close() }()
+	for server.FaultsTriggered() == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The subscription is usable while the delete request is in flight
+	if sub.Active() {
+		t.Error("Active() = true while closing")
+	}
+	if elapsed := time.Since(start); elapsed >= delay {
+		t.Errorf("Active() blocked %v by the delete request", elapsed)
+	}
+
+	// A second Close waits for the first
+	if err := sub.Close(); err != nil || time.Since(start) < delay {
+		t.Errorf("second Close() = %v after %v, want nil after the delete", err, time.Since(start))
+	}
+	if err := <-first; err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if n := server.Notifications(); n != before-1 {
+		t.Errorf("%d notifications on the PLC after Close, want %d", n, before-1)
+	}
+}
@@ -0,0 +1,256 @@
+package goadstc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+// Tag is a typed handle to a PLC symbol.
+//
+// The symbol path is resolved and T is validated against the PLC type once,
+// when the tag is created. Read, Write and Subscribe then use the cached
+// address without symbol lookups. After an online change the tag resolves
+// and validates the path again on its next use.
+//
+// T follows the mapping rules of ReadInto: primitives, time.Duration,
+// time.Time, string, EnumValue, arrays, slices and structs with `ads` tags.
+// A Tag is safe for concurrent use.
+type Tag[T any] struct {
+	client *Client
+	name   string
+
+	mu     sync.Mutex
+	layout *tagLayout
+}
+
+// tagLayout is the resolved address and type of a tag.
+type tagLayout struct {
+	target     *resolvedSymbol
+	typeInfo   symbols.TypeInfo
+	bitField   *symbols.FieldInfo
+	generation uint64 // symbol table generation the layout was resolved in
+
+	// needsCurrent is set when T may not cover every byte of the value
+	// (struct members without a Go field, short slices, bit fields), so
+	// writes start from the current PLC value
+	needsCurrent bool
+}
+
+// TagValue is a decoded notification of a Tag.
+type TagValue[T any] struct {
+	Value     T
+	Timestamp time.Time
+	Err       error // decoding error, Value holds the zero value
+}
+
+// TagSubscription delivers the notifications of a Tag as decoded values.
+type TagSubscription[T any] struct {
+	sub       *Subscription
+	values    chan TagValue[T]
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewTag creates a typed handle for a symbol path, e.g.
+//
+//	speed, err := goadstc.NewTag[float32](ctx, client, "MAIN.fSpeed")
+//	axis, err := goadstc.NewTag[Axis](ctx, client, "GVL.aAxes[2]")
+//
+// It returns an error if the symbol does not exist or T does not match its
+// PLC type and size.
+func NewTag[T any](ctx context.Context, client *Client, symbolName string) (*Tag[T], error) {
+	tag := &Tag[T]{client: client, name: symbolName}
+	if _, err := tag.resolve(ctx); err != nil {
+		return nil, ClassifyError(fmt.Errorf("new tag %q: %w", symbolName, err), "new_tag")
+	}
+	return tag, nil
+}
+
+// Name returns the symbol path of the tag.
+func (t *Tag[T]) Name() string {
+	return t.name
+}
+
+// Read reads the current value of the tag.
+func (t *Tag[T]) Read(ctx context.Context) (T, error) {
+	var zero T
+
+	layout, err := t.resolve(ctx)
+	if err != nil {
+		return zero, ClassifyError(fmt.Errorf("read tag %q: %w", t.name, err), "tag_read")
+	}
+
+	data, err := t.client.readResolved(ctx, layout.target)
+	if err != nil {
+		return zero, ClassifyError(fmt.Errorf("read tag %q: %w", t.name, err), "tag_read")
+	}
+
+	value, err := decodeTag[T](ctx, t.client, layout, data)
+	if err != nil {
+		return zero, ClassifyError(fmt.Errorf("read tag %q: %w", t.name, err), "tag_read")
+	}
+	return value, nil
+}
+
+// Write writes a value to the tag. Bytes not covered by the value, such as
+// struct members without a Go field, keep their current PLC value.
+func (t *Tag[T]) Write(ctx context.Context, value T) error {
+	layout, err := t.resolve(ctx)
+	if err != nil {
+		return ClassifyError(fmt.Errorf("write tag %q: %w", t.name, err), "tag_write")
+	}
+
+	data := make([]byte, layout.target.size)
+	if layout.needsCurrent {
+		data, err = t.client.readResolved(ctx, layout.target)
+		if err != nil {
+			return ClassifyError(fmt.Errorf("write tag %q: read current value: %w", t.name, err), "tag_write")
+		}
+	}
+
+	if err := encodeTag(ctx, t.client, layout, data, value); err != nil {
+		return ClassifyError(fmt.Errorf("write tag %q: %w", t.name, err), "tag_write")
+	}
+
+	if err := t.client.writeResolved(ctx, layout.target, data); err != nil {
+		return ClassifyError(fmt.Errorf("write tag %q: %w", t.name, err), "tag_write")
+	}
+	return nil
+}
+
+// Subscribe creates a notification subscription for the tag that delivers
+// decoded values. Paths through pointers or references cannot be subscribed.
+// Call Close on the subscription when done.
+func (t *Tag[T]) Subscribe(ctx context.Context, opts SymbolNotificationOptions) (*TagSubscription[T], error) {
+	layout, err := t.resolve(ctx)
+	if err != nil {
+		return nil, ClassifyError(fmt.Errorf("subscribe tag %q: %w", t.name, err), "tag_subscribe")
+	}
+	if layout.target.dynamic {
+		return nil, ClassifyError(fmt.Errorf("subscribe tag %q: paths through pointers or references have no fixed address", t.name), "tag_subscribe")
+	}
+
+	sub, err := t.client.Subscribe(ctx, opts.notificationOptions(
+		layout.target.indexGroup, layout.target.indexOffset, layout.target.size))
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &TagSubscription[T]{
+		sub:    sub,
+		values: make(chan TagValue[T]), // buffering happens on the raw subscription
+		done:   make(chan struct{}),
+	}
+	go ts.decode(t.client, layout)
+	return ts, nil
+}
+
+// resolve returns the layout of the tag, resolving the path again if the
+// symbol table was reloaded since the last call.
+func (t *Tag[T]) resolve(ctx context.Context) (*tagLayout, error) {
+	if err := t.client.ensureSymbolsLoaded(ctx); err != nil {
+		return nil, err
+	}
+	generation := t.client.symbolGeneration()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.layout != nil && t.layout.generation == generation {
+		return t.layout, nil
+	}
+
+	target, err := t.client.resolveSymbolPath(ctx, t.name)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := &tagLayout{
+		target:       target,
+		bitField:     resolvedBitField(target),
+		generation:   generation,
+		needsCurrent: needsCurrentValue(reflect.TypeFor[T]()),
+	}
+	if layout.bitField != nil {
+		layout.needsCurrent = true
+	} else {
+		layout.typeInfo = t.client.fullTypeInfo(ctx, target.symbol.Type)
+	}
+
+	// Decoding a zeroed value checks T against the PLC type and size
+	if _, err := decodeTag[T](ctx, t.client, layout, make([]byte, target.size)); err != nil {
+		return nil, err
+	}
+
+	t.layout = layout
+	return layout, nil
+}
+
+// decodeTag decodes PLC data of a tag into T.
+func decodeTag[T any](ctx context.Context, c *Client, layout *tagLayout, data []byte) (T, error) {
+	var value T
+	v := reflect.ValueOf(&value).Elem()
+	if layout.bitField != nil {
+		return value, unmarshalBits(data, layout.bitField, v, layout.target.name)
+	}
+	return value, c.unmarshalValue(ctx, data, layout.typeInfo, v, layout.target.name)
+}
+
+// encodeTag encodes a value of a tag into data.
+func encodeTag[T any](ctx context.Context, c *Client, layout *tagLayout, data []byte, value T) error {
+	v := reflect.ValueOf(&value).Elem()
+	if layout.bitField != nil {
+		return marshalBits(data, layout.bitField, v, layout.target.name)
+	}
+	return c.marshalValue(ctx, data, layout.typeInfo, v, layout.target.name)
+}
+
+// needsCurrentValue reports whether a Go type may leave bytes of a PLC value
+// unset when encoded, so the current value has to be read first.
+func needsCurrentValue(goType reflect.Type) bool {
+	switch goType.Kind() {
+	case reflect.Struct:
+		return goType != timeType
+	case reflect.Array, reflect.Pointer:
+		return needsCurrentValue(goType.Elem())
+	case reflect.Slice, reflect.Interface:
+		return true
+	default:
+		return false
+	}
+}
+
+// Values returns the channel of decoded notifications. It is closed when the
+// subscription is closed.
+func (s *TagSubscription[T]) Values() <-chan TagValue[T] {
+	return s.values
+}
+
+// Subscription returns the underlying raw subscription.
+func (s *TagSubscription[T]) Subscription() *Subscription {
+	return s.sub
+}
+
+// Close unsubscribes from the notification and closes the value channel.
+func (s *TagSubscription[T]) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return s.sub.Close()
+}
+
+// decode converts raw notifications until the underlying channel is closed.
+func (s *TagSubscription[T]) decode(c *Client, layout *tagLayout) {
+	defer close(s.values)
+	for notif := range s.sub.Notifications() {
+		value, err := decodeTag[T](context.Background(), c, layout, notif.Data)
+		select {
+		case s.values <- TagValue[T]{Value: value, Timestamp: notif.Timestamp, Err: err}:
+		case <-s.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package goadstc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mrpasztoradam/goadstc/internal/symbols"
+)
+
+func TestTagCodec(t *testing.T) {
+	c := &Client{}
+	ctx := context.Background()
+	layout := &tagLayout{
+		target:   &resolvedSymbol{name: "MAIN.stMachine", size: 48},
+		typeInfo: testMachineType(),
+	}
+
+	want := testMachine{Enable: true, Door: true, Count: -7, Name: "press", Cycle: 20 * time.Millisecond}
+	data := make([]byte, 48)
+	if err := encodeTag(ctx, c, layout, data, want); err != nil {
+		t.Fatalf("encodeTag() error = %v", err)
+	}
+	got, err := decodeTag[testMachine](ctx, c, layout, data)
+	if err != nil {
+		t.Fatalf("decodeTag() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeTag() = %+v, want %+v", got, want)
+	}
+
+	counter := &tagLayout{
+		target:   &resolvedSymbol{name: "MAIN.nCount", size: 4},
+		typeInfo: symbols.TypeInfo{Name: "DINT", BaseType: symbols.DataTypeInt32, Size: 4},
+	}
+	if _, err := decodeTag[int16](ctx, c, counter, make([]byte, 4)); err == nil {
+		t.Error("decodeTag[int16] on DINT expected a type mismatch error")
+	}
+}
+
+func TestNeedsCurrentValue(t *testing.T) {
+	tests := []struct {
+		goType reflect.Type
+		want   bool
+	}{
+		{reflect.TypeFor[int32](), false},
+		{reflect.TypeFor[string](), false},
+		{reflect.TypeFor[time.Time](), false},
+		{reflect.TypeFor[[4]float64](), false},
+		{reflect.TypeFor[testMachine](), true},
+		{reflect.TypeFor[[2]testInner](), true},
+		{reflect.TypeFor[[]int16](), true},
+	}
+	for _, tt := range tests {
+		if got := needsCurrentValue(tt.goType); got != tt.want {
+			t.Errorf("needsCurrentValue(%s) = %v, want %v", tt.goType, got, tt.want)
+		}
+	}
+}